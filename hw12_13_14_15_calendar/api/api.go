@@ -0,0 +1,9 @@
+// Package api keeps the OpenAPI document generated from the gRPC-gateway annotations of the calendar service.
+package api
+
+import _ "embed" // for the OpenAPI document.
+
+// OpenAPI is the OpenAPI v2 document of the HTTP API, run make generate to update it.
+//
+//go:embed calendar.swagger.json
+var OpenAPI []byte
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"net"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/config"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/logger"
+	internaladmin "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/admin"
+)
+
+const redacted = "[REDACTED]"
+
+// adminInfo is what the admin server dumps at /debug/info.
+type adminInfo struct {
+	Build  versionInfo
+	Config config.Config
+}
+
+// newAdminServer serves debug endpoints, nil means the admin server is off.
+func newAdminServer(log *logger.Logger, cfg *config.Config) *internaladmin.Server {
+	if cfg.Server.Admin.Port == "" {
+		return nil
+	}
+
+	address := net.JoinHostPort(cfg.Server.Admin.Host, cfg.Server.Admin.Port)
+
+	return internaladmin.NewServer(address, log, log.LevelHandler(), adminInfo{currentVersion(), redactConfig(*cfg)})
+}
+
+// redactConfig hides passwords of the config, so the dump is safe to share.
+func redactConfig(cfg config.Config) config.Config {
+	for _, password := range []*string{&cfg.Storage.Database.Password, &cfg.Sender.Email.Password} {
+		if *password != "" {
+			*password = redacted
+		}
+	}
+
+	return cfg
+}
@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactConfig(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Storage.Database.User = "calendar"
+	cfg.Storage.Database.Password = "secret"
+
+	actual := redactConfig(cfg)
+	require.Equal(t, "calendar", actual.Storage.Database.User)
+	require.Equal(t, redacted, actual.Storage.Database.Password)
+	// unset passwords stay empty, so it's clear there are none.
+	require.Empty(t, actual.Sender.Email.Password)
+	// the config itself is left as is.
+	require.Equal(t, "secret", cfg.Storage.Database.Password)
+}
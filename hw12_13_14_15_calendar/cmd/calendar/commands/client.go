@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/config"
+	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// userCredentials identifies the user on every call the same way the server expects it.
+type userCredentials string
+
+func (c userCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{internalgrpc.UserIDMetadataKey: string(c)}, nil
+}
+
+func (c userCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func defaultClientConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "client.yaml"
+	}
+
+	return filepath.Join(dir, "calendar", "client.yaml")
+}
+
+// readClientConfig reads the client config, a missing default file means defaults are used.
+func readClientConfig(cmd *cobra.Command) (*config.ClientConfig, error) {
+	if !cmd.Flags().Changed("client-config") {
+		if _, err := os.Stat(clientConfigFile); errors.Is(err, os.ErrNotExist) {
+			return config.DefaultClientConfig(), nil
+		}
+	}
+
+	return config.ReadClientConfig(clientConfigFile)
+}
+
+// runWithClient connects to the calendar API and runs fn within the configured timeout.
+func runWithClient(cmd *cobra.Command, fn func(ctx context.Context, client pb.CalendarServiceClient) error) error {
+	cfg, err := readClientConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to read client config: %w", err)
+	}
+
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+
+	if cfg.Credentials.UserID != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(userCredentials(cfg.Credentials.UserID)))
+	}
+
+	conn, err := grpc.DialContext(cmd.Context(), cfg.Endpoint, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", cfg.Endpoint, err)
+	}
+
+	defer conn.Close()
+
+	ctx := cmd.Context()
+
+	if cfg.Timeout > 0 {
+		var cancelFn context.CancelFunc
+
+		ctx, cancelFn = context.WithTimeout(ctx, cfg.Timeout)
+
+		defer cancelFn()
+	}
+
+	return fn(ctx, pb.NewCalendarServiceClient(conn))
+}
@@ -0,0 +1,504 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/spf13/cobra"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const dateLayout = "2006-01-02"
+
+// startsAtLayouts are accepted by --starts-at, the latter one in the local time zone.
+var startsAtLayouts = []string{time.RFC3339, "2006-01-02 15:04"}
+
+var errPeriodConflict = errors.New("only one of --day, --week and --month can be set")
+
+var (
+	clientConfigFile string
+	outputFormat     string
+)
+
+type eventFlags struct {
+	title, startsAt, description string
+	duration                     time.Duration
+	tags                         []string
+	color                        string
+	allDay                       bool
+	startDate, endDate           string
+	// remindBefore is only set on create, reminders are added right after the event.
+	remindBefore []time.Duration
+}
+
+// tagFlags filter listed events by tags.
+type tagFlags struct {
+	tags []string
+	all  bool
+}
+
+type listFlags struct {
+	day, week, month bool
+	date, timeZone   string
+	firstDay         string
+	tagFlags
+}
+
+type searchFlags struct {
+	from, to string
+	limit    uint32
+	tagFlags
+}
+
+var (
+	createFlags eventFlags
+	updateFlags eventFlags
+	eventsList  listFlags
+	eventsFind  searchFlags
+	eventsTrash tagFlags
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Manage calendar events via the calendar API",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkOutputFormat(outputFormat); err != nil {
+			return err
+		}
+
+		// arguments are fine at this point, API errors don't need usage next to them.
+		cmd.SilenceUsage = true
+
+		return nil
+	},
+}
+
+var eventsCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an event",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req := &pb.CreateRequest{
+			Title:       createFlags.title,
+			Duration:    durationpb.New(createFlags.duration),
+			Description: createFlags.description,
+			Tags:        createFlags.tags,
+			Color:       createFlags.color,
+			AllDay:      createFlags.allDay,
+			StartDate:   createFlags.startDate,
+			EndDate:     createFlags.endDate,
+		}
+
+		if createFlags.startsAt != "" {
+			startsAt, err := parseStartsAt(createFlags.startsAt)
+			if err != nil {
+				return err
+			}
+
+			req.StartsAt = timestamppb.New(startsAt)
+		}
+
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			res, err := client.CreateEvent(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to create event: %w", err)
+			}
+
+			for _, offset := range createFlags.remindBefore {
+				_, err := client.AddReminder(ctx, &pb.AddReminderRequest{
+					EventId: res.GetId(),
+					Offset:  durationpb.New(offset),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to add reminder: %w", err)
+				}
+			}
+
+			event, err := client.GetEvent(ctx, &pb.GetRequest{Id: res.GetId()})
+			if err != nil {
+				return fmt.Errorf("failed to get created event: %w", err)
+			}
+
+			return printEvent(os.Stdout, outputFormat, time.Local, event)
+		})
+	},
+}
+
+var eventsGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Show an event",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			event, err := client.GetEvent(ctx, &pb.GetRequest{Id: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to get event: %w", err)
+			}
+
+			return printEvent(os.Stdout, outputFormat, time.Local, event)
+		})
+	},
+}
+
+var eventsUpdateCmd = &cobra.Command{
+	Use:   "update <id>",
+	Short: "Update an event, fields without flags are left as is",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			event, err := client.GetEvent(ctx, &pb.GetRequest{Id: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to get event: %w", err)
+			}
+
+			if err := applyEventFlags(cmd, event, updateFlags); err != nil {
+				return err
+			}
+
+			_, err = client.UpdateEvent(ctx, &pb.UpdateRequest{
+				Id:          event.GetId(),
+				Title:       event.GetTitle(),
+				StartsAt:    event.GetStartsAt(),
+				Duration:    event.GetDuration(),
+				Description: event.GetDescription(),
+				Tags:        event.GetTags(),
+				Color:       event.GetColor(),
+				AllDay:      event.GetAllDay(),
+				StartDate:   event.GetStartDate(),
+				EndDate:     event.GetEndDate(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to update event: %w", err)
+			}
+
+			return printEvent(os.Stdout, outputFormat, time.Local, event)
+		})
+	},
+}
+
+var eventsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete an event",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			if _, err := client.DeleteEvent(ctx, &pb.DeleteRequest{Id: args[0]}); err != nil {
+				return fmt.Errorf("failed to delete event: %w", err)
+			}
+
+			return nil
+		})
+	},
+}
+
+var eventsRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore a deleted event from the trash",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			event, err := client.RestoreEvent(ctx, &pb.RestoreRequest{Id: args[0]})
+			if err != nil {
+				return fmt.Errorf("failed to restore event: %w", err)
+			}
+
+			return printEvent(os.Stdout, outputFormat, time.Local, event)
+		})
+	},
+}
+
+var eventsTrashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List deleted events which can still be restored",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			res, err := client.ListDeletedEvents(ctx, &pb.ListDeletedRequest{
+				Tags:      eventsTrash.tags,
+				TagsMatch: eventsTrash.match(),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list deleted events: %w", err)
+			}
+
+			return printEvents(os.Stdout, outputFormat, time.Local, res.GetEvents())
+		})
+	},
+}
+
+var eventsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List events of the day, week or month",
+	Long: "List events of the day, week or month containing --date.\n" +
+		"Without --time-zone period bounds follow the time zone from user settings, UTC if there is none.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkPeriod(eventsList); err != nil {
+			return err
+		}
+
+		req, loc, err := parseListRequest(eventsList)
+		if err != nil {
+			return err
+		}
+
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			res, err := listEvents(ctx, client, eventsList, req)
+			if err != nil {
+				return fmt.Errorf("failed to list events: %w", err)
+			}
+
+			return printEvents(os.Stdout, outputFormat, loc, res.GetEvents())
+		})
+	},
+}
+
+var eventsSearchCmd = &cobra.Command{
+	Use:   "search <query>...",
+	Short: "Search events by words of their titles and descriptions",
+	Long: "Search events having every word of the query as a prefix of some word of their title or description.\n" +
+		"Events matching by title go first.",
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, err := parseSearchRequest(strings.Join(args, " "), eventsFind)
+		if err != nil {
+			return err
+		}
+
+		return runWithClient(cmd, func(ctx context.Context, client pb.CalendarServiceClient) error {
+			res, err := client.SearchEvents(ctx, req)
+			if err != nil {
+				return fmt.Errorf("failed to search events: %w", err)
+			}
+
+			return printEvents(os.Stdout, outputFormat, time.Local, res.GetEvents())
+		})
+	},
+}
+
+func init() {
+	eventsCmd.PersistentFlags().StringVar(
+		&clientConfigFile, "client-config", defaultClientConfigFile(), "Path to client configuration file",
+	)
+	eventsCmd.PersistentFlags().StringVarP(
+		&outputFormat, "output", "o", formatTable, "Output format: table, json or yaml",
+	)
+
+	addEventFlags(eventsCreateCmd, &createFlags)
+	cobra.CheckErr(eventsCreateCmd.MarkFlagRequired("title"))
+	eventsCreateCmd.Flags().DurationSliceVar(
+		&createFlags.remindBefore, "remind-before", nil, "Remind about the event in advance, e.g. 1h,15m",
+	)
+
+	addEventFlags(eventsUpdateCmd, &updateFlags)
+
+	eventsListCmd.Flags().BoolVar(&eventsList.day, "day", false, "List events of the day (default)")
+	eventsListCmd.Flags().BoolVar(&eventsList.week, "week", false, "List events of the week")
+	eventsListCmd.Flags().BoolVar(&eventsList.month, "month", false, "List events of the month")
+	eventsListCmd.Flags().StringVar(&eventsList.date, "date", "", "Date within the period as YYYY-MM-DD (default today)")
+	eventsListCmd.Flags().StringVar(&eventsList.timeZone, "time-zone", "", "IANA time zone of the period")
+	eventsListCmd.Flags().StringVar(&eventsList.firstDay, "first-day", "", "First day of week, e.g. monday or sunday")
+
+	eventsSearchCmd.Flags().StringVar(&eventsFind.from, "from", "", "Find events starting on or after the date, YYYY-MM-DD")
+	eventsSearchCmd.Flags().StringVar(&eventsFind.to, "to", "", "Find events starting on or before the date, YYYY-MM-DD")
+	eventsSearchCmd.Flags().Uint32Var(&eventsFind.limit, "limit", 0, "Maximum number of events, up to 100 (default 20)")
+
+	addTagFlags(eventsListCmd, &eventsList.tagFlags)
+	addTagFlags(eventsSearchCmd, &eventsFind.tagFlags)
+	addTagFlags(eventsTrashCmd, &eventsTrash)
+
+	eventsCmd.AddCommand(
+		eventsCreateCmd, eventsGetCmd, eventsUpdateCmd, eventsDeleteCmd, eventsRestoreCmd, eventsTrashCmd, eventsListCmd,
+		eventsSearchCmd,
+	)
+}
+
+func addEventFlags(cmd *cobra.Command, f *eventFlags) {
+	cmd.Flags().StringVar(&f.title, "title", "", "Event title, at least 10 characters")
+	cmd.Flags().StringVar(&f.startsAt, "starts-at", "", `Start time as RFC 3339 or "YYYY-MM-DD HH:MM" local time`)
+	cmd.Flags().DurationVar(&f.duration, "duration", 0, "Event duration, e.g. 1h30m")
+	cmd.Flags().StringVar(&f.description, "description", "", "Event description")
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "Event tags, e.g. on-call,work")
+	cmd.Flags().StringVar(&f.color, "color", "", "Event color as hex RGB, e.g. #1e90ff")
+	cmd.Flags().BoolVar(&f.allDay, "all-day", false, "All-day event covering dates instead of a time")
+	cmd.Flags().StringVar(&f.startDate, "start-date", "", "First date of an all-day event as YYYY-MM-DD")
+	cmd.Flags().StringVar(&f.endDate, "end-date", "", "Last date of an all-day event as YYYY-MM-DD (default the first one)")
+}
+
+func addTagFlags(cmd *cobra.Command, f *tagFlags) {
+	cmd.Flags().StringSliceVar(&f.tags, "tag", nil, "Keep events having any of the tags, e.g. on-call,meeting")
+	cmd.Flags().BoolVar(&f.all, "all-tags", false, "Keep events having all of the tags instead")
+}
+
+// match is how many of the tags events need, as the API expects it.
+func (f tagFlags) match() string {
+	if f.all {
+		return "all"
+	}
+
+	return "any"
+}
+
+// applyEventFlags overrides event fields with the flags set on the command line.
+func applyEventFlags(cmd *cobra.Command, event *pb.Event, f eventFlags) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("title") {
+		event.Title = f.title
+	}
+
+	if flags.Changed("starts-at") {
+		startsAt, err := parseStartsAt(f.startsAt)
+		if err != nil {
+			return err
+		}
+
+		event.StartsAt = timestamppb.New(startsAt)
+	}
+
+	if flags.Changed("duration") {
+		event.Duration = durationpb.New(f.duration)
+	}
+
+	if flags.Changed("description") {
+		event.Description = f.description
+	}
+
+	if flags.Changed("tag") {
+		event.Tags = f.tags
+	}
+
+	if flags.Changed("color") {
+		event.Color = f.color
+	}
+
+	if flags.Changed("all-day") {
+		event.AllDay = f.allDay
+	}
+
+	if flags.Changed("start-date") {
+		event.StartDate = f.startDate
+	}
+
+	if flags.Changed("end-date") {
+		event.EndDate = f.endDate
+	}
+
+	// a timed event made all-day keeps the local date it starts on.
+	if event.GetAllDay() && event.GetStartDate() == "" {
+		event.StartDate = event.GetStartsAt().AsTime().In(time.Local).Format(dateLayout)
+	}
+
+	return nil
+}
+
+func parseStartsAt(value string) (time.Time, error) {
+	for _, layout := range startsAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid start time %q, expected RFC 3339 or \"YYYY-MM-DD HH:MM\"", value)
+}
+
+// parseListRequest builds the request and the location the listed events are shown in.
+func parseListRequest(f listFlags) (*pb.ListRequest, *time.Location, error) {
+	req := &pb.ListRequest{TimeZone: f.timeZone, Tags: f.tags, TagsMatch: f.match()}
+	loc := time.Local
+
+	if f.timeZone != "" {
+		var err error
+
+		if loc, err = time.LoadLocation(f.timeZone); err != nil {
+			return nil, nil, fmt.Errorf("invalid time zone %q: %w", f.timeZone, err)
+		}
+	}
+
+	date := time.Now().In(loc)
+
+	if f.date != "" {
+		var err error
+
+		if date, err = time.ParseInLocation(dateLayout, f.date, loc); err != nil {
+			return nil, nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", f.date)
+		}
+	}
+
+	// noon keeps the date the same whichever time zone the server picks for the period.
+	date = time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, loc)
+	req.Date = timestamppb.New(date)
+
+	if f.firstDay != "" {
+		day, ok := dayofweek.DayOfWeek_value[strings.ToUpper(f.firstDay)]
+		if !ok || day == int32(dayofweek.DayOfWeek_DAY_OF_WEEK_UNSPECIFIED) {
+			return nil, nil, fmt.Errorf("invalid first day of week %q", f.firstDay)
+		}
+
+		req.FirstDayOfWeek = dayofweek.DayOfWeek(day)
+	}
+
+	return req, loc, nil
+}
+
+// parseSearchRequest bounds the search with local days, both of them included.
+func parseSearchRequest(query string, f searchFlags) (*pb.SearchRequest, error) {
+	req := &pb.SearchRequest{Query: query, Limit: f.limit, Tags: f.tags, TagsMatch: f.match()}
+
+	if f.from != "" {
+		from, err := time.ParseInLocation(dateLayout, f.from, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", f.from)
+		}
+
+		req.From = timestamppb.New(from)
+	}
+
+	if f.to != "" {
+		to, err := time.ParseInLocation(dateLayout, f.to, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", f.to)
+		}
+
+		req.To = timestamppb.New(to.AddDate(0, 0, 1))
+	}
+
+	return req, nil
+}
+
+func checkPeriod(f listFlags) error {
+	set := 0
+
+	for _, flag := range []bool{f.day, f.week, f.month} {
+		if flag {
+			set++
+		}
+	}
+
+	if set > 1 {
+		return errPeriodConflict
+	}
+
+	return nil
+}
+
+// listEvents calls the list method matching the period flags, a day by default.
+func listEvents(
+	ctx context.Context, client pb.CalendarServiceClient, f listFlags, req *pb.ListRequest,
+) (*pb.ListResponse, error) {
+	switch {
+	case f.week:
+		return client.ListWeekEvents(ctx, req)
+	case f.month:
+		return client.ListMonthEvents(ctx, req)
+	default:
+		return client.ListDayEvents(ctx, req)
+	}
+}
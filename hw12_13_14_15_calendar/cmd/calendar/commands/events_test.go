@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+)
+
+func TestParseListRequest(t *testing.T) {
+	req, loc, err := parseListRequest(listFlags{
+		date:     "2026-10-19",
+		timeZone: "Europe/Moscow",
+		firstDay: "Sunday",
+		tagFlags: tagFlags{tags: []string{"on-call", "work"}, all: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Moscow", loc.String())
+	require.Equal(t, "Europe/Moscow", req.GetTimeZone())
+	require.Equal(t, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), req.GetDate().AsTime())
+	require.Equal(t, dayofweek.DayOfWeek_SUNDAY, req.GetFirstDayOfWeek())
+	require.Equal(t, []string{"on-call", "work"}, req.GetTags())
+	require.Equal(t, "all", req.GetTagsMatch())
+}
+
+func TestParseListRequestErrors(t *testing.T) {
+	tests := []struct {
+		flags         listFlags
+		expectedError string
+	}{
+		{listFlags{date: "19.10.2026"}, `invalid date "19.10.2026", expected YYYY-MM-DD`},
+		{listFlags{timeZone: "Mars/Olympus"}, `invalid time zone "Mars/Olympus": unknown time zone Mars/Olympus`},
+		{listFlags{firstDay: "someday"}, `invalid first day of week "someday"`},
+		{listFlags{firstDay: "day_of_week_unspecified"}, `invalid first day of week "day_of_week_unspecified"`},
+	}
+
+	for _, tt := range tests {
+		_, _, err := parseListRequest(tt.flags)
+		require.EqualError(t, err, tt.expectedError)
+	}
+}
+
+func TestCheckPeriod(t *testing.T) {
+	require.NoError(t, checkPeriod(listFlags{}))
+	require.NoError(t, checkPeriod(listFlags{week: true}))
+	require.ErrorIs(t, checkPeriod(listFlags{day: true, month: true}), errPeriodConflict)
+}
+
+func TestParseStartsAt(t *testing.T) {
+	startsAt, err := parseStartsAt("2026-10-19T10:00:00+03:00")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC), startsAt.UTC())
+
+	startsAt, err = parseStartsAt("2026-10-19 10:00")
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 10, 19, 10, 0, 0, 0, time.Local), startsAt)
+
+	_, err = parseStartsAt("tomorrow")
+	require.Error(t, err)
+}
+
+func TestParseSearchRequest(t *testing.T) {
+	req, err := parseSearchRequest("team offsite", searchFlags{from: "2026-10-19", to: "2026-10-20", limit: 5})
+	require.NoError(t, err)
+	require.Equal(t, "team offsite", req.GetQuery())
+	require.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local), req.GetFrom().AsTime().In(time.Local))
+	require.Equal(t, time.Date(2026, 10, 21, 0, 0, 0, 0, time.Local), req.GetTo().AsTime().In(time.Local))
+	require.Equal(t, uint32(5), req.GetLimit())
+
+	req, err = parseSearchRequest("team", searchFlags{})
+	require.NoError(t, err)
+	require.Nil(t, req.GetFrom())
+	require.Nil(t, req.GetTo())
+	require.Empty(t, req.GetTags())
+	require.Equal(t, "any", req.GetTagsMatch())
+
+	_, err = parseSearchRequest("team", searchFlags{to: "tomorrow"})
+	require.EqualError(t, err, `invalid date "tomorrow", expected YYYY-MM-DD`)
+}
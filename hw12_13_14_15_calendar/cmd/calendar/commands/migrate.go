@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/pressly/goose"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage schema of the sql storage database",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// goose reports through the standard logger, timestamps only clutter the command output.
+		goose.SetLogger(log.New(cmd.OutOrStdout(), "", 0))
+	},
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVar(&configFile, "config", "/etc/calendar/config.yaml", "Path to configuration file")
+
+	migrateCmd.AddCommand(
+		newMigrateCmd("up", "Apply all pending migrations"),
+		newMigrateCmd("down", "Roll back the most recently applied migration"),
+		newMigrateCmd("redo", "Roll back the most recently applied migration and apply it again"),
+		newMigrateCmd("status", "Print status of every migration"),
+		newMigrateCmd("version", "Print the current schema version"),
+	)
+}
+
+func newMigrateCmd(command, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   command,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.ReadConfig(configFile)
+			if err != nil {
+				return fmt.Errorf("failed to read config: %w", err)
+			}
+
+			database, err := newSQLDatabase(cfg.Storage)
+			if err != nil {
+				return err
+			}
+
+			if err := database.migrate(command); err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", command, err)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// eventView is the event as printed by the client, times are shown in the location of the listed period.
+type eventView struct {
+	ID          string   `json:"id" yaml:"id"`
+	Title       string   `json:"title" yaml:"title"`
+	StartsAt    string   `json:"startsAt" yaml:"startsAt"`
+	Duration    string   `json:"duration" yaml:"duration"`
+	Description string   `json:"description" yaml:"description"`
+	OwnerID     string   `json:"ownerId" yaml:"ownerId"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Color       string   `json:"color,omitempty" yaml:"color,omitempty"`
+	AllDay      bool     `json:"allDay,omitempty" yaml:"allDay,omitempty"`
+	EndDate     string   `json:"endDate,omitempty" yaml:"endDate,omitempty"`
+}
+
+func newEventView(event *pb.Event, loc *time.Location) eventView {
+	if event.GetAllDay() {
+		return newAllDayEventView(event)
+	}
+
+	return eventView{
+		ID:          event.GetId(),
+		Title:       event.GetTitle(),
+		StartsAt:    event.GetStartsAt().AsTime().In(loc).Format(time.RFC3339),
+		Duration:    event.GetDuration().AsDuration().String(),
+		Description: event.GetDescription(),
+		OwnerID:     event.GetOwnerId(),
+		Tags:        event.GetTags(),
+		Color:       event.GetColor(),
+	}
+}
+
+// newAllDayEventView shows dates of an all-day event as they are, since they don't depend on the location.
+func newAllDayEventView(event *pb.Event) eventView {
+	duration := "all day"
+
+	start, startErr := time.Parse(dateLayout, event.GetStartDate())
+	end, endErr := time.Parse(dateLayout, event.GetEndDate())
+
+	if days := int(end.Sub(start).Hours()/24) + 1; startErr == nil && endErr == nil && days > 1 {
+		duration = fmt.Sprintf("%d days", days)
+	}
+
+	return eventView{
+		ID:          event.GetId(),
+		Title:       event.GetTitle(),
+		StartsAt:    event.GetStartDate(),
+		Duration:    duration,
+		Description: event.GetDescription(),
+		OwnerID:     event.GetOwnerId(),
+		Tags:        event.GetTags(),
+		Color:       event.GetColor(),
+		AllDay:      true,
+		EndDate:     event.GetEndDate(),
+	}
+}
+
+func checkOutputFormat(format string) error {
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, expected table, json or yaml", format)
+	}
+}
+
+func printEvent(w io.Writer, format string, loc *time.Location, event *pb.Event) error {
+	view := newEventView(event, loc)
+
+	if format == formatTable {
+		return printTable(w, []eventView{view})
+	}
+
+	return encode(w, format, view)
+}
+
+func printEvents(w io.Writer, format string, loc *time.Location, events []*pb.Event) error {
+	views := make([]eventView, 0, len(events))
+
+	for _, e := range events {
+		views = append(views, newEventView(e, loc))
+	}
+
+	if format == formatTable {
+		return printTable(w, views)
+	}
+
+	return encode(w, format, views)
+}
+
+func printTable(w io.Writer, views []eventView) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "ID\tTITLE\tSTARTS AT\tDURATION\tTAGS\tDESCRIPTION")
+
+	for _, v := range views {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			v.ID, v.Title, v.StartsAt, v.Duration, strings.Join(v.Tags, ","), v.Description)
+	}
+
+	return tw.Flush()
+}
+
+func encode(w io.Writer, format string, v interface{}) error {
+	if format == formatYAML {
+		return yaml.NewEncoder(w).Encode(v)
+	}
+
+	enc := json.NewEncoder(w)
+
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestEvent() *pb.Event {
+	return &pb.Event{
+		Id:          "a3390737-19c6-4ed6-beee-2e8ae1a1929a",
+		Title:       "Team standup meeting",
+		StartsAt:    timestamppb.New(time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)),
+		Duration:    durationpb.New(30 * time.Minute),
+		Description: "daily sync",
+		OwnerId:     "0b9e0c5e-3f7a-4d6e-9a51-2d6f1c1b2a10",
+		Tags:        []string{"meeting", "work"},
+		Color:       "#1e90ff",
+	}
+}
+
+func TestPrintEvent(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			formatTable,
+			"ID                                    TITLE                 STARTS AT                  DURATION  TAGS          DESCRIPTION\n" +
+				"a3390737-19c6-4ed6-beee-2e8ae1a1929a  Team standup meeting  2026-10-19T10:00:00+03:00  30m0s     meeting,work  daily sync\n",
+		},
+		{
+			formatJSON,
+			`{
+  "id": "a3390737-19c6-4ed6-beee-2e8ae1a1929a",
+  "title": "Team standup meeting",
+  "startsAt": "2026-10-19T10:00:00+03:00",
+  "duration": "30m0s",
+  "description": "daily sync",
+  "ownerId": "0b9e0c5e-3f7a-4d6e-9a51-2d6f1c1b2a10",
+  "tags": [
+    "meeting",
+    "work"
+  ],
+  "color": "#1e90ff"
+}
+`,
+		},
+		{
+			formatYAML,
+			`id: a3390737-19c6-4ed6-beee-2e8ae1a1929a
+title: Team standup meeting
+startsAt: "2026-10-19T10:00:00+03:00"
+duration: 30m0s
+description: daily sync
+ownerId: 0b9e0c5e-3f7a-4d6e-9a51-2d6f1c1b2a10
+tags:
+- meeting
+- work
+color: '#1e90ff'
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			require.NoError(t, printEvent(buf, tt.format, moscow, newTestEvent()))
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestPrintAllDayEvents(t *testing.T) {
+	birthday := &pb.Event{
+		Id: "1", Title: "Birthday", AllDay: true, StartDate: "2026-10-19", EndDate: "2026-10-19",
+		StartsAt: timestamppb.New(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)),
+		Duration: durationpb.New(24 * time.Hour),
+	}
+	vacation := &pb.Event{
+		Id: "2", Title: "Vacation", AllDay: true, StartDate: "2026-10-20", EndDate: "2026-10-26",
+		StartsAt: timestamppb.New(time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)),
+		Duration: durationpb.New(7 * 24 * time.Hour),
+	}
+	buf := &bytes.Buffer{}
+
+	// dates are the same in any location.
+	require.NoError(t, printEvents(buf, formatTable, time.FixedZone("UTC-5", -5*60*60), []*pb.Event{birthday, vacation}))
+	require.Equal(t, "ID  TITLE     STARTS AT   DURATION  TAGS  DESCRIPTION\n"+
+		"1   Birthday  2026-10-19  all day         \n"+
+		"2   Vacation  2026-10-20  7 days          \n", buf.String())
+}
+
+func TestPrintEventsEmpty(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{formatTable, "ID  TITLE  STARTS AT  DURATION  TAGS  DESCRIPTION\n"},
+		{formatJSON, "[]\n"},
+		{formatYAML, "[]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			require.NoError(t, printEvents(buf, tt.format, time.UTC, nil))
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	for _, format := range []string{formatTable, formatJSON, formatYAML} {
+		require.NoError(t, checkOutputFormat(format))
+	}
+
+	require.EqualError(t, checkOutputFormat("xml"), `unsupported output format "xml", expected table, json or yaml`)
+}
@@ -2,20 +2,26 @@ package commands
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net"
+	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"github.com/pressly/goose"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/config"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/sender"
 	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
 	internalhttp "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
 	memorystorage "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage/memory"
 	sqlstorage "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage/sql"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/webhook"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/migrations"
 	"github.com/spf13/cobra"
 )
@@ -30,46 +36,144 @@ var rootCmd = &cobra.Command{
 			return fmt.Errorf("failed to read config: %w", err)
 		}
 
+		// the command line is fine, failures from here on aren't about usage.
+		cmd.SilenceUsage = true
+
 		return startApp(cmd.Context(), cfg)
 	},
 }
 
 func init() {
-	goose.AddNamedMigration("00001_create_events_table.go", migrations.Up0001, migrations.Down0001)
-
 	rootCmd.Flags().StringVar(&configFile, "config", "/etc/calendar/config.yaml", "Path to configuration file")
-	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(versionCmd, eventsCmd, migrateCmd)
 }
 
 func initStorage(ctx context.Context, cfg config.StorageConfig) (app.Storage, error) {
 	switch cfg.Type {
 	case "memory":
-		return memorystorage.New(), nil
+		if cfg.Memory.Dir == "" {
+			return memorystorage.New(), nil
+		}
+
+		return memorystorage.NewPersistent(cfg.Memory.Dir, cfg.Memory.SnapshotInterval)
+	case "sql", "sqlite":
+		return initSQLStorage(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unrecognized type: %q", cfg.Type)
+	}
+}
+
+// sqlDatabase describes the database of sql storages.
+type sqlDatabase struct {
+	dialect, driver, dsn string
+}
+
+func newSQLDatabase(cfg config.StorageConfig) (sqlDatabase, error) {
+	switch cfg.Type {
 	case "sql":
 		dsn := fmt.Sprintf(
 			"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 			cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DB,
 		)
 
-		db, err := goose.OpenDBWithDriver("postgres", dsn)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open DB: %w", err)
-		}
+		return sqlDatabase{"postgres", sqlstorage.PostgresDriver, dsn}, nil
+	case "sqlite":
+		dsn := fmt.Sprintf(
+			"%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite",
+			cfg.SQLite.Path,
+		)
 
-		if err := goose.Up(db, "migrations"); err != nil {
+		return sqlDatabase{"sqlite3", sqlstorage.SQLiteDriver, dsn}, nil
+	default:
+		return sqlDatabase{}, fmt.Errorf("%q storage has no database to migrate", cfg.Type)
+	}
+}
+
+// migrate runs the migrations command against the storage database.
+func (d sqlDatabase) migrate(command string, args ...string) error {
+	db, err := sql.Open(d.driver, d.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open DB: %w", err)
+	}
+
+	defer db.Close()
+
+	return migrations.Run(db, d.dialect, command, args...)
+}
+
+func initSQLStorage(ctx context.Context, cfg config.StorageConfig) (app.Storage, error) {
+	database, err := newSQLDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		if err := database.migrate("up"); err != nil {
 			return nil, fmt.Errorf("failed to migrate: %w", err)
 		}
+	}
 
-		if storage, err := sqlstorage.New(dsn); err != nil {
-			return nil, err
-		} else if err := storage.Connect(ctx); err != nil {
-			return nil, err
-		} else {
-			return storage, nil
-		}
-	default:
-		return nil, fmt.Errorf("unrecognized type: %q", cfg.Type)
+	storage, err := sqlstorage.New(database.driver, database.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.Connect(ctx); err != nil {
+		storage.Close(ctx)
+
+		return nil, err
 	}
+
+	return storage, nil
+}
+
+// notificationQueueSize bounds notifications waiting for the sender before the scheduler blocks.
+const notificationQueueSize = 1000
+
+// newNotifier routes notifications to the log and the channels set up in the config.
+func newNotifier(log *logger.Logger, cfg config.SenderConf) sender.Notifier {
+	channels := sender.Channels{string(storage.ChannelLog): sender.NewLogNotifier(log)}
+
+	if cfg.Webhook.URL != "" {
+		channels[string(storage.ChannelWebhook)] = sender.NewWebhookNotifier(cfg.Webhook.URL, cfg.Webhook.Timeout)
+	}
+
+	if cfg.Email.Host != "" {
+		channels[string(storage.ChannelEmail)] = sender.NewEmailNotifier(
+			cfg.Email.Host, cfg.Email.Port, cfg.Email.User, cfg.Email.Password, cfg.Email.From,
+		)
+	}
+
+	return channels
+}
+
+func newWebhookWorker(log *logger.Logger, storage webhook.Storage, cfg config.WebhooksConf) *webhook.Worker {
+	client := webhook.NewClient(cfg.Timeout, cfg.AllowPrivateNetworks)
+
+	return webhook.New(log, storage, client, cfg.Interval, webhook.RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		MinBackoff:  cfg.MinBackoff,
+		MaxBackoff:  cfg.MaxBackoff,
+	})
+}
+
+func newHTTPOptions(cfg config.HTTPConf) internalhttp.Options {
+	return internalhttp.Options{
+		CORS: internalhttp.CORS{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		},
+		MaxBodySize: cfg.MaxBodySize,
+	}
+}
+
+// storageCloser is implemented by storages holding resources to release on shutdown.
+type storageCloser interface {
+	Close(ctx context.Context) error
 }
 
 func startApp(ctx context.Context, cfg *config.Config) error {
@@ -84,59 +188,67 @@ func startApp(ctx context.Context, cfg *config.Config) error {
 	}
 
 	calendar := app.New(log, storage)
+	notifications := queue.NewMemory(notificationQueueSize)
 
 	grpcAddress := net.JoinHostPort(cfg.Server.Grpc.Host, cfg.Server.Grpc.Port)
 	httpAddress := net.JoinHostPort(cfg.Server.HTTP.Host, cfg.Server.HTTP.Port)
 
-	grpcServer := internalgrpc.NewServer(grpcAddress, log, calendar)
-	httpServer := internalhttp.NewServer(httpAddress, grpcAddress, log)
+	manager := lifecycle.New(log, cfg.Shutdown.Timeout)
 
-	go func() {
-		<-ctx.Done()
-
-		ctx, cancelFn := context.WithTimeout(context.Background(), time.Second*3)
+	// components are stopped in reverse, so the storage outlives everything using it.
+	if c, ok := storage.(storageCloser); ok {
+		manager.Add("storage", lifecycle.Closer(c.Close))
+	}
 
-		defer cancelFn()
+	grpcServer := internalgrpc.NewServer(grpcAddress, log, calendar)
 
-		if err := httpServer.Stop(ctx); err != nil {
-			log.Error(fmt.Sprintln("failed to stop http server:", err))
-		}
+	if admin := newAdminServer(log, cfg); admin != nil {
+		grpcServer.RegisterDebugServices()
+		manager.Add("admin server", admin)
+	}
 
-		if err := grpcServer.Stop(); err != nil {
-			log.Error(fmt.Sprintln("failed to stop grpc server:", err))
-		}
-	}()
+	manager.Add("grpc server", grpcServer)
+	manager.Add("http server", internalhttp.NewServer(httpAddress, grpcAddress, log, newHTTPOptions(cfg.Server.HTTP)))
+	manager.Add("sender", lifecycle.Worker(
+		sender.New(log, notifications, newNotifier(log, cfg.Sender), storage).Run,
+	))
+	manager.Add("scheduler", lifecycle.Worker(
+		scheduler.New(log, storage, cfg.Scheduler.Interval, cfg.Scheduler.TrashRetention).Run,
+	))
+	manager.Add("outbox relay", lifecycle.Worker(
+		scheduler.NewRelay(log, storage, notifications, cfg.Scheduler.RelayInterval, cfg.Scheduler.OutboxRetention).Run,
+	))
+	manager.Add("webhooks", lifecycle.Worker(newWebhookWorker(log, storage, cfg.Webhooks).Run))
 
-	log.Info("calendar is running...")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	errCh := make(chan error, 2)
+	defer signal.Stop(signals)
 
 	go func() {
-		log.Info("grpc is running...")
-
-		if err := grpcServer.Start(ctx); err != nil {
-			errCh <- fmt.Errorf("failed to run grpc server: %w", err)
+		select {
+		case sig := <-signals:
+			manager.Shutdown(fmt.Sprintf("received %s", sig))
+		case <-ctx.Done():
 		}
 	}()
 
-	go func() {
-		log.Info("http is running...")
+	log.Info("calendar is starting...")
 
-		if err := httpServer.Start(ctx); err != nil {
-			errCh <- fmt.Errorf("failed to run http server: %w", err)
-		}
-	}()
+	if err := manager.Run(ctx); err != nil {
+		log.Error(fmt.Sprintln("calendar stopped:", err))
 
-	return <-errCh
-}
+		return err
+	}
 
-func Execute() {
-	ctx, cancelFn := signal.NotifyContext(
-		context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP,
-	)
+	log.Info("calendar stopped")
 
-	defer cancelFn()
+	return nil
+}
 
-	cobra.CheckErr(rootCmd.ExecuteContext(ctx))
+// Execute runs the command, failures exit with non-zero code after cobra reports them.
+func Execute() {
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		os.Exit(1)
+	}
 }
@@ -20,13 +20,15 @@ type versionInfo struct {
 	GitHash   string
 }
 
+func currentVersion() versionInfo {
+	return versionInfo{release, buildDate, gitHash}
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of calendar",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		info := versionInfo{release, buildDate, gitHash}
-
-		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+		if err := json.NewEncoder(os.Stdout).Encode(currentVersion()); err != nil {
 			return fmt.Errorf("error while decode version info: %w", err)
 		}
 
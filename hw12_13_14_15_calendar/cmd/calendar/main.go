@@ -1,8 +1,11 @@
 package main
 
 import (
+	_ "time/tzdata"
+
 	_ "github.com/lib/pq"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/cmd/calendar/commands"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -5,7 +5,7 @@ go 1.16
 require (
 	github.com/bufbuild/buf v0.43.2
 	github.com/envoyproxy/protoc-gen-validate v0.6.0
-	github.com/google/uuid v1.1.2
+	github.com/google/uuid v1.3.0
 	github.com/grpc-ecosystem/go-grpc-middleware v1.3.0
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.5.0
 	github.com/iancoleman/strcase v0.1.3 // indirect
@@ -22,4 +22,6 @@ require (
 	google.golang.org/grpc v1.39.0-dev.0.20210519181852-3dd75a6888ce
 	google.golang.org/grpc/cmd/protoc-gen-go-grpc v1.1.0
 	google.golang.org/protobuf v1.26.1-0.20210520194023-50a85913fbce
+	gopkg.in/yaml.v2 v2.4.0
+	modernc.org/sqlite v1.14.8
 )
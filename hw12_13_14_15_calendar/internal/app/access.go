@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var accessRanks = map[storage.AccessLevel]int{
+	storage.AccessFreeBusy: 1,
+	storage.AccessRead:     2,
+	storage.AccessWrite:    3,
+	storage.AccessOwner:    4,
+}
+
+// allows tells whether the access level includes the required one, no access is an empty level.
+func allows(access, required storage.AccessLevel) bool {
+	return accessRanks[access] >= accessRanks[required]
+}
+
+// calendarAccess returns what the user performing the request may do with the calendar.
+func (a *App) calendarAccess(ctx context.Context, calendar storage.Calendar) (storage.AccessLevel, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return "", ErrUserIDRequired
+	}
+
+	if calendar.OwnerID == userID {
+		return storage.AccessOwner, nil
+	}
+
+	share, err := a.storage.GetShare(ctx, calendar.ID, userID)
+	if errors.Is(err, storage.ErrShareNotFound) {
+		return "", nil
+	}
+
+	return share.Access, err
+}
+
+// requireCalendarAccess returns the calendar if the user performing the request has the required access to it.
+func (a *App) requireCalendarAccess(
+	ctx context.Context, id string, required storage.AccessLevel,
+) (storage.Calendar, storage.AccessLevel, error) {
+	calendar, err := a.storage.GetCalendar(ctx, id)
+	if err != nil {
+		return calendar, "", err
+	}
+
+	access, err := a.calendarAccess(ctx, calendar)
+	if err != nil {
+		return calendar, access, err
+	}
+
+	if !allows(access, required) {
+		return calendar, access, ErrPermissionDenied
+	}
+
+	return calendar, access, nil
+}
+
+// eventAccess returns what the user performing the request may do with the event.
+// The owner has full access, calendar shares apply to events of the calendar and attendees may read.
+// Events nobody owns stay open to anonymous requests the way they were before users.
+func (a *App) eventAccess(ctx context.Context, event storage.Event) (storage.AccessLevel, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		if event.OwnerID == "" {
+			return storage.AccessOwner, nil
+		}
+
+		return "", ErrUserIDRequired
+	}
+
+	if event.OwnerID == userID {
+		return storage.AccessOwner, nil
+	}
+
+	var access storage.AccessLevel
+
+	if event.CalendarID != "" {
+		calendar, err := a.storage.GetCalendar(ctx, event.CalendarID)
+
+		// events of a deleted calendar wait in the trash for their owners to restore them.
+		switch {
+		case errors.Is(err, storage.ErrCalendarNotFound):
+		case err != nil:
+			return "", err
+		default:
+			if access, err = a.calendarAccess(ctx, calendar); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if allows(access, storage.AccessRead) {
+		return access, nil
+	}
+
+	_, err := a.storage.GetAttendee(ctx, event.ID, userID)
+	if errors.Is(err, storage.ErrAttendeeNotFound) {
+		return access, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return storage.AccessRead, nil
+}
+
+// requireEventAccess returns the event if the user performing the request has the required access to it.
+func (a *App) requireEventAccess(
+	ctx context.Context, id string, required storage.AccessLevel,
+) (storage.Event, error) {
+	event, err := a.storage.GetEvent(ctx, id)
+	if err != nil {
+		return event, err
+	}
+
+	access, err := a.eventAccess(ctx, event)
+	if err != nil {
+		return event, err
+	}
+
+	if !allows(access, required) {
+		return event, ErrPermissionDenied
+	}
+
+	return event, nil
+}
+
+// busyEvents hides everything but the time of events, which is all free/busy access shows.
+func busyEvents(events []storage.Event) []storage.Event {
+	for i, e := range events {
+		events[i] = storage.Event{
+			ID:         e.ID,
+			StartsAt:   e.StartsAt,
+			Duration:   e.Duration,
+			OwnerID:    e.OwnerID,
+			CalendarID: e.CalendarID,
+			AllDay:     e.AllDay,
+		}
+	}
+
+	return events
+}
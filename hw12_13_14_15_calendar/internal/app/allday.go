@@ -0,0 +1,53 @@
+package app
+
+import (
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const day = 24 * time.Hour
+
+// normalizeAllDay turns an all-day event into whole days from UTC midnight of the date it starts on
+// in the location of its start, it lasts a day at least.
+func normalizeAllDay(event *storage.Event) {
+	if !event.AllDay {
+		return
+	}
+
+	start := event.StartsAt
+	event.StartsAt = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	days := int((event.Duration + day - 1) / day)
+	if days < 1 {
+		days = 1
+	}
+
+	event.Duration = time.Duration(days) * day
+}
+
+// localDays moves all-day events to the same dates of the location, so they start at its midnight
+// and last until the midnight after their last date, which makes days shorter or longer around DST changes.
+func localDays(events []storage.Event, loc *time.Location) []storage.Event {
+	for i, e := range events {
+		if !e.AllDay {
+			continue
+		}
+
+		dates := localDates(storage.Interval{Start: e.StartsAt, End: e.StartsAt.Add(e.Duration)}, loc)
+		events[i].StartsAt = dates.Start
+		events[i].Duration = dates.End.Sub(dates.Start)
+	}
+
+	return events
+}
+
+// localDates moves whole days kept as UTC midnights to the same dates of the location.
+func localDates(i storage.Interval, loc *time.Location) storage.Interval {
+	start, end := i.Start.UTC(), i.End.UTC()
+
+	return storage.Interval{
+		Start: time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc),
+		End:   time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc),
+	}
+}
@@ -2,12 +2,20 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
 )
 
+var (
+	ErrUserIDRequired   = errors.New("user id is required")
+	ErrInvalidTimeZone  = errors.New("invalid time zone")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrOwnerInvited     = errors.New("event owner can't be invited")
+)
+
 type App struct {
 	logger  Logger
 	storage Storage
@@ -19,40 +27,474 @@ type Logger interface {
 }
 
 type Storage interface {
-	CreateEvent(ctx context.Context, event storage.Event) error
-	UpdateEvent(ctx context.Context, id string, event storage.Event) error
-	DeleteEvent(ctx context.Context, id string) error
-	ListDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
-	ListWeekEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
-	ListMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
+	CreateEvent(ctx context.Context, event storage.Event, log storage.ChangeLog) error
+	UpdateEvent(ctx context.Context, id string, event storage.Event, log storage.ChangeLog) error
+	DeleteEvent(ctx context.Context, id string, log storage.ChangeLog) error
+	RestoreEvent(ctx context.Context, id string, log storage.ChangeLog) error
+	PurgeDeletedEvents(ctx context.Context, before time.Time) (int, error)
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
+	GetDeletedEvent(ctx context.Context, id string) (storage.Event, error)
+	ListDeletedEvents(ctx context.Context, filter storage.EventFilter) ([]storage.Event, error)
+	ListDayEvents(ctx context.Context, date time.Time, filter storage.EventFilter) ([]storage.Event, error)
+	ListWeekEvents(
+		ctx context.Context, date time.Time, firstDay time.Weekday, filter storage.EventFilter,
+	) ([]storage.Event, error)
+	ListMonthEvents(ctx context.Context, date time.Time, filter storage.EventFilter) ([]storage.Event, error)
+	SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.Event, error)
+	GetUserSettings(ctx context.Context, userID string) (storage.UserSettings, error)
+	SaveUserSettings(ctx context.Context, settings storage.UserSettings) error
+	SaveAttendee(ctx context.Context, attendee storage.Attendee) error
+	SetAttendeeStatus(ctx context.Context, eventID, userID string, status storage.AttendeeStatus) error
+	RemoveAttendee(ctx context.Context, eventID, userID string) error
+	GetAttendee(ctx context.Context, eventID, userID string) (storage.Attendee, error)
+	ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error)
+	ListBusyIntervals(ctx context.Context, userIDs []string, from, to time.Time) ([]storage.BusyInterval, error)
+	CreateCalendar(ctx context.Context, calendar storage.Calendar) error
+	UpdateCalendar(ctx context.Context, calendar storage.Calendar) error
+	DeleteCalendar(ctx context.Context, id string, logTrashed storage.LogTrashed) ([]storage.Event, error)
+	GetCalendar(ctx context.Context, id string) (storage.Calendar, error)
+	ListCalendars(ctx context.Context, userID string) ([]storage.Calendar, error)
+	SaveShare(ctx context.Context, share storage.CalendarShare) error
+	RemoveShare(ctx context.Context, calendarID, userID string) error
+	GetShare(ctx context.Context, calendarID, userID string) (storage.CalendarShare, error)
+	ListShares(ctx context.Context, calendarID string) ([]storage.CalendarShare, error)
+	CreateReminder(ctx context.Context, reminder storage.Reminder) error
+	DeleteReminder(ctx context.Context, eventID, id string) error
+	ListReminders(ctx context.Context, eventID string) ([]storage.Reminder, error)
+	ListDueReminders(ctx context.Context, from, to time.Time) ([]storage.Reminder, error)
+	MarkReminderFired(ctx context.Context, id string, firedAt time.Time) error
+	FireReminder(
+		ctx context.Context, id string, firedAt time.Time,
+		notifications []storage.Notification, messages []storage.OutboxMessage,
+	) error
+	ListPendingOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id string, sentAt time.Time) error
+	PurgeSentOutbox(ctx context.Context, before time.Time) (int, error)
+	GetNotification(ctx context.Context, id string) (storage.Notification, error)
+	UpdateNotification(ctx context.Context, notification storage.Notification) error
+	ResendNotification(ctx context.Context, id string, message storage.OutboxMessage) error
+	ListNotifications(ctx context.Context, filter storage.NotificationFilter) ([]storage.Notification, error)
+	AppendAuditRecord(ctx context.Context, record storage.AuditRecord) error
+	ListAuditRecords(ctx context.Context, eventID string) ([]storage.AuditRecord, error)
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	GetWebhook(ctx context.Context, id string) (storage.Webhook, error)
+	ListWebhooks(ctx context.Context, ownerID string) ([]storage.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+	UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]storage.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID string) ([]storage.WebhookDelivery, error)
+	LastListChange(ctx context.Context, scopes []string) (time.Time, error)
+}
+
+// ListOptions override user settings the listed period is computed with.
+type ListOptions struct {
+	TimeZone       string
+	FirstDayOfWeek *time.Weekday
+	// CalendarID limits the list to events of the calendar.
+	CalendarID string
+	// Tags limits the list to events having any or, if TagsMatch is storage.TagsAll, all of the tags.
+	Tags      []string
+	TagsMatch storage.TagsMatch
 }
 
 func New(logger Logger, storage Storage) *App {
 	return &App{logger, storage}
 }
 
-func (a *App) CreateEvent(ctx context.Context, id, title string) error {
-	ownerID := uuid.New()
+// CreateEvent creates the event owned by the user performing the request,
+// events created anonymously get a random owner. Creating events in a calendar requires write access to it.
+func (a *App) CreateEvent(ctx context.Context, event storage.Event) error {
+	if event.CalendarID != "" {
+		if _, _, err := a.requireCalendarAccess(ctx, event.CalendarID, storage.AccessWrite); err != nil {
+			return err
+		}
+	}
+
+	// events created anonymously have no owner and stay open to anonymous requests.
+	event.OwnerID, _ = UserIDFromContext(ctx)
+
+	event.Tags = normalizeTags(event.Tags)
+	normalizeAllDay(&event)
+
+	// the event has no attendees to tell about it yet.
+	log, err := changeLog(ctx, storage.AuditCreate, event.ID, storage.Event{}, event, nil)
+	if err != nil {
+		return err
+	}
 
-	return a.storage.CreateEvent(ctx, storage.Event{ID: id, Title: title, OwnerID: ownerID.String()})
+	if err := a.storage.CreateEvent(ctx, event, log); err != nil {
+		return err
+	}
+
+	a.notifyWebhooks(ctx, log, storage.Event{}, event)
+
+	return nil
+}
+
+func (a *App) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	return a.requireEventAccess(ctx, id, storage.AccessRead)
 }
 
+// UpdateEvent replaces the event details, the owner is kept as is.
+// The calendar is kept as well unless the event is moved to another one the user may write to.
 func (a *App) UpdateEvent(ctx context.Context, id string, event storage.Event) error {
-	return a.storage.UpdateEvent(ctx, id, event)
+	prev, err := a.requireEventAccess(ctx, id, storage.AccessWrite)
+	if err != nil {
+		return err
+	}
+
+	event.OwnerID = prev.OwnerID
+	event.Tags = normalizeTags(event.Tags)
+	normalizeAllDay(&event)
+
+	if event.CalendarID == "" {
+		event.CalendarID = prev.CalendarID
+	} else if event.CalendarID != prev.CalendarID {
+		if _, _, err := a.requireCalendarAccess(ctx, event.CalendarID, storage.AccessWrite); err != nil {
+			return err
+		}
+	}
+
+	event.ID = id
+
+	attendees, err := a.storage.ListAttendees(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	log, err := changeLog(ctx, storage.AuditUpdate, id, prev, event, attendees)
+	if err != nil {
+		return err
+	}
+
+	if err := a.storage.UpdateEvent(ctx, id, event, log); err != nil {
+		return err
+	}
+
+	a.notifyWebhooks(ctx, log, prev, event)
+
+	return nil
 }
 
+// DeleteEvent moves the event to the trash, it may be restored until the trash is purged.
 func (a *App) DeleteEvent(ctx context.Context, id string) error {
-	return a.storage.DeleteEvent(ctx, id)
+	event, err := a.requireEventAccess(ctx, id, storage.AccessWrite)
+	if err != nil {
+		return err
+	}
+
+	attendees, err := a.storage.ListAttendees(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	log, err := changeLog(ctx, storage.AuditDelete, id, event, storage.Event{}, attendees)
+	if err != nil {
+		return err
+	}
+
+	if err := a.storage.DeleteEvent(ctx, id, log); err != nil {
+		return err
+	}
+
+	a.notifyWebhooks(ctx, log, event, storage.Event{})
+
+	return nil
+}
+
+// RestoreEvent takes the event out of the trash, it takes the same access as deleting it.
+func (a *App) RestoreEvent(ctx context.Context, id string) (storage.Event, error) {
+	event, err := a.storage.GetDeletedEvent(ctx, id)
+	if err != nil {
+		return event, err
+	}
+
+	access, err := a.eventAccess(ctx, event)
+	if err != nil {
+		return event, err
+	}
+
+	if !allows(access, storage.AccessWrite) {
+		return event, ErrPermissionDenied
+	}
+
+	event.DeletedAt = nil
+
+	// the event is taken out of its calendar if the calendar is deleted.
+	if event.CalendarID != "" {
+		if _, err := a.storage.GetCalendar(ctx, event.CalendarID); errors.Is(err, storage.ErrCalendarNotFound) {
+			event.CalendarID = ""
+		} else if err != nil {
+			return event, err
+		}
+	}
+
+	log, err := changeLog(ctx, storage.AuditRestore, id, storage.Event{}, event, nil)
+	if err != nil {
+		return event, err
+	}
+
+	if err := a.storage.RestoreEvent(ctx, id, log); err != nil {
+		return event, err
+	}
+
+	a.notifyWebhooks(ctx, log, storage.Event{}, event)
+
+	return event, nil
+}
+
+// ListDeletedEvents returns trashed events the user performing the request may restore,
+// only tags of the filter are taken.
+func (a *App) ListDeletedEvents(ctx context.Context, filter storage.EventFilter) ([]storage.Event, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUserIDRequired
+	}
+
+	events, err := a.storage.ListDeletedEvents(ctx, withTags(storage.EventFilter{UserID: userID}, filter))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]storage.Event, 0, len(events))
+
+	for _, e := range events {
+		access, err := a.eventAccess(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if allows(access, storage.AccessWrite) {
+			res = append(res, e)
+		}
+	}
+
+	return res, nil
 }
 
-func (a *App) ListDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	return a.storage.ListDayEvents(ctx, date)
+func (a *App) ListDayEvents(ctx context.Context, date time.Time, opts ListOptions) ([]storage.Event, error) {
+	scope, err := a.listScope(ctx, date, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := a.storage.ListDayEvents(ctx, date.In(scope.loc), scope.filter)
+
+	return scope.visible(events), err
 }
 
-func (a *App) ListWeekEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	return a.storage.ListWeekEvents(ctx, date)
+func (a *App) ListWeekEvents(ctx context.Context, date time.Time, opts ListOptions) ([]storage.Event, error) {
+	scope, err := a.listScope(ctx, date, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := a.storage.ListWeekEvents(ctx, date.In(scope.loc), scope.settings.FirstDayOfWeek, scope.filter)
+
+	return scope.visible(events), err
 }
 
-func (a *App) ListMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	return a.storage.ListMonthEvents(ctx, date)
+func (a *App) ListMonthEvents(ctx context.Context, date time.Time, opts ListOptions) ([]storage.Event, error) {
+	scope, err := a.listScope(ctx, date, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := a.storage.ListMonthEvents(ctx, date.In(scope.loc), scope.filter)
+
+	return scope.visible(events), err
+}
+
+// InviteAttendee invites the attendee to an event the user performing the request may write to.
+// Inviting again changes the role and email, while the response given so far is kept.
+func (a *App) InviteAttendee(ctx context.Context, attendee storage.Attendee) (storage.Attendee, error) {
+	event, err := a.managedEvent(ctx, attendee.EventID)
+	if err != nil {
+		return attendee, err
+	}
+
+	if attendee.UserID == event.OwnerID {
+		return attendee, ErrOwnerInvited
+	}
+
+	if attendee.Role == "" {
+		attendee.Role = storage.RoleRequired
+	}
+
+	attendee.Status = storage.StatusNeedsAction
+
+	if err := a.storage.SaveAttendee(ctx, attendee); err != nil {
+		return attendee, err
+	}
+
+	return a.storage.GetAttendee(ctx, attendee.EventID, attendee.UserID)
+}
+
+// RemoveAttendee removes the attendee from an event the user performing the request may write to.
+func (a *App) RemoveAttendee(ctx context.Context, eventID, userID string) error {
+	if _, err := a.managedEvent(ctx, eventID); err != nil {
+		return err
+	}
+
+	return a.storage.RemoveAttendee(ctx, eventID, userID)
+}
+
+// RespondToInvitation saves the response of the user performing the request to the invitation.
+func (a *App) RespondToInvitation(
+	ctx context.Context, eventID string, status storage.AttendeeStatus,
+) (storage.Attendee, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return storage.Attendee{}, ErrUserIDRequired
+	}
+
+	if err := a.storage.SetAttendeeStatus(ctx, eventID, userID, status); err != nil {
+		return storage.Attendee{}, err
+	}
+
+	return a.storage.GetAttendee(ctx, eventID, userID)
+}
+
+func (a *App) ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error) {
+	if _, err := a.requireEventAccess(ctx, eventID, storage.AccessRead); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListAttendees(ctx, eventID)
+}
+
+// managedEvent returns the event if the user performing the request may change it and its attendees.
+func (a *App) managedEvent(ctx context.Context, id string) (storage.Event, error) {
+	if _, ok := UserIDFromContext(ctx); !ok {
+		return storage.Event{}, ErrUserIDRequired
+	}
+
+	return a.requireEventAccess(ctx, id, storage.AccessWrite)
+}
+
+func (a *App) GetUserSettings(ctx context.Context) (storage.UserSettings, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return storage.UserSettings{}, ErrUserIDRequired
+	}
+
+	return a.userSettings(ctx, userID)
+}
+
+func (a *App) UpdateUserSettings(ctx context.Context, settings storage.UserSettings) (storage.UserSettings, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return settings, ErrUserIDRequired
+	}
+
+	if _, err := loadLocation(settings.TimeZone); err != nil {
+		return settings, err
+	}
+
+	settings.UserID = userID
+
+	return settings, a.storage.SaveUserSettings(ctx, settings)
+}
+
+// userSettings returns settings saved by the user or defaults when there are none.
+func (a *App) userSettings(ctx context.Context, userID string) (storage.UserSettings, error) {
+	settings, err := a.storage.GetUserSettings(ctx, userID)
+	if errors.Is(err, storage.ErrUserSettingsNotFound) {
+		return storage.UserSettings{UserID: userID, FirstDayOfWeek: time.Monday}, nil
+	}
+
+	return settings, err
+}
+
+// listScope is what a list request covers and how its period is computed.
+type listScope struct {
+	settings storage.UserSettings
+	loc      *time.Location
+	filter   storage.EventFilter
+	// access is the access to the listed calendar, if any.
+	access storage.AccessLevel
+}
+
+// visible hides details of events the scope only allows to see as busy time
+// and shows all-day events as days of the scope time zone.
+func (s listScope) visible(events []storage.Event) []storage.Event {
+	events = localDays(events, s.loc)
+
+	if s.access == storage.AccessFreeBusy {
+		return busyEvents(events)
+	}
+
+	return events
+}
+
+// listScope resolves the listed events and the period settings from options, falling back to
+// the listed calendar and then to user settings. Without a time zone anywhere the location of date is kept.
+// Lists of a calendar require any access to it, others are limited to events of the user performing
+// the request while anonymous requests list every event.
+func (a *App) listScope(ctx context.Context, date time.Time, opts ListOptions) (listScope, error) {
+	scope := listScope{settings: storage.UserSettings{FirstDayOfWeek: time.Monday}}
+	userID, ok := UserIDFromContext(ctx)
+
+	if ok {
+		var err error
+
+		if scope.settings, err = a.userSettings(ctx, userID); err != nil {
+			return scope, err
+		}
+	}
+
+	if opts.CalendarID != "" {
+		calendar, access, err := a.requireCalendarAccess(ctx, opts.CalendarID, storage.AccessFreeBusy)
+		if err != nil {
+			return scope, err
+		}
+
+		if calendar.TimeZone != "" {
+			scope.settings.TimeZone = calendar.TimeZone
+		}
+
+		scope.access = access
+		scope.filter = storage.EventFilter{CalendarID: calendar.ID}
+	} else {
+		scope.filter = storage.EventFilter{UserID: userID}
+	}
+
+	scope.filter = withTags(scope.filter, storage.EventFilter{Tags: opts.Tags, TagsMatch: opts.TagsMatch})
+
+	// tags are hidden from free/busy access, so they can't tell events apart either.
+	if scope.access == storage.AccessFreeBusy && len(scope.filter.Tags) > 0 {
+		return scope, ErrPermissionDenied
+	}
+
+	if opts.TimeZone != "" {
+		scope.settings.TimeZone = opts.TimeZone
+	}
+
+	if opts.FirstDayOfWeek != nil {
+		scope.settings.FirstDayOfWeek = *opts.FirstDayOfWeek
+	}
+
+	if scope.settings.TimeZone == "" {
+		scope.loc = date.Location()
+
+		return scope, nil
+	}
+
+	var err error
+
+	scope.loc, err = loadLocation(scope.settings.TimeZone)
+
+	return scope, err
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	// empty name means UTC for time.LoadLocation, it is accepted as "not set",
+	// while the server local zone means nothing to clients.
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimeZone, name)
+	}
+
+	return loc, nil
 }
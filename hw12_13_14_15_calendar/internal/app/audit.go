@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// changeLog tells about the change made by the user performing the request,
+// the storage writes it to the event history and the notices to the attendees along with the change itself.
+func changeLog(
+	ctx context.Context, op storage.AuditOperation, id string, before, after storage.Event,
+	attendees []storage.Attendee,
+) (storage.ChangeLog, error) {
+	actorID, _ := UserIDFromContext(ctx)
+	record := storage.AuditRecord{
+		ID:        uuid.New().String(),
+		EventID:   id,
+		ActorID:   actorID,
+		Operation: op,
+		CreatedAt: time.Now().UTC(),
+		Changes:   storage.DiffEvents(before, after),
+	}
+
+	event := after
+	if op == storage.AuditDelete {
+		event = before
+	}
+
+	notifications, messages, err := changeNotices(record, event, attendees)
+	if err != nil {
+		return storage.ChangeLog{}, err
+	}
+
+	return storage.ChangeLog{
+		Audit:         []storage.AuditRecord{record},
+		Notifications: notifications,
+		Outbox:        messages,
+	}, nil
+}
+
+// notifyWebhooks queues deliveries of the logged change to webhooks subscribed to it.
+// Failing to do so is only logged, since the change itself has been made already.
+func (a *App) notifyWebhooks(ctx context.Context, log storage.ChangeLog, before, after storage.Event) {
+	for _, record := range log.Audit {
+		event := after
+		if record.Operation == storage.AuditDelete {
+			event = before
+		}
+
+		if err := a.queueWebhookDeliveries(ctx, record, event); err != nil {
+			a.logger.Error(fmt.Sprintf(
+				"failed to queue webhook deliveries about %s of event %s: %s", record.Operation, record.EventID, err,
+			))
+		}
+	}
+}
+
+// ListEventHistory returns changes of an event the user performing the request may read, the oldest first.
+func (a *App) ListEventHistory(ctx context.Context, id string) ([]storage.AuditRecord, error) {
+	if _, err := a.requireEventAccess(ctx, id, storage.AccessRead); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListAuditRecords(ctx, id)
+}
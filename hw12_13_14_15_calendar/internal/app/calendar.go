@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrOwnerShared = errors.New("calendar can't be shared with its owner")
+
+// UserCalendar is a calendar along with the access the user performing the request has to it.
+type UserCalendar struct {
+	storage.Calendar
+	Access storage.AccessLevel
+}
+
+// CreateCalendar creates the calendar owned by the user performing the request.
+func (a *App) CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return calendar, ErrUserIDRequired
+	}
+
+	if _, err := loadLocation(calendar.TimeZone); err != nil {
+		return calendar, err
+	}
+
+	calendar.OwnerID = userID
+
+	return calendar, a.storage.CreateCalendar(ctx, calendar)
+}
+
+func (a *App) GetCalendar(ctx context.Context, id string) (UserCalendar, error) {
+	calendar, access, err := a.requireCalendarAccess(ctx, id, storage.AccessFreeBusy)
+
+	return UserCalendar{calendar, access}, err
+}
+
+// UpdateCalendar replaces the calendar details, only the owner may do it.
+func (a *App) UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error) {
+	prev, _, err := a.requireCalendarAccess(ctx, calendar.ID, storage.AccessOwner)
+	if err != nil {
+		return calendar, err
+	}
+
+	if _, err := loadLocation(calendar.TimeZone); err != nil {
+		return calendar, err
+	}
+
+	calendar.OwnerID = prev.OwnerID
+
+	return calendar, a.storage.UpdateCalendar(ctx, calendar)
+}
+
+// DeleteCalendar deletes the calendar, only the owner may do it.
+// Its events are moved to the trash, so their owners may restore them out of the calendar.
+// Every trashed event gets its deletion in the history, told to attendees and delivered to webhooks,
+// as if deleted one by one.
+func (a *App) DeleteCalendar(ctx context.Context, id string) error {
+	if _, _, err := a.requireCalendarAccess(ctx, id, storage.AccessOwner); err != nil {
+		return err
+	}
+
+	logs := map[string]storage.ChangeLog{}
+
+	events, err := a.storage.DeleteCalendar(ctx, id, func(
+		event storage.Event, attendees []storage.Attendee,
+	) (storage.ChangeLog, error) {
+		log, err := changeLog(ctx, storage.AuditDelete, event.ID, event, storage.Event{}, attendees)
+		logs[event.ID] = log
+
+		return log, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		a.notifyWebhooks(ctx, logs[event.ID], event, storage.Event{})
+	}
+
+	return nil
+}
+
+// ListCalendars lists calendars the user performing the request owns or has been shared.
+func (a *App) ListCalendars(ctx context.Context) ([]UserCalendar, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUserIDRequired
+	}
+
+	calendars, err := a.storage.ListCalendars(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]UserCalendar, 0, len(calendars))
+
+	for _, c := range calendars {
+		access, err := a.calendarAccess(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, UserCalendar{c, access})
+	}
+
+	return res, nil
+}
+
+// ShareCalendar shares a calendar of the user performing the request, sharing again changes the access.
+func (a *App) ShareCalendar(ctx context.Context, share storage.CalendarShare) error {
+	calendar, _, err := a.requireCalendarAccess(ctx, share.CalendarID, storage.AccessOwner)
+	if err != nil {
+		return err
+	}
+
+	if share.UserID == calendar.OwnerID {
+		return ErrOwnerShared
+	}
+
+	return a.storage.SaveShare(ctx, share)
+}
+
+// UnshareCalendar takes away the access to a calendar of the user performing the request.
+func (a *App) UnshareCalendar(ctx context.Context, calendarID, userID string) error {
+	if _, _, err := a.requireCalendarAccess(ctx, calendarID, storage.AccessOwner); err != nil {
+		return err
+	}
+
+	return a.storage.RemoveShare(ctx, calendarID, userID)
+}
+
+// ListCalendarShares lists whom a calendar of the user performing the request is shared with.
+func (a *App) ListCalendarShares(ctx context.Context, calendarID string) ([]storage.CalendarShare, error) {
+	if _, _, err := a.requireCalendarAccess(ctx, calendarID, storage.AccessOwner); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListShares(ctx, calendarID)
+}
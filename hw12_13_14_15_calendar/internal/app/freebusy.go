@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrInvalidRange = errors.New("range must end after it starts")
+
+// FreeBusy tells when users are busy and when all of them are free within the requested range.
+type FreeBusy struct {
+	// Users hold merged busy intervals in the order users were requested.
+	Users []UserBusy
+	// Free holds common free slots, only when they were asked for.
+	Free []storage.Interval
+}
+
+type UserBusy struct {
+	UserID string
+	Busy   []storage.Interval
+}
+
+// FreeBusy computes busy time of the users within [from, to) from events they own or attend.
+// A positive minFree also looks for slots of at least that length none of the users is busy at.
+// Busy time of others is only shown to users they share a calendar with, every access level includes it.
+func (a *App) FreeBusy(
+	ctx context.Context, userIDs []string, from, to time.Time, minFree time.Duration,
+) (FreeBusy, error) {
+	requesterID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return FreeBusy{}, ErrUserIDRequired
+	}
+
+	if !to.After(from) {
+		return FreeBusy{}, ErrInvalidRange
+	}
+
+	userIDs = uniqueStrings(userIDs)
+
+	if err := a.requireFreeBusyAccess(ctx, requesterID, userIDs); err != nil {
+		return FreeBusy{}, err
+	}
+
+	intervals, err := a.storage.ListBusyIntervals(ctx, userIDs, from, to)
+	if err != nil {
+		return FreeBusy{}, err
+	}
+
+	byUser := make(map[string][]storage.Interval, len(userIDs))
+	locations := make(map[string]*time.Location)
+
+	for _, i := range intervals {
+		if i.AllDay {
+			loc, err := a.ownerLocation(ctx, locations, i.OwnerID)
+			if err != nil {
+				return FreeBusy{}, err
+			}
+
+			i.Interval = localDates(i.Interval, loc)
+		}
+
+		if i.Interval = clipInterval(i.Interval, from, to); i.End.After(i.Start) {
+			byUser[i.UserID] = append(byUser[i.UserID], i.Interval)
+		}
+	}
+
+	res := FreeBusy{Users: make([]UserBusy, 0, len(userIDs))}
+
+	var all []storage.Interval
+
+	for _, userID := range userIDs {
+		// all-day events moved to time zones of their owners may come out of order.
+		sortIntervals(byUser[userID])
+
+		busy := mergeIntervals(byUser[userID])
+		res.Users = append(res.Users, UserBusy{UserID: userID, Busy: busy})
+		all = append(all, busy...)
+	}
+
+	if minFree > 0 {
+		sortIntervals(all)
+
+		res.Free = freeSlots(mergeIntervals(all), from, to, minFree)
+	}
+
+	return res, nil
+}
+
+// requireFreeBusyAccess checks the user may see busy time of the others, that is
+// each of them shares a calendar with the user.
+func (a *App) requireFreeBusyAccess(ctx context.Context, userID string, userIDs []string) error {
+	calendars, err := a.storage.ListCalendars(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	sharing := map[string]struct{}{userID: {}}
+
+	for _, c := range calendars {
+		sharing[c.OwnerID] = struct{}{}
+	}
+
+	for _, id := range userIDs {
+		if _, ok := sharing[id]; !ok {
+			return ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
+
+// ownerLocation returns the time zone of the user settings, UTC when there is none.
+// Locations are cached, so every owner is looked up once per request.
+func (a *App) ownerLocation(
+	ctx context.Context, locations map[string]*time.Location, ownerID string,
+) (*time.Location, error) {
+	if loc, ok := locations[ownerID]; ok {
+		return loc, nil
+	}
+
+	settings, err := a.userSettings(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := loadLocation(settings.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	locations[ownerID] = loc
+
+	return loc, nil
+}
+
+func sortIntervals(intervals []storage.Interval) {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+}
+
+// mergeIntervals joins overlapping and adjacent intervals, they must be ordered by start.
+func mergeIntervals(intervals []storage.Interval) []storage.Interval {
+	merged := []storage.Interval{}
+
+	for _, i := range intervals {
+		last := len(merged) - 1
+
+		if last >= 0 && !i.Start.After(merged[last].End) {
+			if i.End.After(merged[last].End) {
+				merged[last].End = i.End
+			}
+
+			continue
+		}
+
+		merged = append(merged, i)
+	}
+
+	return merged
+}
+
+// freeSlots returns gaps between merged busy intervals within [from, to) lasting at least minLength.
+func freeSlots(busy []storage.Interval, from, to time.Time, minLength time.Duration) []storage.Interval {
+	slots := []storage.Interval{}
+	start := from
+
+	for _, i := range append(busy, storage.Interval{Start: to, End: to}) {
+		if i.Start.Sub(start) >= minLength {
+			slots = append(slots, storage.Interval{Start: start, End: i.Start})
+		}
+
+		if i.End.After(start) {
+			start = i.End
+		}
+	}
+
+	return slots
+}
+
+func clipInterval(i storage.Interval, from, to time.Time) storage.Interval {
+	if i.Start.Before(from) {
+		i.Start = from
+	}
+
+	if i.End.After(to) {
+		i.End = to
+	}
+
+	return i
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	res := make([]string, 0, len(values))
+
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
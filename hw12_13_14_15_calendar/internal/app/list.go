@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrInvalidView = errors.New("invalid view")
+
+// View is the period events are listed for.
+type View string
+
+const (
+	ViewDay   View = "day"
+	ViewWeek  View = "week"
+	ViewMonth View = "month"
+)
+
+// ListEvents lists events of the view period containing the calendar date of day, which is taken as is
+// in the time zone of the list rather than converted to it.
+func (a *App) ListEvents(ctx context.Context, view View, day time.Time, opts ListOptions) ([]storage.Event, error) {
+	scope, err := a.listScope(ctx, day, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, scope.loc)
+
+	var events []storage.Event
+
+	switch view {
+	case ViewDay:
+		events, err = a.storage.ListDayEvents(ctx, date, scope.filter)
+	case ViewWeek:
+		events, err = a.storage.ListWeekEvents(ctx, date, scope.settings.FirstDayOfWeek, scope.filter)
+	case ViewMonth:
+		events, err = a.storage.ListMonthEvents(ctx, date, scope.filter)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidView, view)
+	}
+
+	return scope.visible(events), err
+}
+
+// ListChangedAt returns when events the list with the options shows were changed last, the zero time if never.
+// It checks the view and access the same way ListEvents does, but doesn't list events.
+func (a *App) ListChangedAt(ctx context.Context, view View, opts ListOptions) (time.Time, error) {
+	switch view {
+	case ViewDay, ViewWeek, ViewMonth:
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidView, view)
+	}
+
+	if _, err := a.listScope(ctx, time.Time{}, opts); err != nil {
+		return time.Time{}, err
+	}
+
+	userID, _ := UserIDFromContext(ctx)
+
+	return a.storage.LastListChange(ctx, storage.ListScopes(userID, opts.CalendarID))
+}
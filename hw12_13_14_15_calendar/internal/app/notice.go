@@ -0,0 +1,82 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// changeNotices returns records of notices telling attendees about the recorded change
+// along with the outbox messages publishing them. Attendees who declined the invitation
+// and the one making the change aren't told, neither is anyone about changes which change nothing.
+func changeNotices(
+	record storage.AuditRecord, event storage.Event, attendees []storage.Attendee,
+) ([]storage.Notification, []storage.OutboxMessage, error) {
+	var message string
+
+	switch {
+	case record.Operation == storage.AuditUpdate && len(record.Changes) > 0:
+		message = fmt.Sprintf("%q has been changed", event.Title)
+	case record.Operation == storage.AuditDelete:
+		message = fmt.Sprintf("%q has been cancelled", event.Title)
+	default:
+		return nil, nil, nil
+	}
+
+	var (
+		notifications []storage.Notification
+		messages      []storage.OutboxMessage
+	)
+
+	for _, a := range attendees {
+		if a.Status == storage.StatusDeclined || a.UserID == record.ActorID {
+			continue
+		}
+
+		// attendees invited without an email are only told in the log.
+		channel := storage.ChannelLog
+		if a.Email != "" {
+			channel = storage.ChannelEmail
+		}
+
+		n := queue.Notification{
+			ID:       uuid.New().String(),
+			EventID:  record.EventID,
+			Title:    event.Title,
+			StartsAt: event.StartsAt,
+			UserID:   a.UserID,
+			Email:    a.Email,
+			Channel:  string(channel),
+			Message:  message,
+			// every change is told once.
+			IdempotencyKey: fmt.Sprintf("%s/%s", record.ID, a.UserID),
+		}
+
+		payload, err := json.Marshal(n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		notifications = append(notifications, storage.Notification{
+			ID:          n.ID,
+			EventID:     n.EventID,
+			UserID:      n.UserID,
+			Channel:     channel,
+			ScheduledAt: record.CreatedAt,
+			Status:      storage.NotificationPending,
+			Payload:     string(payload),
+			CreatedAt:   record.CreatedAt,
+		})
+
+		messages = append(messages, storage.OutboxMessage{
+			ID:        uuid.New().String(),
+			Payload:   string(payload),
+			CreatedAt: record.CreatedAt,
+		})
+	}
+
+	return notifications, messages, nil
+}
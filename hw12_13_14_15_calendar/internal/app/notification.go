@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrNotificationNotFailed = errors.New("only failed notifications can be sent again")
+
+// ListNotifications lists notifications addressed to the user performing the request, the latest scheduled first.
+// Notifications of an event are listed to every recipient once the user may write to the event.
+func (a *App) ListNotifications(
+	ctx context.Context, filter storage.NotificationFilter,
+) ([]storage.Notification, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUserIDRequired
+	}
+
+	filter.UserID = userID
+
+	if filter.EventID != "" {
+		event, err := a.storage.GetEvent(ctx, filter.EventID)
+		if err != nil {
+			return nil, err
+		}
+
+		access, err := a.eventAccess(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allows(access, storage.AccessRead) {
+			return nil, ErrPermissionDenied
+		}
+
+		if allows(access, storage.AccessWrite) {
+			filter.UserID = ""
+		}
+	}
+
+	return a.storage.ListNotifications(ctx, filter)
+}
+
+// ResendNotification publishes a failed notification once again, it's up to its recipient
+// and the users who may write to its event. The notification is pending until the next attempt.
+func (a *App) ResendNotification(ctx context.Context, id string) (storage.Notification, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return storage.Notification{}, ErrUserIDRequired
+	}
+
+	notification, err := a.storage.GetNotification(ctx, id)
+	if err != nil {
+		return notification, err
+	}
+
+	if notification.UserID != userID {
+		if _, err := a.requireEventAccess(ctx, notification.EventID, storage.AccessWrite); err != nil {
+			return notification, err
+		}
+	}
+
+	if notification.Status != storage.NotificationFailed {
+		return notification, ErrNotificationNotFailed
+	}
+
+	// the idempotency key is left as is, the sender only drops copies of delivered notifications.
+	message := storage.OutboxMessage{
+		ID:        uuid.New().String(),
+		Payload:   notification.Payload,
+		CreatedAt: time.Now(),
+	}
+
+	if err := a.storage.ResendNotification(ctx, id, message); err != nil {
+		return notification, err
+	}
+
+	notification.Status = storage.NotificationPending
+
+	return notification, nil
+}
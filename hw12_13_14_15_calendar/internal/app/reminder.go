@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// AddReminder adds the reminder to an event the user performing the request may write to,
+// reminders are delivered to the log unless another channel is set.
+func (a *App) AddReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error) {
+	event, err := a.requireEventAccess(ctx, reminder.EventID, storage.AccessWrite)
+	if err != nil {
+		return reminder, err
+	}
+
+	if reminder.Channel == "" {
+		reminder.Channel = storage.ChannelLog
+	}
+
+	reminder.RemindAt = storage.RemindAt(event, reminder)
+	reminder.FiredAt = nil
+
+	return reminder, a.storage.CreateReminder(ctx, reminder)
+}
+
+// RemoveReminder removes the reminder from an event the user performing the request may write to.
+func (a *App) RemoveReminder(ctx context.Context, eventID, id string) error {
+	if _, err := a.requireEventAccess(ctx, eventID, storage.AccessWrite); err != nil {
+		return err
+	}
+
+	return a.storage.DeleteReminder(ctx, eventID, id)
+}
+
+func (a *App) ListReminders(ctx context.Context, eventID string) ([]storage.Reminder, error) {
+	if _, err := a.requireEventAccess(ctx, eventID, storage.AccessRead); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListReminders(ctx, eventID)
+}
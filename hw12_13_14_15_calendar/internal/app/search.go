@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// defaultSearchLimit is how many events are found unless asked otherwise.
+const defaultSearchLimit = 20
+
+// SearchEvents finds events the user performing the request may read by words of their titles and descriptions,
+// the best matching ones first. Only tags of the query filter are taken.
+func (a *App) SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.Event, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUserIDRequired
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && !query.To.After(query.From) {
+		return nil, ErrInvalidRange
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+
+	query.Filter = withTags(storage.EventFilter{UserID: userID}, query.Filter)
+
+	return a.storage.SearchEvents(ctx, query)
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// normalizeTags lowercases and sorts tags dropping duplicates, so tags differing in case are the same tag.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			res = append(res, tag)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// withTags narrows the filter down to events tagged the way the other filter asks for.
+func withTags(filter, tags storage.EventFilter) storage.EventFilter {
+	filter.Tags = normalizeTags(tags.Tags)
+	filter.TagsMatch = tags.TagsMatch
+
+	return filter
+}
@@ -0,0 +1,17 @@
+package app
+
+import "context"
+
+type userIDKey struct{}
+
+// ContextWithUserID attaches ID of the user performing the request.
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext returns ID of the user performing the request, if known.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+
+	return userID, ok && userID != ""
+}
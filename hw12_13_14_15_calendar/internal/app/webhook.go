@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+
+// WebhookPayload is the JSON posted to webhooks about a change of an event.
+type WebhookPayload struct {
+	DeliveryID string                 `json:"deliveryId"`
+	Operation  storage.AuditOperation `json:"operation"`
+	// ActorID is empty for anonymous changes.
+	ActorID    string    `json:"actorId,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+	// Event is the event after the change, or before it once deleted.
+	Event   WebhookEvent    `json:"event"`
+	Changes storage.Changes `json:"changes"`
+}
+
+type WebhookEvent struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	StartsAt    time.Time `json:"startsAt"`
+	Duration    string    `json:"duration"`
+	Description string    `json:"description,omitempty"`
+	OwnerID     string    `json:"ownerId"`
+	CalendarID  string    `json:"calendarId,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Color       string    `json:"color,omitempty"`
+	AllDay      bool      `json:"allDay,omitempty"`
+}
+
+// CreateWebhook subscribes the webhook to changes of events owned by the user performing the request.
+func (a *App) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return webhook, ErrUserIDRequired
+	}
+
+	if !validWebhookURL(webhook.URL) {
+		return webhook, ErrInvalidWebhookURL
+	}
+
+	webhook.OwnerID = userID
+
+	return webhook, a.storage.CreateWebhook(ctx, webhook)
+}
+
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Hostname() != "" && u.User == nil
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries, only the owner may do it.
+func (a *App) DeleteWebhook(ctx context.Context, id string) error {
+	if _, err := a.ownedWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	return a.storage.DeleteWebhook(ctx, id)
+}
+
+// ListWebhooks lists webhooks of the user performing the request.
+func (a *App) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUserIDRequired
+	}
+
+	return a.storage.ListWebhooks(ctx, userID)
+}
+
+// ListWebhookDeliveries lists deliveries of a webhook the user performing the request owns, the latest first.
+func (a *App) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]storage.WebhookDelivery, error) {
+	if _, err := a.ownedWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	return a.storage.ListWebhookDeliveries(ctx, webhookID)
+}
+
+func (a *App) ownedWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	userID, ok := UserIDFromContext(ctx)
+	if !ok {
+		return storage.Webhook{}, ErrUserIDRequired
+	}
+
+	webhook, err := a.storage.GetWebhook(ctx, id)
+	if err != nil {
+		return webhook, err
+	}
+
+	if webhook.OwnerID != userID {
+		return webhook, ErrPermissionDenied
+	}
+
+	return webhook, nil
+}
+
+// queueWebhookDeliveries queues the change recorded to webhooks of the event owner subscribed to it,
+// they are posted by the delivery worker right away.
+func (a *App) queueWebhookDeliveries(ctx context.Context, record storage.AuditRecord, event storage.Event) error {
+	webhooks, err := a.storage.ListWebhooks(ctx, event.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range webhooks {
+		if !w.Subscribed(record.Operation) {
+			continue
+		}
+
+		delivery := storage.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     w.ID,
+			EventID:       record.EventID,
+			Operation:     record.Operation,
+			Status:        storage.DeliveryPending,
+			NextAttemptAt: record.CreatedAt,
+			CreatedAt:     record.CreatedAt,
+		}
+
+		payload, err := json.Marshal(WebhookPayload{
+			DeliveryID: delivery.ID,
+			Operation:  record.Operation,
+			ActorID:    record.ActorID,
+			OccurredAt: record.CreatedAt,
+			Event: WebhookEvent{
+				ID:          record.EventID,
+				Title:       event.Title,
+				StartsAt:    event.StartsAt.UTC(),
+				Duration:    event.Duration.String(),
+				Description: event.Description,
+				OwnerID:     event.OwnerID,
+				CalendarID:  event.CalendarID,
+				Tags:        event.Tags,
+				Color:       event.Color,
+				AllDay:      event.AllDay,
+			},
+			Changes: record.Changes,
+		})
+		if err != nil {
+			return err
+		}
+
+		delivery.Payload = string(payload)
+
+		if err := a.storage.CreateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ClientConfig describes how the command-line client reaches the calendar API.
+type ClientConfig struct {
+	Endpoint    string
+	Timeout     time.Duration
+	Credentials CredentialsConf
+}
+
+type CredentialsConf struct {
+	UserID string
+}
+
+// DefaultClientConfig points to the calendar running locally with the sample config.
+func DefaultClientConfig() *ClientConfig {
+	return &ClientConfig{
+		Endpoint: "localhost:8080",
+		Timeout:  5 * time.Second,
+	}
+}
+
+func ReadClientConfig(path string) (*ClientConfig, error) {
+	cfg := DefaultClientConfig()
+
+	v := viper.New()
+
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read error: %w", err)
+	}
+
+	if err := v.Unmarshal(cfg); err != nil {
+		return cfg, fmt.Errorf("while unmarshal config: %w", err)
+	}
+
+	return cfg, nil
+}
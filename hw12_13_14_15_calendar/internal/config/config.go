@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,9 +11,13 @@ import (
 // Организация конфига в main принуждает нас сужать API компонентов, использовать
 // при их конструировании только необходимые параметры, а также уменьшает вероятность циклической зависимости.
 type Config struct {
-	Logger  LoggerConf
-	Server  ServerConf
-	Storage StorageConfig
+	Logger    LoggerConf
+	Server    ServerConf
+	Storage   StorageConfig
+	Scheduler SchedulerConf
+	Sender    SenderConf
+	Webhooks  WebhooksConf
+	Shutdown  ShutdownConf
 }
 
 type LoggerConf struct {
@@ -20,8 +25,17 @@ type LoggerConf struct {
 }
 
 type StorageConfig struct {
-	Type     string
-	Database DatabaseConfig
+	Type string
+	// AutoMigrate applies pending migrations of sql storages on start.
+	AutoMigrate bool
+	Memory      MemoryConfig
+	Database    DatabaseConfig
+	SQLite      SQLiteConfig
+}
+
+type MemoryConfig struct {
+	Dir              string
+	SnapshotInterval time.Duration
 }
 
 type DatabaseConfig struct {
@@ -29,25 +43,110 @@ type DatabaseConfig struct {
 	Port                     uint16
 }
 
+type SQLiteConfig struct {
+	Path string
+}
+
+type SchedulerConf struct {
+	// Interval is how often due notifications are looked for.
+	Interval time.Duration
+	// TrashRetention is how long deleted events can be restored before they are purged, zero keeps them forever.
+	TrashRetention time.Duration
+	// RelayInterval is how often notifications written to the outbox are published.
+	RelayInterval time.Duration
+	// OutboxRetention is how long published notifications are kept in the outbox, zero keeps them forever.
+	OutboxRetention time.Duration
+}
+
+// SenderConf configures delivery channels besides the log, a channel is off until configured.
+type SenderConf struct {
+	Webhook WebhookConf
+	Email   EmailConf
+}
+
+type WebhookConf struct {
+	URL     string
+	Timeout time.Duration
+}
+
+type EmailConf struct {
+	Host, User, Password, From string
+	Port                       uint16
+}
+
+// WebhooksConf configures delivery of event changes to webhooks.
+type WebhooksConf struct {
+	// Interval is how often due deliveries are looked for.
+	Interval time.Duration
+	// Timeout bounds a single attempt.
+	Timeout     time.Duration
+	MaxAttempts int
+	// MinBackoff is the delay after the first failed attempt, it doubles after every next one up to MaxBackoff.
+	MinBackoff, MaxBackoff time.Duration
+	// AllowPrivateNetworks lets webhooks reach loopback, private and link-local addresses, meant for local setups.
+	AllowPrivateNetworks bool
+}
+
+type ShutdownConf struct {
+	// Timeout bounds draining of in-flight requests, the ones left are cancelled.
+	Timeout time.Duration
+}
+
 type ServerConf struct {
-	HTTP HTTPConf
-	Grpc GrpcConf
+	HTTP  HTTPConf
+	Grpc  GrpcConf
+	Admin AdminConf
 }
 
 type HTTPConf struct {
 	Host, Port string
+	CORS       CORSConf
+	// MaxBodySize bounds request bodies in bytes, zero means no limit.
+	MaxBodySize int64
+}
+
+// CORSConf lets web front ends of other origins call the API, none can while AllowedOrigins is empty.
+type CORSConf struct {
+	AllowedOrigins, AllowedMethods, AllowedHeaders, ExposedHeaders []string
+	AllowCredentials                                               bool
+	MaxAge                                                         time.Duration
 }
 
 type GrpcConf struct {
 	Host, Port string
 }
 
+// AdminConf configures the listener of debug endpoints, it's off while Port is empty.
+// The gRPC server serves reflection and channelz only while it's on.
+type AdminConf struct {
+	Host, Port string
+}
+
 func ReadConfig(path string) (*Config, error) {
 	cfg := &Config{}
 
 	v := viper.New()
 
 	v.SetConfigFile(path)
+	v.SetDefault("storage.autoMigrate", true)
+	v.SetDefault("scheduler.interval", time.Minute)
+	v.SetDefault("scheduler.trashRetention", 30*24*time.Hour)
+	v.SetDefault("scheduler.relayInterval", time.Second)
+	v.SetDefault("scheduler.outboxRetention", 24*time.Hour)
+	v.SetDefault("sender.webhook.timeout", 5*time.Second)
+	v.SetDefault("webhooks.interval", 5*time.Second)
+	v.SetDefault("webhooks.timeout", 10*time.Second)
+	v.SetDefault("webhooks.maxAttempts", 8)
+	v.SetDefault("webhooks.minBackoff", 10*time.Second)
+	v.SetDefault("webhooks.maxBackoff", time.Hour)
+	v.SetDefault("shutdown.timeout", 10*time.Second)
+	v.SetDefault("server.http.maxBodySize", 1<<20)
+	v.SetDefault("server.http.cors.allowedMethods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	v.SetDefault("server.http.cors.allowedHeaders", []string{
+		"Content-Type", "X-User-Id", "If-None-Match", "If-Modified-Since",
+	})
+	v.SetDefault("server.http.cors.exposedHeaders", []string{"ETag", "Last-Modified"})
+	v.SetDefault("server.http.cors.maxAge", 10*time.Minute)
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("read error: %w", err)
@@ -0,0 +1,55 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+type worker struct {
+	run      func(ctx context.Context) error
+	ctx      context.Context
+	cancelFn context.CancelFunc
+}
+
+// Worker adapts a function running until its context is done, it is ready right away.
+func Worker(run func(ctx context.Context) error) Component {
+	ctx, cancelFn := context.WithCancel(context.Background())
+
+	return &worker{run, ctx, cancelFn}
+}
+
+func (w *worker) Run(ready func()) error {
+	ready()
+
+	return w.run(w.ctx)
+}
+
+func (w *worker) Stop(ctx context.Context) error {
+	w.cancelFn()
+
+	return nil
+}
+
+type closer struct {
+	close   func(ctx context.Context) error
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// Closer adapts a resource which is ready right away and only has to be released on shutdown.
+func Closer(close func(ctx context.Context) error) Component {
+	return &closer{close: close, stopped: make(chan struct{})}
+}
+
+func (c *closer) Run(ready func()) error {
+	ready()
+	<-c.stopped
+
+	return nil
+}
+
+func (c *closer) Stop(ctx context.Context) error {
+	c.once.Do(func() { close(c.stopped) })
+
+	return c.close(ctx)
+}
@@ -0,0 +1,196 @@
+// Package lifecycle starts the components of the service in dependency order
+// and stops them in reverse once the service is asked to shut down or any component fails.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrStoppedUnexpectedly = errors.New("stopped unexpectedly")
+
+type Logger interface {
+	Info(msg string)
+	Error(msg string)
+}
+
+// Component is a long running part of the service.
+type Component interface {
+	// Run serves until Stop is called or the component fails.
+	// ready must be called once components started after this one may rely on it.
+	Run(ready func()) error
+	// Stop makes Run return, in-flight work is finished unless ctx is done first.
+	Stop(ctx context.Context) error
+}
+
+type Manager struct {
+	logger          Logger
+	shutdownTimeout time.Duration
+	components      []namedComponent
+	shutdown        chan string
+	ready           chan struct{}
+	done            chan struct{}
+}
+
+type namedComponent struct {
+	name string
+	Component
+}
+
+type runningComponent struct {
+	namedComponent
+	done chan struct{}
+	err  error
+}
+
+func New(logger Logger, shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		logger:          logger,
+		shutdownTimeout: shutdownTimeout,
+		shutdown:        make(chan string, 1),
+		ready:           make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Add appends the component, it is started after every component added before.
+func (m *Manager) Add(name string, c Component) {
+	m.components = append(m.components, namedComponent{name, c})
+}
+
+// Ready is closed once every component is ready.
+// It stays open if the components are stopped before, so waiting on it should select on Done as well.
+func (m *Manager) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Done is closed once Run returns.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Shutdown asks Run to stop the components, reason is reported in the log.
+func (m *Manager) Shutdown(reason string) {
+	select {
+	case m.shutdown <- reason:
+	default:
+	}
+}
+
+// Run starts the components and blocks until shutdown is requested, ctx is done or a component fails.
+// Requested shutdown results in nil error, unless some component fails to stop in time.
+func (m *Manager) Run(ctx context.Context) error {
+	defer close(m.done)
+
+	var (
+		reason  string
+		failure error
+		failed  *runningComponent
+	)
+
+	exited := make(chan *runningComponent, len(m.components))
+	started := make([]*runningComponent, 0, len(m.components))
+
+	waitStop := func(readyCh <-chan struct{}) bool {
+		select {
+		case <-readyCh:
+			return false
+		case r := <-exited:
+			failed, failure = r, exitError(r)
+		case <-ctx.Done():
+			reason = ctx.Err().Error()
+		case reason = <-m.shutdown:
+		}
+
+		return true
+	}
+
+	stopped := false
+
+	for _, c := range m.components {
+		r := &runningComponent{namedComponent: c, done: make(chan struct{})}
+		readyCh := make(chan struct{})
+		once := &sync.Once{}
+
+		go func() {
+			r.err = r.Run(func() { once.Do(func() { close(readyCh) }) })
+			close(r.done)
+			exited <- r
+		}()
+
+		started = append(started, r)
+
+		if stopped = waitStop(readyCh); stopped {
+			break
+		}
+
+		m.logger.Info(fmt.Sprintf("%s is ready", r.name))
+	}
+
+	if !stopped {
+		close(m.ready)
+		waitStop(nil)
+	}
+
+	if failure != nil {
+		reason = failure.Error()
+	}
+
+	m.logger.Info(fmt.Sprintln("shutting down:", reason))
+
+	if err := m.stop(started, failed); err != nil && failure == nil {
+		failure = err
+	}
+
+	return failure
+}
+
+// stop stops components in reverse order sharing the shutdown deadline.
+func (m *Manager) stop(started []*runningComponent, failed *runningComponent) error {
+	ctx, cancelFn := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancelFn()
+
+	var firstErr error
+
+	for i := len(started) - 1; i >= 0; i-- {
+		r := started[i]
+
+		err := r.Stop(ctx)
+		if err == nil {
+			select {
+			case <-r.done:
+				if r != failed {
+					err = r.err
+				}
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+
+		if err != nil {
+			err = fmt.Errorf("failed to stop %s: %w", r.name, err)
+			m.logger.Error(err.Error())
+
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		m.logger.Info(fmt.Sprintf("%s is stopped", r.name))
+	}
+
+	return firstErr
+}
+
+func exitError(r *runningComponent) error {
+	if r.err != nil {
+		return fmt.Errorf("%s failed: %w", r.name, r.err)
+	}
+
+	return fmt.Errorf("%s %w", r.name, ErrStoppedUnexpectedly)
+}
@@ -0,0 +1,235 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errBoom = errors.New("boom")
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+type journal struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (j *journal) add(entry string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) get() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return append([]string{}, j.entries...)
+}
+
+type fakeComponent struct {
+	name    string
+	journal *journal
+	// startErr fails the component before it gets ready.
+	startErr error
+	// fail makes the running component exit with the error sent.
+	fail chan error
+	// hang keeps Run going after Stop.
+	hang    bool
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newFakeComponent(name string, j *journal) *fakeComponent {
+	return &fakeComponent{name: name, journal: j, fail: make(chan error, 1), stopped: make(chan struct{})}
+}
+
+func (c *fakeComponent) Run(ready func()) error {
+	c.journal.add("run " + c.name)
+
+	if c.startErr != nil {
+		return c.startErr
+	}
+
+	ready()
+
+	select {
+	case err := <-c.fail:
+		return err
+	case <-c.stopped:
+		if c.hang {
+			select {}
+		}
+
+		return nil
+	}
+}
+
+func (c *fakeComponent) Stop(ctx context.Context) error {
+	c.journal.add("stop " + c.name)
+	c.once.Do(func() { close(c.stopped) })
+
+	return nil
+}
+
+func runManager(m *Manager) <-chan error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- m.Run(context.Background())
+	}()
+
+	return errCh
+}
+
+func TestShutdown(t *testing.T) {
+	j := &journal{}
+	m := New(nopLogger{}, time.Second)
+
+	for _, name := range []string{"storage", "server", "worker"} {
+		m.Add(name, newFakeComponent(name, j))
+	}
+
+	errCh := runManager(m)
+
+	<-m.Ready()
+	m.Shutdown("test finished")
+
+	require.NoError(t, <-errCh)
+	require.Equal(t, []string{
+		"run storage", "run server", "run worker",
+		"stop worker", "stop server", "stop storage",
+	}, j.get())
+}
+
+func TestContextDone(t *testing.T) {
+	m := New(nopLogger{}, time.Second)
+	m.Add("server", newFakeComponent("server", &journal{}))
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- m.Run(ctx)
+	}()
+
+	<-m.Ready()
+	cancelFn()
+
+	require.NoError(t, <-errCh)
+}
+
+func TestFailureWhileStarting(t *testing.T) {
+	j := &journal{}
+	m := New(nopLogger{}, time.Second)
+
+	failing := newFakeComponent("server", j)
+	failing.startErr = errBoom
+
+	m.Add("storage", newFakeComponent("storage", j))
+	m.Add("server", failing)
+	m.Add("worker", newFakeComponent("worker", j))
+
+	err := <-runManager(m)
+	require.ErrorIs(t, err, errBoom)
+	require.EqualError(t, err, "server failed: boom")
+	require.Equal(t, []string{"run storage", "run server", "stop server", "stop storage"}, j.get())
+
+	select {
+	case <-m.Ready():
+		require.Fail(t, "manager must not get ready")
+	case <-m.Done():
+	}
+}
+
+func TestFailureWhileRunning(t *testing.T) {
+	j := &journal{}
+	m := New(nopLogger{}, time.Second)
+
+	failing := newFakeComponent("server", j)
+
+	m.Add("storage", newFakeComponent("storage", j))
+	m.Add("server", failing)
+
+	errCh := runManager(m)
+
+	<-m.Ready()
+	failing.fail <- errBoom
+
+	require.EqualError(t, <-errCh, "server failed: boom")
+	require.Equal(t, []string{"run storage", "run server", "stop server", "stop storage"}, j.get())
+}
+
+func TestStoppedUnexpectedly(t *testing.T) {
+	m := New(nopLogger{}, time.Second)
+
+	exiting := newFakeComponent("worker", &journal{})
+	m.Add("worker", exiting)
+
+	errCh := runManager(m)
+
+	<-m.Ready()
+	exiting.fail <- nil
+
+	require.ErrorIs(t, <-errCh, ErrStoppedUnexpectedly)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	j := &journal{}
+	m := New(nopLogger{}, 50*time.Millisecond)
+
+	hanging := newFakeComponent("server", j)
+	hanging.hang = true
+
+	m.Add("storage", newFakeComponent("storage", j))
+	m.Add("server", hanging)
+
+	errCh := runManager(m)
+
+	<-m.Ready()
+	m.Shutdown("test finished")
+
+	err := <-errCh
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.EqualError(t, err, "failed to stop server: context deadline exceeded")
+	// the rest is stopped anyway.
+	require.Equal(t, []string{"run storage", "run server", "stop server", "stop storage"}, j.get())
+}
+
+func TestAdapters(t *testing.T) {
+	closed := make(chan struct{})
+	m := New(nopLogger{}, time.Second)
+
+	m.Add("storage", Closer(func(ctx context.Context) error {
+		close(closed)
+
+		return nil
+	}))
+	m.Add("worker", Worker(func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return nil
+	}))
+
+	errCh := runManager(m)
+
+	<-m.Ready()
+	m.Shutdown("test finished")
+
+	require.NoError(t, <-errCh)
+
+	select {
+	case <-closed:
+	default:
+		require.Fail(t, "storage must be closed")
+	}
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,6 +11,7 @@ import (
 
 type Logger struct {
 	logger *zap.Logger
+	level  zap.AtomicLevel
 }
 
 var errFailedToSetLevel = errors.New("failed to set log level")
@@ -21,12 +23,13 @@ func New(level, file string) (*Logger, error) {
 		return nil, fmt.Errorf("%w: %+v", errFailedToSetLevel, err)
 	}
 
+	atomicLevel := zap.NewAtomicLevelAt(lvl)
 	cfg := zap.Config{
 		Development:      true,
 		Encoding:         "console",
 		OutputPaths:      []string{file},
 		ErrorOutputPaths: []string{file},
-		Level:            zap.NewAtomicLevelAt(lvl),
+		Level:            atomicLevel,
 		EncoderConfig:    zapcore.EncoderConfig{MessageKey: "M"},
 	}
 
@@ -35,7 +38,7 @@ func New(level, file string) (*Logger, error) {
 		return nil, err
 	}
 
-	return &Logger{logger}, nil
+	return &Logger{logger, atomicLevel}, nil
 }
 
 func (l *Logger) Info(msg string) {
@@ -45,3 +48,9 @@ func (l *Logger) Info(msg string) {
 func (l *Logger) Error(msg string) {
 	l.logger.Error(msg)
 }
+
+// LevelHandler reports the level as JSON on GET and changes it at runtime on PUT,
+// the level goes either in JSON like {"level":"debug"} or as a form value.
+func (l *Logger) LevelHandler() http.Handler {
+	return l.level
+}
@@ -3,7 +3,10 @@ package logger
 import (
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -61,6 +64,24 @@ func (s *LoggerTestSuite) TestMethods() {
 	require.Equal(s.T(), data, []byte(fmt.Sprintf("%s\n%s\n", infoLine, errorLine)))
 }
 
+func (s *LoggerTestSuite) TestLevelHandler() {
+	log, err := New("info", s.output.Name())
+	require.NoError(s.T(), err)
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"error"}`))
+	rec := httptest.NewRecorder()
+	log.LevelHandler().ServeHTTP(rec, req)
+	require.Equal(s.T(), http.StatusOK, rec.Code)
+	require.JSONEq(s.T(), `{"level":"error"}`, rec.Body.String())
+
+	log.Info("info line")
+	log.Error("error line")
+
+	data, err := io.ReadAll(s.output)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), "error line\n", string(data))
+}
+
 func TestLogger(t *testing.T) {
 	suite.Run(t, new(LoggerTestSuite))
 }
@@ -0,0 +1,45 @@
+package queue
+
+import "context"
+
+// Memory is an in-process queue for the scheduler and the sender running within a single binary.
+type Memory struct {
+	ch chan Notification
+}
+
+func NewMemory(size int) *Memory {
+	return &Memory{make(chan Notification, size)}
+}
+
+// Publish blocks while the queue is full.
+func (q *Memory) Publish(ctx context.Context, n Notification) error {
+	select {
+	case q.ch <- n:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Memory) Consume(ctx context.Context) (<-chan Notification, error) {
+	out := make(chan Notification)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-q.ch:
+				select {
+				case out <- n:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
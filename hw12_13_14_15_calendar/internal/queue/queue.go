@@ -0,0 +1,35 @@
+// Package queue carries notifications from the scheduler to the sender.
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Notification tells the owner or an attendee about an upcoming event as one of its reminders fires.
+type Notification struct {
+	// ID identifies the record the outcome of the delivery is saved to.
+	ID         string    `json:"id,omitempty"`
+	EventID    string    `json:"eventId"`
+	ReminderID string    `json:"reminderId"`
+	Title      string    `json:"title"`
+	StartsAt   time.Time `json:"startsAt"`
+	UserID     string    `json:"userId"`
+	// Email is known for attendees who were invited with one.
+	Email string `json:"email,omitempty"`
+	// Channel is how the notification is to be delivered.
+	Channel string `json:"channel"`
+	// Message is the custom text of the reminder, if any.
+	Message string `json:"message,omitempty"`
+	// IdempotencyKey is the same for every copy of the notification published more than once.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, n Notification) error
+}
+
+type Consumer interface {
+	// Consume delivers notifications until ctx is done.
+	Consume(ctx context.Context) (<-chan Notification, error)
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// relayBatch bounds the number of messages published on a single tick.
+const relayBatch = 100
+
+type OutboxStorage interface {
+	ListPendingOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, id string, sentAt time.Time) error
+	PurgeSentOutbox(ctx context.Context, before time.Time) (int, error)
+}
+
+// Relay publishes notifications written to the outbox to the queue.
+type Relay struct {
+	logger    Logger
+	storage   OutboxStorage
+	publisher queue.Publisher
+	interval  time.Duration
+	// retention is how long sent messages are kept in the outbox, zero keeps them forever.
+	retention time.Duration
+}
+
+func NewRelay(
+	logger Logger, storage OutboxStorage, publisher queue.Publisher, interval, retention time.Duration,
+) *Relay {
+	return &Relay{logger, storage, publisher, interval, retention}
+}
+
+// Run publishes pending messages every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			r.purge(ctx, now)
+
+			// messages left pending are published again on the next tick.
+			if err := r.publish(ctx); err != nil {
+				r.logger.Error(fmt.Sprintln("failed to publish notifications:", err))
+			}
+		}
+	}
+}
+
+func (r *Relay) purge(ctx context.Context, now time.Time) {
+	if r.retention <= 0 {
+		return
+	}
+
+	if _, err := r.storage.PurgeSentOutbox(ctx, now.Add(-r.retention)); err != nil {
+		r.logger.Error(fmt.Sprintln("failed to purge sent notifications:", err))
+	}
+}
+
+// publish stops at the first failure, so messages are published in the order they were written.
+func (r *Relay) publish(ctx context.Context) error {
+	messages, err := r.storage.ListPendingOutbox(ctx, relayBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		var n queue.Notification
+
+		if err := json.Unmarshal([]byte(m.Payload), &n); err != nil {
+			// a malformed message would block the rest of the outbox forever.
+			r.logger.Error(fmt.Sprintf("dropped malformed notification %s: %s", m.ID, err))
+		} else if err := r.publisher.Publish(ctx, n); err != nil {
+			return err
+		}
+
+		// a message published but not marked sent is published again, the sender drops the copy by its key.
+		if err := r.storage.MarkOutboxSent(ctx, m.ID, time.Now()); err != nil &&
+			!errors.Is(err, storage.ErrOutboxMessageNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
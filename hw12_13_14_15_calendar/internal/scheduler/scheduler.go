@@ -0,0 +1,187 @@
+// Package scheduler periodically looks for due reminders of events
+// and publishes notifications about them to the owners and attendees.
+// It also purges events which have been in the trash longer than the retention period.
+//
+// Notifications are recorded and written to the outbox of the storage in the same transaction the reminder
+// is marked fired with, the relay publishes them from there. A notification is published at least once
+// and carries an idempotency key, so the sender is able to deliver it just once.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+type Logger interface {
+	Info(msg string)
+	Error(msg string)
+}
+
+type Storage interface {
+	ListDueReminders(ctx context.Context, from, to time.Time) ([]storage.Reminder, error)
+	FireReminder(
+		ctx context.Context, id string, firedAt time.Time,
+		notifications []storage.Notification, messages []storage.OutboxMessage,
+	) error
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
+	ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error)
+	PurgeDeletedEvents(ctx context.Context, before time.Time) (int, error)
+}
+
+type Scheduler struct {
+	logger   Logger
+	storage  Storage
+	interval time.Duration
+	// retention is how long deleted events stay in the trash, zero keeps them forever.
+	retention time.Duration
+}
+
+func New(logger Logger, storage Storage, interval, retention time.Duration) *Scheduler {
+	return &Scheduler{logger, storage, interval, retention}
+}
+
+// Run writes notifications of due reminders to the outbox until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			s.purge(ctx, now)
+
+			// every reminder which hasn't fired yet is due once its time has come, including those due
+			// while the service was down or set in the past, so failed notifications are retried on the next tick.
+			if err := s.notify(ctx, time.Time{}, now); err != nil {
+				s.logger.Error(fmt.Sprintln("failed to schedule notifications:", err))
+			}
+		}
+	}
+}
+
+// purge deletes events trashed longer than the retention period ago, failures are retried on the next tick.
+func (s *Scheduler) purge(ctx context.Context, now time.Time) {
+	if s.retention <= 0 {
+		return
+	}
+
+	purged, err := s.storage.PurgeDeletedEvents(ctx, now.Add(-s.retention))
+	if err != nil {
+		s.logger.Error(fmt.Sprintln("failed to purge deleted events:", err))
+	} else if purged > 0 {
+		s.logger.Info(fmt.Sprintf("purged %d deleted events", purged))
+	}
+}
+
+func (s *Scheduler) notify(ctx context.Context, from, to time.Time) error {
+	reminders, err := s.storage.ListDueReminders(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reminders {
+		event, err := s.storage.GetEvent(ctx, r.EventID)
+		if err != nil {
+			return err
+		}
+
+		attendees, err := s.storage.ListAttendees(ctx, r.EventID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		records, messages, err := outboxMessages(notifications(event, r, attendees), r, now)
+		if err != nil {
+			return err
+		}
+
+		// fired reminders are not due anymore, so a retry after a later failure doesn't fire them again.
+		if err := s.storage.FireReminder(ctx, r.ID, now, records, messages); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// outboxMessages returns records of the notifications pending delivery along with the messages publishing them.
+func outboxMessages(
+	notifications []queue.Notification, reminder storage.Reminder, now time.Time,
+) ([]storage.Notification, []storage.OutboxMessage, error) {
+	records := make([]storage.Notification, 0, len(notifications))
+	messages := make([]storage.OutboxMessage, 0, len(notifications))
+
+	for _, n := range notifications {
+		n.ID = uuid.New().String()
+
+		payload, err := json.Marshal(n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		records = append(records, storage.Notification{
+			ID:          n.ID,
+			EventID:     n.EventID,
+			ReminderID:  n.ReminderID,
+			UserID:      n.UserID,
+			Channel:     reminder.Channel,
+			ScheduledAt: reminder.RemindAt,
+			Status:      storage.NotificationPending,
+			Payload:     string(payload),
+			CreatedAt:   now,
+		})
+
+		messages = append(messages, storage.OutboxMessage{
+			ID:        uuid.New().String(),
+			Payload:   string(payload),
+			CreatedAt: now,
+		})
+	}
+
+	return records, messages, nil
+}
+
+// notifications are addressed to the owner and every attendee who hasn't declined the invitation.
+func notifications(event storage.Event, reminder storage.Reminder, attendees []storage.Attendee) []queue.Notification {
+	notification := queue.Notification{
+		EventID:    event.ID,
+		ReminderID: reminder.ID,
+		Title:      event.Title,
+		StartsAt:   event.StartsAt,
+		UserID:     event.OwnerID,
+		Channel:    string(reminder.Channel),
+		Message:    reminder.Message,
+	}
+	res := []queue.Notification{notification}
+
+	for _, a := range attendees {
+		if a.Status == storage.StatusDeclined {
+			continue
+		}
+
+		notification.UserID = a.UserID
+		notification.Email = a.Email
+		res = append(res, notification)
+	}
+
+	for i := range res {
+		res[i].IdempotencyKey = idempotencyKey(reminder, res[i].UserID)
+	}
+
+	return res
+}
+
+// idempotencyKey includes the time the reminder fires at, so the one re-armed by moving the event fires again.
+func idempotencyKey(reminder storage.Reminder, userID string) string {
+	return fmt.Sprintf("%s/%d/%s", reminder.ID, reminder.RemindAt.Unix(), userID)
+}
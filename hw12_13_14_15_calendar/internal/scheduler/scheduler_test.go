@@ -0,0 +1,492 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+var errPublish = errors.New("queue is unavailable")
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+type remindersStorage struct {
+	mu        sync.Mutex
+	events    map[string]storage.Event
+	reminders []storage.Reminder
+	attendees map[string][]storage.Attendee
+	outbox    []storage.OutboxMessage
+	// records are notifications written along with the outbox.
+	records []storage.Notification
+	// purges records the times trashed events were purged before.
+	purges []time.Time
+	// outboxPurges records the times sent messages were purged before.
+	outboxPurges []time.Time
+}
+
+func newRemindersStorage(events ...storage.Event) *remindersStorage {
+	st := &remindersStorage{
+		events:    make(map[string]storage.Event),
+		attendees: make(map[string][]storage.Attendee),
+	}
+
+	for _, e := range events {
+		st.events[e.ID] = e
+	}
+
+	return st
+}
+
+func (s *remindersStorage) addReminder(id, eventID string, offset time.Duration) storage.Reminder {
+	reminder := storage.Reminder{ID: id, EventID: eventID, Offset: offset, Channel: storage.ChannelLog}
+	reminder.RemindAt = storage.RemindAt(s.events[eventID], reminder)
+	s.reminders = append(s.reminders, reminder)
+
+	return reminder
+}
+
+func (s *remindersStorage) ListDueReminders(ctx context.Context, from, to time.Time) ([]storage.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reminders []storage.Reminder
+
+	for _, r := range s.reminders {
+		if r.FiredAt == nil && !r.RemindAt.Before(from) && r.RemindAt.Before(to) {
+			reminders = append(reminders, r)
+		}
+	}
+
+	return reminders, nil
+}
+
+func (s *remindersStorage) FireReminder(
+	ctx context.Context, id string, firedAt time.Time,
+	notifications []storage.Notification, messages []storage.OutboxMessage,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.reminders {
+		if s.reminders[i].ID == id {
+			s.reminders[i].FiredAt = &firedAt
+			s.records = append(s.records, notifications...)
+			s.outbox = append(s.outbox, messages...)
+
+			return nil
+		}
+	}
+
+	return storage.ErrReminderNotFound
+}
+
+func (s *remindersStorage) ListPendingOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []storage.OutboxMessage
+
+	for _, m := range s.outbox {
+		if m.SentAt == nil && len(messages) < limit {
+			messages = append(messages, m)
+		}
+	}
+
+	return messages, nil
+}
+
+func (s *remindersStorage) MarkOutboxSent(ctx context.Context, id string, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.outbox {
+		if s.outbox[i].ID == id {
+			s.outbox[i].SentAt = &sentAt
+
+			return nil
+		}
+	}
+
+	return storage.ErrOutboxMessageNotFound
+}
+
+func (s *remindersStorage) PurgeSentOutbox(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outboxPurges = append(s.outboxPurges, before)
+
+	return 0, nil
+}
+
+func (s *remindersStorage) pendingOutbox() []storage.OutboxMessage {
+	messages, _ := s.ListPendingOutbox(context.Background(), relayBatch)
+
+	return messages
+}
+
+func (s *remindersStorage) notificationRecords() []storage.Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]storage.Notification{}, s.records...)
+}
+
+func (s *remindersStorage) outboxPurgedBefore() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]time.Time{}, s.outboxPurges...)
+}
+
+func (s *remindersStorage) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return event, storage.ErrEventNotFound
+	}
+
+	return event, nil
+}
+
+func (s *remindersStorage) ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attendees[eventID], nil
+}
+
+func (s *remindersStorage) PurgeDeletedEvents(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purges = append(s.purges, before)
+
+	return 0, nil
+}
+
+func (s *remindersStorage) purgedBefore() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]time.Time{}, s.purges...)
+}
+
+// flakyPublisher fails attempts after the first successful ones and records what was published.
+type flakyPublisher struct {
+	mu        sync.Mutex
+	successes int
+	failures  int
+	published []queue.Notification
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, n queue.Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.published) >= p.successes && p.failures > 0 {
+		p.failures--
+
+		return errPublish
+	}
+
+	p.published = append(p.published, n)
+
+	return nil
+}
+
+func (p *flakyPublisher) notifications() []queue.Notification {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]queue.Notification{}, p.published...)
+}
+
+// startsIn returns the time after d the way it comes through the queue.
+func startsIn(d time.Duration) time.Time {
+	return time.Now().Add(d).UTC().Round(0)
+}
+
+// runScheduler runs the scheduler along with the relay until the publisher gets the expected number of notifications.
+func runScheduler(t *testing.T, st *remindersStorage, publisher *flakyPublisher, expected int) {
+	t.Helper()
+
+	s := New(nopLogger{}, st, 10*time.Millisecond, 0)
+	r := NewRelay(nopLogger{}, st, publisher, 5*time.Millisecond, 0)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(publisher.notifications()) >= expected
+	}, time.Second, 10*time.Millisecond)
+
+	// a few more ticks must not notify again.
+	time.Sleep(50 * time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+}
+
+func TestSchedulerRetriesFailedPublish(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "event",
+		StartsAt: startsIn(time.Hour + 50*time.Millisecond),
+		OwnerID:  "owner",
+	}
+
+	st := newRemindersStorage(event)
+	reminder := st.addReminder("1", event.ID, time.Hour)
+
+	publisher := &flakyPublisher{failures: 2}
+	runScheduler(t, st, publisher, 1)
+
+	require.Equal(t, []queue.Notification{{
+		ID:         st.notificationRecords()[0].ID,
+		EventID:    event.ID,
+		ReminderID: reminder.ID,
+		Title:      event.Title,
+		StartsAt:   event.StartsAt,
+		UserID:     event.OwnerID,
+		Channel:    "log",
+		// the key is made of the reminder, the time it fires at and the recipient.
+		IdempotencyKey: fmt.Sprintf("1/%d/owner", reminder.RemindAt.Unix()),
+	}}, publisher.notifications())
+	require.Empty(t, st.pendingOutbox())
+}
+
+func TestSchedulerFiresRemindersOnce(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "event",
+		StartsAt: startsIn(time.Hour + 50*time.Millisecond),
+		OwnerID:  "owner",
+	}
+
+	st := newRemindersStorage(event)
+	first := st.addReminder("1", event.ID, time.Hour)
+	second := st.addReminder("2", event.ID, time.Hour-20*time.Millisecond)
+
+	// the second reminder fails, while the first one must not be published again on retry.
+	publisher := &flakyPublisher{successes: 1, failures: 2}
+	runScheduler(t, st, publisher, 2)
+
+	notifications := publisher.notifications()
+	require.Len(t, notifications, 2)
+	require.Equal(t, first.ID, notifications[0].ReminderID)
+	require.Equal(t, second.ID, notifications[1].ReminderID)
+}
+
+func TestSchedulerFiresOverdueReminders(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "event",
+		StartsAt: startsIn(time.Minute),
+		OwnerID:  "owner",
+	}
+
+	st := newRemindersStorage(event)
+	// the reminder was due while the service was down.
+	overdue := st.addReminder("1", event.ID, time.Hour)
+	fired := st.addReminder("2", event.ID, 2*time.Hour)
+	firedAt := fired.RemindAt
+	st.reminders[1].FiredAt = &firedAt
+
+	publisher := &flakyPublisher{}
+	runScheduler(t, st, publisher, 1)
+
+	notifications := publisher.notifications()
+	require.Len(t, notifications, 1)
+	require.Equal(t, overdue.ID, notifications[0].ReminderID)
+}
+
+func TestSchedulerNotifiesAttendees(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "event",
+		StartsAt: startsIn(time.Hour + 50*time.Millisecond),
+		OwnerID:  "owner",
+	}
+	attendees := []storage.Attendee{
+		{EventID: event.ID, UserID: "accepted", Email: "accepted@example.com", Status: storage.StatusAccepted},
+		{EventID: event.ID, UserID: "declined", Status: storage.StatusDeclined},
+		{EventID: event.ID, UserID: "pending", Status: storage.StatusNeedsAction},
+	}
+
+	st := newRemindersStorage(event)
+	st.attendees[event.ID] = attendees
+	reminder := st.addReminder("1", event.ID, time.Hour)
+	reminder.Message = "bring slides"
+	st.reminders[0] = reminder
+
+	publisher := &flakyPublisher{}
+	runScheduler(t, st, publisher, 3)
+
+	notification := queue.Notification{
+		EventID:    event.ID,
+		ReminderID: reminder.ID,
+		Title:      event.Title,
+		StartsAt:   event.StartsAt,
+		Channel:    "log",
+		Message:    "bring slides",
+	}
+	expected := []queue.Notification{notification, notification, notification}
+	expected[0].UserID = event.OwnerID
+	expected[1].UserID = "accepted"
+	expected[1].Email = "accepted@example.com"
+	expected[2].UserID = "pending"
+
+	for i, record := range st.notificationRecords() {
+		expected[i].ID = record.ID
+		expected[i].IdempotencyKey = idempotencyKey(reminder, expected[i].UserID)
+	}
+
+	require.Equal(t, expected, publisher.notifications())
+}
+
+func TestSchedulerWritesOutbox(t *testing.T) {
+	event := storage.Event{
+		ID:       "1",
+		Title:    "event",
+		StartsAt: startsIn(time.Hour + 20*time.Millisecond),
+		OwnerID:  "owner",
+	}
+
+	st := newRemindersStorage(event)
+	st.attendees[event.ID] = []storage.Attendee{{EventID: event.ID, UserID: "attendee"}}
+	reminder := st.addReminder("1", event.ID, time.Hour)
+
+	// notifications stay in the outbox until the relay publishes them.
+	s := New(nopLogger{}, st, 10*time.Millisecond, 0)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(st.pendingOutbox()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+
+	keys := make([]string, 0, 2)
+	records := st.notificationRecords()
+
+	for i, m := range st.pendingOutbox() {
+		var n queue.Notification
+
+		require.NoError(t, json.Unmarshal([]byte(m.Payload), &n))
+		require.Equal(t, reminder.ID, n.ReminderID)
+
+		// every notification is recorded pending delivery along with the payload it's published with.
+		require.Equal(t, storage.Notification{
+			ID:          n.ID,
+			EventID:     event.ID,
+			ReminderID:  reminder.ID,
+			UserID:      n.UserID,
+			Channel:     storage.ChannelLog,
+			ScheduledAt: reminder.RemindAt,
+			Status:      storage.NotificationPending,
+			Payload:     m.Payload,
+			CreatedAt:   m.CreatedAt,
+		}, records[i])
+
+		keys = append(keys, n.IdempotencyKey)
+	}
+
+	require.Equal(t, []string{idempotencyKey(reminder, "owner"), idempotencyKey(reminder, "attendee")}, keys)
+	// the reminder re-armed by moving the event is notified about again.
+	require.NotEqual(t, keys[0], idempotencyKey(storage.Reminder{ID: reminder.ID, RemindAt: reminder.RemindAt.Add(time.Minute)}, "owner"))
+}
+
+func TestRelayDropsMalformedMessages(t *testing.T) {
+	st := newRemindersStorage()
+	st.outbox = []storage.OutboxMessage{
+		{ID: "1", Payload: "{"},
+		{ID: "2", Payload: `{"eventId":"event"}`},
+	}
+
+	publisher := &flakyPublisher{}
+	runScheduler(t, st, publisher, 1)
+
+	require.Equal(t, []queue.Notification{{EventID: "event"}}, publisher.notifications())
+	require.Empty(t, st.pendingOutbox())
+}
+
+func TestRelayPurgesSentMessages(t *testing.T) {
+	st := newRemindersStorage()
+	r := NewRelay(nopLogger{}, st, &flakyPublisher{}, 10*time.Millisecond, time.Hour)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(st.outboxPurgedBefore()) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+
+	require.WithinDuration(t, time.Now().Add(-time.Hour), st.outboxPurgedBefore()[0], time.Second)
+}
+
+func TestSchedulerPurgesTrash(t *testing.T) {
+	st := newRemindersStorage()
+	s := New(nopLogger{}, st, 10*time.Millisecond, time.Hour)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(st.purgedBefore()) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+
+	before := st.purgedBefore()[0]
+	require.WithinDuration(t, time.Now().Add(-time.Hour), before, time.Second)
+}
+
+func TestSchedulerKeepsTrashWithoutRetention(t *testing.T) {
+	st := newRemindersStorage()
+
+	runScheduler(t, st, &flakyPublisher{}, 0)
+
+	require.Empty(t, st.purgedBefore())
+}
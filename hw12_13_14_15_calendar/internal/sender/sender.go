@@ -0,0 +1,294 @@
+// Package sender delivers notifications published through the outbox and records how every attempt went.
+// Notifications are published at least once, the copies of the delivered ones are dropped
+// by their records, or by their idempotency keys if they have none.
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var (
+	ErrUnknownChannel = errors.New("no notifier for channel")
+	ErrDeliveryFailed = errors.New("delivery failed")
+	ErrNoEmail        = errors.New("recipient has no email")
+)
+
+type Logger interface {
+	Info(msg string)
+	Error(msg string)
+}
+
+type Storage interface {
+	GetNotification(ctx context.Context, id string) (storage.Notification, error)
+	UpdateNotification(ctx context.Context, notification storage.Notification) error
+}
+
+// Notifier delivers a notification to the user.
+type Notifier interface {
+	Notify(ctx context.Context, n queue.Notification) error
+}
+
+// seenCapacity bounds the number of remembered idempotency keys,
+// copies are published shortly after the original, so older keys are forgotten.
+const seenCapacity = 10000
+
+type Sender struct {
+	logger   Logger
+	consumer queue.Consumer
+	notifier Notifier
+	storage  Storage
+	seen     *seenKeys
+}
+
+func New(logger Logger, consumer queue.Consumer, notifier Notifier, storage Storage) *Sender {
+	return &Sender{logger, consumer, notifier, storage, newSeenKeys(seenCapacity)}
+}
+
+// Run delivers consumed notifications until ctx is done.
+func (s *Sender) Run(ctx context.Context) error {
+	notifications, err := s.consumer.Consume(ctx)
+	if err != nil {
+		return err
+	}
+
+	for n := range notifications {
+		if s.delivered(ctx, n) {
+			s.logger.Info(fmt.Sprintf("skip duplicate notification %s", n.IdempotencyKey))
+
+			continue
+		}
+
+		err := s.notifier.Notify(ctx, n)
+		s.record(ctx, n, err)
+
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("failed to notify about event %s: %s", n.EventID, err))
+
+			continue
+		}
+
+		s.seen.add(n.IdempotencyKey)
+	}
+
+	return nil
+}
+
+// delivered tells whether a copy of the notification has been delivered already.
+// Its record is looked up, since keys remembered in memory are lost on restart.
+// The notification is delivered once more if the record can't be read, rather than lost.
+func (s *Sender) delivered(ctx context.Context, n queue.Notification) bool {
+	if s.seen.has(n.IdempotencyKey) {
+		return true
+	}
+
+	// notifications published before they were recorded have no record.
+	if n.ID == "" {
+		return false
+	}
+
+	notification, err := s.storage.GetNotification(ctx, n.ID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotificationNotFound) {
+			s.logger.Error(fmt.Sprintf("failed to look notification %s up: %s", n.ID, err))
+		}
+
+		return false
+	}
+
+	return notification.Status == storage.NotificationSent
+}
+
+// record saves the outcome of the attempt to the notification record, failures to save it are only logged,
+// so they don't hold the delivery of the rest of notifications up.
+func (s *Sender) record(ctx context.Context, n queue.Notification, deliveryErr error) {
+	// notifications published before they were recorded have no record.
+	if n.ID == "" {
+		return
+	}
+
+	notification, err := s.storage.GetNotification(ctx, n.ID)
+	if errors.Is(err, storage.ErrNotificationNotFound) {
+		// the event has been purged along with its notifications since the notification was published.
+		return
+	} else if err != nil {
+		s.logger.Error(fmt.Sprintf("failed to record notification %s: %s", n.ID, err))
+
+		return
+	}
+
+	notification.Attempts++
+
+	if deliveryErr == nil {
+		now := time.Now().UTC()
+
+		notification.Status = storage.NotificationSent
+		notification.LastError = ""
+		notification.SentAt = &now
+	} else {
+		notification.Status = storage.NotificationFailed
+		notification.LastError = deliveryErr.Error()
+	}
+
+	if err := s.storage.UpdateNotification(ctx, notification); err != nil &&
+		!errors.Is(err, storage.ErrNotificationNotFound) {
+		s.logger.Error(fmt.Sprintf("failed to record notification %s: %s", n.ID, err))
+	}
+}
+
+// seenKeys remembers the keys of delivered notifications, the oldest key is forgotten once the capacity is reached.
+type seenKeys struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+	ring []string
+	next int
+}
+
+func newSeenKeys(capacity int) *seenKeys {
+	return &seenKeys{keys: make(map[string]struct{}, capacity), ring: make([]string, capacity)}
+}
+
+// has tells whether the key was delivered, notifications without a key are never duplicates.
+func (s *seenKeys) has(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.keys[key]
+
+	return ok
+}
+
+func (s *seenKeys) add(key string) {
+	if key == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key]; ok {
+		return
+	}
+
+	delete(s.keys, s.ring[s.next])
+	s.ring[s.next] = key
+	s.next = (s.next + 1) % len(s.ring)
+	s.keys[key] = struct{}{}
+}
+
+// Channels delivers every notification with the notifier of its channel.
+type Channels map[string]Notifier
+
+func (c Channels) Notify(ctx context.Context, notification queue.Notification) error {
+	notifier, ok := c[notification.Channel]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownChannel, notification.Channel)
+	}
+
+	return notifier.Notify(ctx, notification)
+}
+
+// LogNotifier only logs notifications.
+type LogNotifier struct {
+	logger Logger
+}
+
+func NewLogNotifier(logger Logger) *LogNotifier {
+	return &LogNotifier{logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification queue.Notification) error {
+	n.logger.Info(fmt.Sprintf("notify user %s: %s", notification.UserID, text(notification)))
+
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to the URL, any status but 2xx is a failure.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url, &http.Client{Timeout: timeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification queue.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrDeliveryFailed, res.Status)
+	}
+
+	return nil
+}
+
+// EmailNotifier mails notifications to recipients invited with an email.
+type EmailNotifier struct {
+	addr, from string
+	auth       smtp.Auth
+}
+
+// NewEmailNotifier authenticates to the SMTP server only when the user is set.
+func NewEmailNotifier(host string, port uint16, user, password, from string) *EmailNotifier {
+	var auth smtp.Auth
+
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+
+	return &EmailNotifier{net.JoinHostPort(host, strconv.Itoa(int(port))), from, auth}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification queue.Notification) error {
+	if notification.Email == "" {
+		return fmt.Errorf("%w: user %s", ErrNoEmail, notification.UserID)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Reminder: %s\r\n\r\n%s\r\n",
+		n.from, notification.Email, notification.Title, text(notification))
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{notification.Email}, []byte(msg))
+}
+
+// text is the custom message of the reminder or says when the event starts.
+func text(notification queue.Notification) string {
+	if notification.Message != "" {
+		return notification.Message
+	}
+
+	return fmt.Sprintf("%q starts at %s", notification.Title, notification.StartsAt.Format(time.RFC3339))
+}
@@ -0,0 +1,292 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	notified []queue.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification queue.Notification) error {
+	n.notified = append(n.notified, notification)
+
+	return nil
+}
+
+func TestChannels(t *testing.T) {
+	log, webhook := &recordingNotifier{}, &recordingNotifier{}
+	channels := Channels{"log": log, "webhook": webhook}
+
+	require.NoError(t, channels.Notify(context.Background(), queue.Notification{EventID: "1", Channel: "webhook"}))
+	require.NoError(t, channels.Notify(context.Background(), queue.Notification{EventID: "2", Channel: "log"}))
+	require.ErrorIs(t, channels.Notify(context.Background(), queue.Notification{Channel: "email"}), ErrUnknownChannel)
+
+	require.Len(t, webhook.notified, 1)
+	require.Equal(t, "1", webhook.notified[0].EventID)
+	require.Len(t, log.notified, 1)
+	require.Equal(t, "2", log.notified[0].EventID)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan queue.Notification, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var n queue.Notification
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+
+		received <- n
+	}))
+	defer server.Close()
+
+	notification := queue.Notification{
+		EventID:    "event",
+		ReminderID: "reminder",
+		UserID:     "user",
+		Title:      "Team standup",
+		StartsAt:   time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+		Channel:    "webhook",
+		Message:    "standup soon",
+	}
+
+	require.NoError(t, NewWebhookNotifier(server.URL, time.Second).Notify(context.Background(), notification))
+	require.Equal(t, notification, <-received)
+}
+
+func TestWebhookNotifierFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL, time.Second).Notify(context.Background(), queue.Notification{})
+	require.ErrorIs(t, err, ErrDeliveryFailed)
+}
+
+func TestEmailNotifierWithoutEmail(t *testing.T) {
+	err := NewEmailNotifier("localhost", 25, "", "", "calendar@localhost").
+		Notify(context.Background(), queue.Notification{UserID: "user"})
+	require.ErrorIs(t, err, ErrNoEmail)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+// flakyNotifier fails the first attempt to notify about the event and records what was delivered.
+type flakyNotifier struct {
+	mu       sync.Mutex
+	failing  string
+	notified []queue.Notification
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, notification queue.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if notification.EventID == n.failing {
+		n.failing = ""
+
+		return ErrDeliveryFailed
+	}
+
+	n.notified = append(n.notified, notification)
+
+	return nil
+}
+
+func (n *flakyNotifier) events() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	events := make([]string, 0, len(n.notified))
+
+	for _, notification := range n.notified {
+		events = append(events, notification.EventID)
+	}
+
+	return events
+}
+
+type notificationsStorage struct {
+	mu      sync.Mutex
+	records map[string]storage.Notification
+}
+
+func newNotificationsStorage(ids ...string) *notificationsStorage {
+	st := &notificationsStorage{records: make(map[string]storage.Notification)}
+
+	for _, id := range ids {
+		st.records[id] = storage.Notification{ID: id, Status: storage.NotificationPending}
+	}
+
+	return st
+}
+
+func (s *notificationsStorage) GetNotification(ctx context.Context, id string) (storage.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.records[id]
+	if !ok {
+		return notification, storage.ErrNotificationNotFound
+	}
+
+	return notification, nil
+}
+
+func (s *notificationsStorage) UpdateNotification(ctx context.Context, notification storage.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[notification.ID]; !ok {
+		return storage.ErrNotificationNotFound
+	}
+
+	s.records[notification.ID] = notification
+
+	return nil
+}
+
+func (s *notificationsStorage) get(id string) storage.Notification {
+	notification, _ := s.GetNotification(context.Background(), id)
+
+	return notification
+}
+
+// runSender delivers the notifications until the notifier gets the expected number of them.
+func runSender(t *testing.T, notifier *flakyNotifier, st Storage, expected int, notifications ...queue.Notification) {
+	t.Helper()
+
+	q := queue.NewMemory(10)
+
+	for _, n := range notifications {
+		require.NoError(t, q.Publish(context.Background(), n))
+	}
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- New(nopLogger{}, q, notifier, st).Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(notifier.events()) >= expected
+	}, time.Second, 5*time.Millisecond)
+
+	// the rest of the copies must not be delivered.
+	time.Sleep(20 * time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+}
+
+func TestSenderRecordsAttempts(t *testing.T) {
+	st := newNotificationsStorage("1", "2", "3")
+	notifier := &flakyNotifier{failing: "retried"}
+
+	runSender(t, notifier, st, 2,
+		queue.Notification{ID: "1", EventID: "delivered", IdempotencyKey: "1"},
+		// the copy of the notification which failed to deliver is another attempt.
+		queue.Notification{ID: "2", EventID: "retried", IdempotencyKey: "2"},
+		queue.Notification{ID: "2", EventID: "retried", IdempotencyKey: "2"},
+		queue.Notification{ID: "unknown", EventID: "purged"},
+	)
+
+	delivered := st.get("1")
+	require.Equal(t, storage.NotificationSent, delivered.Status)
+	require.Equal(t, 1, delivered.Attempts)
+	require.NotNil(t, delivered.SentAt)
+
+	retried := st.get("2")
+	require.Equal(t, storage.NotificationSent, retried.Status)
+	require.Equal(t, 2, retried.Attempts)
+	require.Empty(t, retried.LastError)
+
+	// notifications which were never published are left pending.
+	require.Equal(t, storage.Notification{ID: "3", Status: storage.NotificationPending}, st.get("3"))
+}
+
+func TestSenderRecordsFailures(t *testing.T) {
+	st := newNotificationsStorage("1", "2")
+	notifier := &flakyNotifier{failing: "failed"}
+
+	runSender(t, notifier, st, 1,
+		queue.Notification{ID: "1", EventID: "failed", IdempotencyKey: "1"},
+		queue.Notification{ID: "2", EventID: "delivered", IdempotencyKey: "2"},
+	)
+
+	failed := st.get("1")
+	require.Equal(t, storage.NotificationFailed, failed.Status)
+	require.Equal(t, 1, failed.Attempts)
+	require.Equal(t, ErrDeliveryFailed.Error(), failed.LastError)
+	require.Nil(t, failed.SentAt)
+
+	require.Equal(t, storage.NotificationSent, st.get("2").Status)
+}
+
+func TestSenderDropsDuplicates(t *testing.T) {
+	notifier := &flakyNotifier{failing: "failed"}
+	expected := []string{"delivered", "unkeyed", "unkeyed", "failed"}
+
+	runSender(t, notifier, newNotificationsStorage(), len(expected),
+		queue.Notification{EventID: "delivered", IdempotencyKey: "1"},
+		queue.Notification{EventID: "delivered", IdempotencyKey: "1"},
+		// notifications without a key are never duplicates.
+		queue.Notification{EventID: "unkeyed"},
+		queue.Notification{EventID: "unkeyed"},
+		// the copy of the notification which failed to deliver is another attempt.
+		queue.Notification{EventID: "failed", IdempotencyKey: "2"},
+		queue.Notification{EventID: "failed", IdempotencyKey: "2"},
+		queue.Notification{EventID: "failed", IdempotencyKey: "2"},
+	)
+
+	require.Equal(t, expected, notifier.events())
+}
+
+func TestSenderDropsNotificationsRecordedSent(t *testing.T) {
+	// the notification was delivered before the restart, the new sender remembers no keys.
+	st := newNotificationsStorage("sent", "pending")
+	st.records["sent"] = storage.Notification{ID: "sent", Status: storage.NotificationSent, Attempts: 1}
+
+	notifier := &flakyNotifier{}
+
+	runSender(t, notifier, st, 1,
+		queue.Notification{ID: "sent", EventID: "sent", IdempotencyKey: "1"},
+		queue.Notification{ID: "pending", EventID: "pending", IdempotencyKey: "2"},
+	)
+
+	require.Equal(t, []string{"pending"}, notifier.events())
+	require.Equal(t, 1, st.get("sent").Attempts)
+}
+
+func TestSeenKeysForgetOldest(t *testing.T) {
+	seen := newSeenKeys(2)
+
+	seen.add("1")
+	seen.add("2")
+	seen.add("2")
+	require.True(t, seen.has("1"))
+
+	seen.add("3")
+	require.False(t, seen.has("1"))
+	require.True(t, seen.has("2"))
+	require.True(t, seen.has("3"))
+	require.False(t, seen.has(""))
+}
@@ -0,0 +1,105 @@
+// Package internaladmin serves debug endpoints for operators on a listener of its own,
+// which is meant to be reachable from inside the deployment only.
+package internaladmin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+type Server struct {
+	address  string
+	server   *http.Server
+	listener net.Listener
+}
+
+type Logger interface {
+	Info(msg string)
+	Error(msg string)
+}
+
+// NewServer serves pprof profiles at /debug/pprof/, the log level handler at /debug/loglevel
+// and info, such as the build and the config, as JSON at /debug/info.
+func NewServer(address string, logger Logger, logLevel http.Handler, info interface{}) *Server {
+	return &Server{
+		address: address,
+		server:  &http.Server{Addr: address, Handler: handler(logger, logLevel, info)},
+	}
+}
+
+func handler(logger Logger, logLevel http.Handler, info interface{}) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/loglevel", logLevel)
+	mux.HandleFunc("/debug/info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(info); err != nil {
+			logger.Error("failed to write info: " + err.Error())
+		}
+	})
+
+	return mux
+}
+
+// Listen binds the server address ahead of Run, so the actual address of port 0 is known.
+func (s *Server) Listen() error {
+	if s.listener != nil {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+
+	s.listener = lis
+
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.address
+	}
+
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Run(ready func()) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	ready()
+
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Stop waits for in-flight requests to finish, the connections left when ctx is done are closed.
+func (s *Server) Stop(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package internaladmin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+func TestHandler(t *testing.T) {
+	logLevel := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	info := struct {
+		Release string
+		Port    string `json:"port"`
+	}{"develop", "8080"}
+
+	h := handler(nopLogger{}, logLevel, info)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/debug/info")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"Release":"develop","port":"8080"}`, rec.Body.String())
+
+	require.Equal(t, http.StatusAccepted, serve(http.MethodPut, "/debug/loglevel").Code)
+
+	rec = serve(http.MethodGet, "/debug/pprof/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "goroutine")
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/debug/pprof/goroutine?debug=1").Code)
+	require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/").Code)
+}
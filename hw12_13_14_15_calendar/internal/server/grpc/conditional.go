@@ -0,0 +1,75 @@
+package internalgrpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Lists carry validators derived from the newest change of events they show, a request repeating them
+// gets the not-modified mark and no events instead of the list.
+const (
+	IfNoneMatchMetadataKey     = "if-none-match"
+	IfModifiedSinceMetadataKey = "if-modified-since"
+	ETagMetadataKey            = "etag"
+	LastModifiedMetadataKey    = "last-modified"
+	NotModifiedMetadataKey     = "not-modified"
+)
+
+// listValidators returns validators of the list changed at the time, Last-Modified is left out
+// while changes within the current second are still possible, since it can't tell them apart.
+func listValidators(changedAt time.Time) metadata.MD {
+	if changedAt.IsZero() {
+		return metadata.Pairs(ETagMetadataKey, `"0"`)
+	}
+
+	md := metadata.Pairs(ETagMetadataKey, `"`+strconv.FormatInt(changedAt.UnixNano(), 36)+`"`)
+
+	if lastModified := changedAt.Truncate(time.Second); lastModified.Before(time.Now().Truncate(time.Second)) {
+		md.Set(LastModifiedMetadataKey, lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	return md
+}
+
+// sendListValidators sends validators of the list changed at the time and reports whether the request
+// validators match them, If-None-Match takes precedence over If-Modified-Since like in HTTP.
+func sendListValidators(ctx context.Context, changedAt time.Time) (bool, error) {
+	validators := listValidators(changedAt)
+	notModified := false
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get(IfNoneMatchMetadataKey); len(values) > 0 {
+		notModified = matchETag(values, validators.Get(ETagMetadataKey)[0])
+	} else if values := md.Get(IfModifiedSinceMetadataKey); len(values) > 0 && !changedAt.IsZero() {
+		since, err := http.ParseTime(values[0])
+		notModified = err == nil && !changedAt.Truncate(time.Second).After(since)
+	}
+
+	if notModified {
+		validators.Set(NotModifiedMetadataKey, "true")
+	}
+
+	return notModified, grpc.SetHeader(ctx, validators)
+}
+
+// matchETag reports whether If-None-Match values match the tag, weak tags match as well.
+func matchETag(values []string, etag string) bool {
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
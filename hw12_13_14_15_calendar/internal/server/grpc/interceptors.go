@@ -6,11 +6,18 @@ import (
 	"net"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
+// UserIDMetadataKey carries ID of the user performing the request.
+const UserIDMetadataKey = "x-user-id"
+
 func loggingInterceptor(logger app.Logger) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
@@ -35,3 +42,24 @@ func loggingInterceptor(logger app.Logger) grpc.UnaryServerInterceptor {
 		return
 	}
 }
+
+func userInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if values := md.Get(UserIDMetadataKey); len(values) > 0 {
+			if _, err := uuid.Parse(values[0]); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid %s: %s", UserIDMetadataKey, err)
+			}
+
+			ctx = app.ContextWithUserID(ctx, values[0])
+		}
+
+		return handler(ctx, req)
+	}
+}
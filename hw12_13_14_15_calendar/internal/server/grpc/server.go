@@ -2,21 +2,26 @@ package internalgrpc
 
 import (
 	"context"
+	"errors"
 	"net"
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_validator "github.com/grpc-ecosystem/go-grpc-middleware/validator"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
 	"google.golang.org/grpc"
+	channelzservice "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/reflection"
 )
 
 type Server struct {
-	address string
-	logger  Logger
-	server  *grpc.Server
-	service pb.CalendarServiceServer
+	address  string
+	logger   Logger
+	server   *grpc.Server
+	service  pb.CalendarServiceServer
+	listener net.Listener
 }
 
 type Logger interface {
@@ -25,44 +30,123 @@ type Logger interface {
 }
 
 type Application interface {
-	CreateEvent(ctx context.Context, id, title string) error
+	CreateEvent(ctx context.Context, event storage.Event) error
+	GetEvent(ctx context.Context, id string) (storage.Event, error)
 	UpdateEvent(ctx context.Context, id string, event storage.Event) error
 	DeleteEvent(ctx context.Context, id string) error
-	ListDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
-	ListWeekEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
-	ListMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error)
+	RestoreEvent(ctx context.Context, id string) (storage.Event, error)
+	ListDeletedEvents(ctx context.Context, filter storage.EventFilter) ([]storage.Event, error)
+	SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.Event, error)
+	ListEventHistory(ctx context.Context, id string) ([]storage.AuditRecord, error)
+	ListDayEvents(ctx context.Context, date time.Time, opts app.ListOptions) ([]storage.Event, error)
+	ListWeekEvents(ctx context.Context, date time.Time, opts app.ListOptions) ([]storage.Event, error)
+	ListMonthEvents(ctx context.Context, date time.Time, opts app.ListOptions) ([]storage.Event, error)
+	ListEvents(ctx context.Context, view app.View, day time.Time, opts app.ListOptions) ([]storage.Event, error)
+	ListChangedAt(ctx context.Context, view app.View, opts app.ListOptions) (time.Time, error)
+	GetUserSettings(ctx context.Context) (storage.UserSettings, error)
+	UpdateUserSettings(ctx context.Context, settings storage.UserSettings) (storage.UserSettings, error)
+	InviteAttendee(ctx context.Context, attendee storage.Attendee) (storage.Attendee, error)
+	RemoveAttendee(ctx context.Context, eventID, userID string) error
+	ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error)
+	RespondToInvitation(ctx context.Context, eventID string, status storage.AttendeeStatus) (storage.Attendee, error)
+	AddReminder(ctx context.Context, reminder storage.Reminder) (storage.Reminder, error)
+	RemoveReminder(ctx context.Context, eventID, id string) error
+	ListReminders(ctx context.Context, eventID string) ([]storage.Reminder, error)
+	ListNotifications(ctx context.Context, filter storage.NotificationFilter) ([]storage.Notification, error)
+	ResendNotification(ctx context.Context, id string) (storage.Notification, error)
+	FreeBusy(ctx context.Context, userIDs []string, from, to time.Time, minFree time.Duration) (app.FreeBusy, error)
+	CreateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	GetCalendar(ctx context.Context, id string) (app.UserCalendar, error)
+	UpdateCalendar(ctx context.Context, calendar storage.Calendar) (storage.Calendar, error)
+	DeleteCalendar(ctx context.Context, id string) error
+	ListCalendars(ctx context.Context) ([]app.UserCalendar, error)
+	ShareCalendar(ctx context.Context, share storage.CalendarShare) error
+	UnshareCalendar(ctx context.Context, calendarID, userID string) error
+	ListCalendarShares(ctx context.Context, calendarID string) ([]storage.CalendarShare, error)
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID string) ([]storage.WebhookDelivery, error)
 }
 
 func NewServer(address string, logger Logger, app Application) *Server {
-	return &Server{address, logger, nil, &calendarServiceServer{app: app}}
-}
-
-func (s *Server) Start(ctx context.Context) error {
-	s.server = grpc.NewServer(
+	server := grpc.NewServer(
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-			loggingInterceptor(s.logger),
+			loggingInterceptor(logger),
+			userInterceptor(),
 			grpc_validator.UnaryServerInterceptor(),
 		)),
 	)
 
-	pb.RegisterCalendarServiceServer(s.server, s.service)
+	service := &calendarServiceServer{app: app}
+
+	pb.RegisterCalendarServiceServer(server, service)
+
+	return &Server{address, logger, server, service, nil}
+}
+
+// RegisterDebugServices lets operators introspect the server with reflection and channelz,
+// it has to be called before Run.
+func (s *Server) RegisterDebugServices() {
+	reflection.Register(s.server)
+	channelzservice.RegisterChannelzServiceToServer(s.server)
+}
+
+// Listen binds the server address ahead of Run, so the actual address of port 0 is known.
+func (s *Server) Listen() error {
+	if s.listener != nil {
+		return nil
+	}
 
 	lis, err := net.Listen("tcp", s.address)
 	if err != nil {
 		return err
 	}
 
-	if err := s.server.Serve(lis); err != nil {
+	s.listener = lis
+
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.address
+	}
+
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Run(ready func()) error {
+	if err := s.Listen(); err != nil {
 		return err
 	}
 
-	<-ctx.Done()
+	ready()
+
+	// the server stopped before it got to serve is not a failure either.
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
 
-	return s.Stop()
+	return nil
 }
 
-func (s *Server) Stop() error {
-	s.server.GracefulStop()
+// Stop waits for in-flight requests to finish, the ones left when ctx is done are cancelled.
+func (s *Server) Stop(ctx context.Context) error {
+	done := make(chan struct{})
 
-	return nil
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+
+		return ctx.Err()
+	}
 }
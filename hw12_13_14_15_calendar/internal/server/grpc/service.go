@@ -3,10 +3,14 @@ package internalgrpc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -16,33 +20,83 @@ import (
 
 var ErrDateIsRequired = errors.New("date is required")
 
+const dateLayout = "2006-01-02"
+
+var (
+	errStartDateRequired  = errors.New("start date is required for all-day events")
+	errEndDateBeforeStart = errors.New("end date is before the start date")
+)
+
 type calendarServiceServer struct {
 	app Application
 	pb.UnimplementedCalendarServiceServer
 }
 
 func (s *calendarServiceServer) CreateEvent(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
-	id := uuid.New()
+	event := storage.Event{
+		ID:          uuid.New().String(),
+		Title:       req.GetTitle(),
+		Duration:    req.GetDuration().AsDuration(),
+		Description: req.GetDescription(),
+		CalendarID:  req.GetCalendarId(),
+		Tags:        req.GetTags(),
+		Color:       req.GetColor(),
+		AllDay:      req.GetAllDay(),
+	}
+
+	if req.GetStartsAt() != nil {
+		event.StartsAt = req.GetStartsAt().AsTime()
+	}
+
+	if event.AllDay {
+		if err := parseEventDates(&event, req.GetStartDate(), req.GetEndDate()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "event create error: %s", err)
+		}
+	}
+
+	if err := s.app.CreateEvent(ctx, event); err != nil {
+		return nil, eventError("event create error", err)
+	}
+
+	return &pb.CreateResponse{Id: event.ID}, nil
+}
 
-	if err := s.app.CreateEvent(ctx, id.String(), req.GetTitle()); err != nil {
-		return nil, status.Errorf(codes.Internal, "event create error: %s", err)
+func (s *calendarServiceServer) GetEvent(ctx context.Context, req *pb.GetRequest) (*pb.Event, error) {
+	event, err := s.app.GetEvent(ctx, req.GetId())
+	if err != nil {
+		return nil, eventError("event get error", err)
 	}
 
-	return &pb.CreateResponse{Id: id.String()}, nil
+	return formatResponseEvent(event), nil
 }
 
 func (s *calendarServiceServer) UpdateEvent(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
 	event := storage.Event{
-		ID:           req.GetId(),
-		Title:        req.GetTitle(),
-		StartsAt:     req.GetStartsAt().AsTime(),
-		Duration:     req.GetDuration().AsDuration(),
-		Description:  req.GetDescription(),
-		NotifyBefore: req.GetNotifyBefore().AsDuration(),
+		ID:          req.GetId(),
+		Title:       req.GetTitle(),
+		StartsAt:    req.GetStartsAt().AsTime(),
+		Duration:    req.GetDuration().AsDuration(),
+		Description: req.GetDescription(),
+		CalendarID:  req.GetCalendarId(),
+		Tags:        req.GetTags(),
+		Color:       req.GetColor(),
+		AllDay:      req.GetAllDay(),
+	}
+
+	// times are only required when there are no dates in their place.
+	switch {
+	case event.AllDay:
+		if err := parseEventDates(&event, req.GetStartDate(), req.GetEndDate()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "event update error: %s", err)
+		}
+	case req.GetStartsAt() == nil:
+		return nil, status.Error(codes.InvalidArgument, "invalid UpdateRequest.StartsAt: value is required")
+	case req.GetDuration() == nil:
+		return nil, status.Error(codes.InvalidArgument, "invalid UpdateRequest.Duration: value is required")
 	}
 
 	if err := s.app.UpdateEvent(ctx, req.GetId(), event); err != nil {
-		return nil, status.Errorf(codes.Internal, "event update error: %s", err)
+		return nil, eventError("event update error", err)
 	}
 
 	return &pb.UpdateResponse{Event: req}, nil
@@ -50,49 +104,944 @@ func (s *calendarServiceServer) UpdateEvent(ctx context.Context, req *pb.UpdateR
 
 func (s *calendarServiceServer) DeleteEvent(ctx context.Context, req *pb.DeleteRequest) (*emptypb.Empty, error) {
 	if err := s.app.DeleteEvent(ctx, req.GetId()); err != nil {
-		return nil, status.Errorf(codes.Internal, "event delete error: %s", err)
+		return nil, eventError("event delete error", err)
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
+func (s *calendarServiceServer) RestoreEvent(ctx context.Context, req *pb.RestoreRequest) (*pb.Event, error) {
+	event, err := s.app.RestoreEvent(ctx, req.GetId())
+	if err != nil {
+		return nil, eventError("event restore error", err)
+	}
+
+	return formatResponseEvent(event), nil
+}
+
+func (s *calendarServiceServer) ListDeletedEvents(
+	ctx context.Context, req *pb.ListDeletedRequest,
+) (*pb.ListResponse, error) {
+	events, err := s.app.ListDeletedEvents(ctx, parseTagsFilter(req.GetTags(), req.GetTagsMatch()))
+	if err != nil {
+		return nil, eventError("list deleted events error", err)
+	}
+
+	return &pb.ListResponse{Events: formatResponseEvents(events)}, nil
+}
+
+func (s *calendarServiceServer) SearchEvents(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	query := storage.SearchQuery{
+		Text:   req.GetQuery(),
+		Filter: parseTagsFilter(req.GetTags(), req.GetTagsMatch()),
+		Limit:  int(req.GetLimit()),
+	}
+
+	if req.GetFrom() != nil {
+		query.From = req.GetFrom().AsTime()
+	}
+
+	if req.GetTo() != nil {
+		query.To = req.GetTo().AsTime()
+	}
+
+	events, err := s.app.SearchEvents(ctx, query)
+	if errors.Is(err, app.ErrInvalidRange) {
+		return nil, status.Errorf(codes.InvalidArgument, "search events error: %s", err)
+	} else if err != nil {
+		return nil, eventError("search events error", err)
+	}
+
+	return &pb.SearchResponse{Events: formatResponseEvents(events)}, nil
+}
+
+func (s *calendarServiceServer) ListEventHistory(
+	ctx context.Context, req *pb.ListEventHistoryRequest,
+) (*pb.ListEventHistoryResponse, error) {
+	records, err := s.app.ListEventHistory(ctx, req.GetEventId())
+	if err != nil {
+		return nil, eventError("list event history error", err)
+	}
+
+	res := &pb.ListEventHistoryResponse{Records: make([]*pb.AuditRecord, 0, len(records))}
+
+	for _, r := range records {
+		res.Records = append(res.Records, formatResponseAuditRecord(r))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) ListEvents(ctx context.Context, req *pb.ListEventsRequest) (*pb.ListResponse, error) {
+	day, err := time.Parse(dateLayout, req.GetDate())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "list events error: invalid date %q", req.GetDate())
+	}
+
+	view := app.View(req.GetView())
+	if view == "" {
+		view = app.ViewDay
+	}
+
+	opts := app.ListOptions{
+		TimeZone:   req.GetTz(),
+		CalendarID: req.GetCalendarId(),
+		Tags:       req.GetTags(),
+		TagsMatch:  storage.TagsMatch(req.GetTagsMatch()),
+	}
+
+	if firstDay, ok := parseDayOfWeek(req.GetFirstDayOfWeek()); ok {
+		opts.FirstDayOfWeek = &firstDay
+	}
+
+	// validators are answered before events are listed, so an unchanged list costs no listing.
+	changedAt, err := s.app.ListChangedAt(ctx, view, opts)
+	if err != nil {
+		return nil, listError("list events error", err)
+	}
+
+	notModified, err := sendListValidators(ctx, changedAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list events error: %s", err)
+	}
+
+	if notModified {
+		return &pb.ListResponse{}, nil
+	}
+
+	events, err := s.app.ListEvents(ctx, view, day, opts)
+	if err != nil {
+		return nil, listError("list events error", err)
+	}
+
+	return &pb.ListResponse{Events: formatResponseEvents(events)}, nil
+}
+
 func (s *calendarServiceServer) ListDayEvents(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
-	events, err := s.app.ListDayEvents(ctx, req.GetDate().AsTime())
+	events, err := s.app.ListDayEvents(ctx, req.GetDate().AsTime(), parseListOptions(req))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "list day events error: %s", err)
+		return nil, listError("list day events error", err)
 	}
 
 	return &pb.ListResponse{Events: formatResponseEvents(events)}, nil
 }
 
 func (s *calendarServiceServer) ListWeekEvents(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
-	events, err := s.app.ListWeekEvents(ctx, req.GetDate().AsTime())
+	events, err := s.app.ListWeekEvents(ctx, req.GetDate().AsTime(), parseListOptions(req))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "list week events error: %s", err)
+		return nil, listError("list week events error", err)
 	}
 
 	return &pb.ListResponse{Events: formatResponseEvents(events)}, nil
 }
 
 func (s *calendarServiceServer) ListMonthEvents(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
-	events, err := s.app.ListMonthEvents(ctx, req.GetDate().AsTime())
+	events, err := s.app.ListMonthEvents(ctx, req.GetDate().AsTime(), parseListOptions(req))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "list month events error: %s", err)
+		return nil, listError("list month events error", err)
 	}
 
 	return &pb.ListResponse{Events: formatResponseEvents(events)}, nil
 }
 
+func (s *calendarServiceServer) InviteAttendee(ctx context.Context, req *pb.InviteRequest) (*pb.Attendee, error) {
+	attendee, err := s.app.InviteAttendee(ctx, storage.Attendee{
+		EventID: req.GetEventId(),
+		UserID:  req.GetUserId(),
+		Email:   req.GetEmail(),
+		Role:    parseAttendeeRole(req.GetRole()),
+	})
+	if err != nil {
+		return nil, attendeeError("invite attendee error", err)
+	}
+
+	return formatResponseAttendee(attendee), nil
+}
+
+func (s *calendarServiceServer) RemoveAttendee(
+	ctx context.Context, req *pb.RemoveAttendeeRequest,
+) (*emptypb.Empty, error) {
+	if err := s.app.RemoveAttendee(ctx, req.GetEventId(), req.GetUserId()); err != nil {
+		return nil, attendeeError("remove attendee error", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *calendarServiceServer) ListAttendees(
+	ctx context.Context, req *pb.ListAttendeesRequest,
+) (*pb.ListAttendeesResponse, error) {
+	attendees, err := s.app.ListAttendees(ctx, req.GetEventId())
+	if err != nil {
+		return nil, attendeeError("list attendees error", err)
+	}
+
+	res := &pb.ListAttendeesResponse{Attendees: make([]*pb.Attendee, 0, len(attendees))}
+
+	for _, a := range attendees {
+		res.Attendees = append(res.Attendees, formatResponseAttendee(a))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) RespondToInvitation(ctx context.Context, req *pb.RespondRequest) (*pb.Attendee, error) {
+	attendee, err := s.app.RespondToInvitation(ctx, req.GetEventId(), parseResponseStatus(req.GetStatus()))
+	if err != nil {
+		return nil, attendeeError("respond to invitation error", err)
+	}
+
+	return formatResponseAttendee(attendee), nil
+}
+
+func (s *calendarServiceServer) AddReminder(ctx context.Context, req *pb.AddReminderRequest) (*pb.Reminder, error) {
+	reminder, err := s.app.AddReminder(ctx, storage.Reminder{
+		ID:      uuid.New().String(),
+		EventID: req.GetEventId(),
+		Offset:  req.GetOffset().AsDuration(),
+		Channel: reminderChannels[req.GetChannel()],
+		Message: req.GetMessage(),
+	})
+	if err != nil {
+		return nil, reminderError("add reminder error", err)
+	}
+
+	return formatResponseReminder(reminder), nil
+}
+
+func (s *calendarServiceServer) RemoveReminder(
+	ctx context.Context, req *pb.RemoveReminderRequest,
+) (*emptypb.Empty, error) {
+	if err := s.app.RemoveReminder(ctx, req.GetEventId(), req.GetId()); err != nil {
+		return nil, reminderError("remove reminder error", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *calendarServiceServer) ListReminders(
+	ctx context.Context, req *pb.ListRemindersRequest,
+) (*pb.ListRemindersResponse, error) {
+	reminders, err := s.app.ListReminders(ctx, req.GetEventId())
+	if err != nil {
+		return nil, reminderError("list reminders error", err)
+	}
+
+	res := &pb.ListRemindersResponse{Reminders: make([]*pb.Reminder, 0, len(reminders))}
+
+	for _, r := range reminders {
+		res.Reminders = append(res.Reminders, formatResponseReminder(r))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) ListNotifications(
+	ctx context.Context, req *pb.ListNotificationsRequest,
+) (*pb.ListNotificationsResponse, error) {
+	notifications, err := s.app.ListNotifications(ctx, storage.NotificationFilter{
+		EventID: req.GetEventId(),
+		Status:  notificationStatuses[req.GetStatus()],
+	})
+	if err != nil {
+		return nil, notificationError("list notifications error", err)
+	}
+
+	res := &pb.ListNotificationsResponse{Notifications: make([]*pb.Notification, 0, len(notifications))}
+
+	for _, n := range notifications {
+		res.Notifications = append(res.Notifications, formatResponseNotification(n))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) ResendNotification(
+	ctx context.Context, req *pb.ResendNotificationRequest,
+) (*pb.Notification, error) {
+	notification, err := s.app.ResendNotification(ctx, req.GetId())
+	if err != nil {
+		return nil, notificationError("resend notification error", err)
+	}
+
+	return formatResponseNotification(notification), nil
+}
+
+func (s *calendarServiceServer) FreeBusy(ctx context.Context, req *pb.FreeBusyRequest) (*pb.FreeBusyResponse, error) {
+	freeBusy, err := s.app.FreeBusy(
+		ctx, req.GetUserIds(), req.GetFrom().AsTime(), req.GetTo().AsTime(), req.GetMinFreeSlot().AsDuration(),
+	)
+	if err != nil {
+		return nil, freeBusyError("free busy error", err)
+	}
+
+	res := &pb.FreeBusyResponse{
+		Users: make([]*pb.UserBusy, 0, len(freeBusy.Users)),
+		Free:  formatResponseIntervals(freeBusy.Free),
+	}
+
+	for _, u := range freeBusy.Users {
+		res.Users = append(res.Users, &pb.UserBusy{UserId: u.UserID, Busy: formatResponseIntervals(u.Busy)})
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) CreateCalendar(
+	ctx context.Context, req *pb.CreateCalendarRequest,
+) (*pb.Calendar, error) {
+	calendar, err := s.app.CreateCalendar(ctx, storage.Calendar{
+		ID:       uuid.New().String(),
+		Name:     req.GetName(),
+		Color:    req.GetColor(),
+		TimeZone: req.GetTimeZone(),
+	})
+	if err != nil {
+		return nil, calendarError("calendar create error", err)
+	}
+
+	return formatResponseCalendar(app.UserCalendar{Calendar: calendar, Access: storage.AccessOwner}), nil
+}
+
+func (s *calendarServiceServer) UpdateCalendar(
+	ctx context.Context, req *pb.UpdateCalendarRequest,
+) (*pb.Calendar, error) {
+	calendar, err := s.app.UpdateCalendar(ctx, storage.Calendar{
+		ID:       req.GetId(),
+		Name:     req.GetName(),
+		Color:    req.GetColor(),
+		TimeZone: req.GetTimeZone(),
+	})
+	if err != nil {
+		return nil, calendarError("calendar update error", err)
+	}
+
+	return formatResponseCalendar(app.UserCalendar{Calendar: calendar, Access: storage.AccessOwner}), nil
+}
+
+func (s *calendarServiceServer) GetCalendar(ctx context.Context, req *pb.GetCalendarRequest) (*pb.Calendar, error) {
+	calendar, err := s.app.GetCalendar(ctx, req.GetId())
+	if err != nil {
+		return nil, calendarError("calendar get error", err)
+	}
+
+	return formatResponseCalendar(calendar), nil
+}
+
+func (s *calendarServiceServer) DeleteCalendar(
+	ctx context.Context, req *pb.DeleteCalendarRequest,
+) (*emptypb.Empty, error) {
+	if err := s.app.DeleteCalendar(ctx, req.GetId()); err != nil {
+		return nil, calendarError("calendar delete error", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *calendarServiceServer) ListCalendars(ctx context.Context, _ *emptypb.Empty) (*pb.ListCalendarsResponse, error) {
+	calendars, err := s.app.ListCalendars(ctx)
+	if err != nil {
+		return nil, calendarError("list calendars error", err)
+	}
+
+	res := &pb.ListCalendarsResponse{Calendars: make([]*pb.Calendar, 0, len(calendars))}
+
+	for _, c := range calendars {
+		res.Calendars = append(res.Calendars, formatResponseCalendar(c))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) ShareCalendar(
+	ctx context.Context, req *pb.ShareCalendarRequest,
+) (*pb.CalendarShare, error) {
+	share := storage.CalendarShare{
+		CalendarID: req.GetCalendarId(),
+		UserID:     req.GetUserId(),
+		Access:     accessLevels[req.GetAccess()],
+	}
+
+	if err := s.app.ShareCalendar(ctx, share); err != nil {
+		return nil, calendarError("share calendar error", err)
+	}
+
+	return formatResponseShare(share), nil
+}
+
+func (s *calendarServiceServer) UnshareCalendar(
+	ctx context.Context, req *pb.UnshareCalendarRequest,
+) (*emptypb.Empty, error) {
+	if err := s.app.UnshareCalendar(ctx, req.GetCalendarId(), req.GetUserId()); err != nil {
+		return nil, calendarError("unshare calendar error", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *calendarServiceServer) ListCalendarShares(
+	ctx context.Context, req *pb.ListCalendarSharesRequest,
+) (*pb.ListCalendarSharesResponse, error) {
+	shares, err := s.app.ListCalendarShares(ctx, req.GetCalendarId())
+	if err != nil {
+		return nil, calendarError("list calendar shares error", err)
+	}
+
+	res := &pb.ListCalendarSharesResponse{Shares: make([]*pb.CalendarShare, 0, len(shares))}
+
+	for _, share := range shares {
+		res.Shares = append(res.Shares, formatResponseShare(share))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) CreateWebhook(
+	ctx context.Context, req *pb.CreateWebhookRequest,
+) (*pb.Webhook, error) {
+	operations := make(storage.Operations, 0, len(req.GetOperations()))
+
+	for _, op := range req.GetOperations() {
+		operations = append(operations, parseAuditOperation(op))
+	}
+
+	webhook, err := s.app.CreateWebhook(ctx, storage.Webhook{
+		ID:         uuid.New().String(),
+		URL:        req.GetUrl(),
+		Secret:     req.GetSecret(),
+		Operations: operations,
+	})
+	if err != nil {
+		return nil, webhookError("webhook create error", err)
+	}
+
+	return formatResponseWebhook(webhook), nil
+}
+
+func (s *calendarServiceServer) DeleteWebhook(
+	ctx context.Context, req *pb.DeleteWebhookRequest,
+) (*emptypb.Empty, error) {
+	if err := s.app.DeleteWebhook(ctx, req.GetId()); err != nil {
+		return nil, webhookError("webhook delete error", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *calendarServiceServer) ListWebhooks(ctx context.Context, _ *emptypb.Empty) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := s.app.ListWebhooks(ctx)
+	if err != nil {
+		return nil, webhookError("list webhooks error", err)
+	}
+
+	res := &pb.ListWebhooksResponse{Webhooks: make([]*pb.Webhook, 0, len(webhooks))}
+
+	for _, w := range webhooks {
+		res.Webhooks = append(res.Webhooks, formatResponseWebhook(w))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) ListWebhookDeliveries(
+	ctx context.Context, req *pb.ListWebhookDeliveriesRequest,
+) (*pb.ListWebhookDeliveriesResponse, error) {
+	deliveries, err := s.app.ListWebhookDeliveries(ctx, req.GetWebhookId())
+	if err != nil {
+		return nil, webhookError("list webhook deliveries error", err)
+	}
+
+	res := &pb.ListWebhookDeliveriesResponse{Deliveries: make([]*pb.WebhookDelivery, 0, len(deliveries))}
+
+	for _, d := range deliveries {
+		res.Deliveries = append(res.Deliveries, formatResponseDelivery(d))
+	}
+
+	return res, nil
+}
+
+func (s *calendarServiceServer) GetSettings(ctx context.Context, _ *emptypb.Empty) (*pb.Settings, error) {
+	settings, err := s.app.GetUserSettings(ctx)
+	if err != nil {
+		return nil, settingsError("get settings error", err)
+	}
+
+	return formatResponseSettings(settings), nil
+}
+
+func (s *calendarServiceServer) UpdateSettings(ctx context.Context, req *pb.Settings) (*pb.Settings, error) {
+	settings := storage.UserSettings{
+		TimeZone:       req.GetTimeZone(),
+		FirstDayOfWeek: time.Monday,
+	}
+
+	if day, ok := parseDayOfWeek(req.GetFirstDayOfWeek()); ok {
+		settings.FirstDayOfWeek = day
+	}
+
+	settings, err := s.app.UpdateUserSettings(ctx, settings)
+	if err != nil {
+		return nil, settingsError("update settings error", err)
+	}
+
+	return formatResponseSettings(settings), nil
+}
+
+func eventError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrCalendarNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func listError(msg string, err error) error {
+	if errors.Is(err, app.ErrInvalidTimeZone) || errors.Is(err, app.ErrInvalidView) {
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	}
+
+	return eventError(msg, err)
+}
+
+func settingsError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrInvalidTimeZone):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func attendeeError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrOwnerInvited):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrAttendeeNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func reminderError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrReminderNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func notificationError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrNotificationNotFailed):
+		return status.Errorf(codes.FailedPrecondition, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrEventNotFound), errors.Is(err, storage.ErrNotificationNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func freeBusyError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrInvalidRange):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func webhookError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrInvalidWebhookURL):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func calendarError(msg string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrUserIDRequired):
+		return status.Errorf(codes.Unauthenticated, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrPermissionDenied):
+		return status.Errorf(codes.PermissionDenied, "%s: %s", msg, err)
+	case errors.Is(err, app.ErrInvalidTimeZone), errors.Is(err, app.ErrOwnerShared):
+		return status.Errorf(codes.InvalidArgument, "%s: %s", msg, err)
+	case errors.Is(err, storage.ErrCalendarNotFound), errors.Is(err, storage.ErrShareNotFound):
+		return status.Errorf(codes.NotFound, "%s: %s", msg, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %s", msg, err)
+	}
+}
+
+func parseListOptions(req *pb.ListRequest) app.ListOptions {
+	opts := app.ListOptions{
+		TimeZone:   req.GetTimeZone(),
+		CalendarID: req.GetCalendarId(),
+		Tags:       req.GetTags(),
+		TagsMatch:  storage.TagsMatch(req.GetTagsMatch()),
+	}
+
+	if day, ok := parseDayOfWeek(req.GetFirstDayOfWeek()); ok {
+		opts.FirstDayOfWeek = &day
+	}
+
+	return opts
+}
+
+// parseEventDates sets the start and duration of an all-day event by its first and last dates,
+// the event lasts the first date only if the last one is empty.
+func parseEventDates(event *storage.Event, startDate, endDate string) error {
+	if startDate == "" {
+		return errStartDateRequired
+	}
+
+	if endDate == "" {
+		endDate = startDate
+	}
+
+	start, err := time.Parse(dateLayout, startDate)
+	if err != nil {
+		return fmt.Errorf("invalid start date %q", startDate)
+	}
+
+	end, err := time.Parse(dateLayout, endDate)
+	if err != nil {
+		return fmt.Errorf("invalid end date %q", endDate)
+	}
+
+	if end.Before(start) {
+		return errEndDateBeforeStart
+	}
+
+	event.StartsAt = start
+	event.Duration = end.AddDate(0, 0, 1).Sub(start)
+
+	return nil
+}
+
+func parseTagsFilter(tags []string, match string) storage.EventFilter {
+	return storage.EventFilter{Tags: tags, TagsMatch: storage.TagsMatch(match)}
+}
+
+func parseDayOfWeek(day dayofweek.DayOfWeek) (time.Weekday, bool) {
+	switch day {
+	case dayofweek.DayOfWeek_DAY_OF_WEEK_UNSPECIFIED:
+		return 0, false
+	case dayofweek.DayOfWeek_SUNDAY:
+		return time.Sunday, true
+	default:
+		return time.Weekday(day), true
+	}
+}
+
+func formatDayOfWeek(day time.Weekday) dayofweek.DayOfWeek {
+	if day == time.Sunday {
+		return dayofweek.DayOfWeek_SUNDAY
+	}
+
+	return dayofweek.DayOfWeek(day)
+}
+
+var (
+	attendeeRoles = map[pb.AttendeeRole]storage.AttendeeRole{
+		pb.AttendeeRole_ATTENDEE_ROLE_REQUIRED: storage.RoleRequired,
+		pb.AttendeeRole_ATTENDEE_ROLE_OPTIONAL: storage.RoleOptional,
+	}
+	responseStatuses = map[pb.ResponseStatus]storage.AttendeeStatus{
+		pb.ResponseStatus_RESPONSE_STATUS_NEEDS_ACTION: storage.StatusNeedsAction,
+		pb.ResponseStatus_RESPONSE_STATUS_ACCEPTED:     storage.StatusAccepted,
+		pb.ResponseStatus_RESPONSE_STATUS_DECLINED:     storage.StatusDeclined,
+		pb.ResponseStatus_RESPONSE_STATUS_TENTATIVE:    storage.StatusTentative,
+	}
+	accessLevels = map[pb.AccessLevel]storage.AccessLevel{
+		pb.AccessLevel_ACCESS_LEVEL_FREE_BUSY: storage.AccessFreeBusy,
+		pb.AccessLevel_ACCESS_LEVEL_READ:      storage.AccessRead,
+		pb.AccessLevel_ACCESS_LEVEL_WRITE:     storage.AccessWrite,
+		pb.AccessLevel_ACCESS_LEVEL_OWNER:     storage.AccessOwner,
+	}
+	reminderChannels = map[pb.ReminderChannel]storage.Channel{
+		pb.ReminderChannel_REMINDER_CHANNEL_LOG:     storage.ChannelLog,
+		pb.ReminderChannel_REMINDER_CHANNEL_WEBHOOK: storage.ChannelWebhook,
+		pb.ReminderChannel_REMINDER_CHANNEL_EMAIL:   storage.ChannelEmail,
+	}
+	auditOperations = map[storage.AuditOperation]pb.AuditOperation{
+		storage.AuditCreate:  pb.AuditOperation_AUDIT_OPERATION_CREATE,
+		storage.AuditUpdate:  pb.AuditOperation_AUDIT_OPERATION_UPDATE,
+		storage.AuditDelete:  pb.AuditOperation_AUDIT_OPERATION_DELETE,
+		storage.AuditRestore: pb.AuditOperation_AUDIT_OPERATION_RESTORE,
+	}
+	notificationStatuses = map[pb.NotificationStatus]storage.NotificationStatus{
+		pb.NotificationStatus_NOTIFICATION_STATUS_PENDING: storage.NotificationPending,
+		pb.NotificationStatus_NOTIFICATION_STATUS_SENT:    storage.NotificationSent,
+		pb.NotificationStatus_NOTIFICATION_STATUS_FAILED:  storage.NotificationFailed,
+	}
+	deliveryStatuses = map[storage.DeliveryStatus]pb.DeliveryStatus{
+		storage.DeliveryPending:   pb.DeliveryStatus_DELIVERY_STATUS_PENDING,
+		storage.DeliveryDelivered: pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED,
+		storage.DeliveryFailed:    pb.DeliveryStatus_DELIVERY_STATUS_FAILED,
+	}
+)
+
+// parseAttendeeRole leaves the role empty when unspecified, so the default is up to the app.
+func parseAttendeeRole(role pb.AttendeeRole) storage.AttendeeRole {
+	return attendeeRoles[role]
+}
+
+func formatAttendeeRole(role storage.AttendeeRole) pb.AttendeeRole {
+	for r, value := range attendeeRoles {
+		if value == role {
+			return r
+		}
+	}
+
+	return pb.AttendeeRole_ATTENDEE_ROLE_UNSPECIFIED
+}
+
+func parseResponseStatus(status pb.ResponseStatus) storage.AttendeeStatus {
+	return responseStatuses[status]
+}
+
+func formatResponseStatus(status storage.AttendeeStatus) pb.ResponseStatus {
+	for s, value := range responseStatuses {
+		if value == status {
+			return s
+		}
+	}
+
+	return pb.ResponseStatus_RESPONSE_STATUS_UNSPECIFIED
+}
+
+func formatResponseAttendee(attendee storage.Attendee) *pb.Attendee {
+	return &pb.Attendee{
+		EventId: attendee.EventID,
+		UserId:  attendee.UserID,
+		Email:   attendee.Email,
+		Role:    formatAttendeeRole(attendee.Role),
+		Status:  formatResponseStatus(attendee.Status),
+	}
+}
+
+func formatAccessLevel(access storage.AccessLevel) pb.AccessLevel {
+	for a, value := range accessLevels {
+		if value == access {
+			return a
+		}
+	}
+
+	return pb.AccessLevel_ACCESS_LEVEL_UNSPECIFIED
+}
+
+func formatResponseCalendar(calendar app.UserCalendar) *pb.Calendar {
+	return &pb.Calendar{
+		Id:       calendar.ID,
+		OwnerId:  calendar.OwnerID,
+		Name:     calendar.Name,
+		Color:    calendar.Color,
+		TimeZone: calendar.TimeZone,
+		Access:   formatAccessLevel(calendar.Access),
+	}
+}
+
+func formatResponseShare(share storage.CalendarShare) *pb.CalendarShare {
+	return &pb.CalendarShare{
+		CalendarId: share.CalendarID,
+		UserId:     share.UserID,
+		Access:     formatAccessLevel(share.Access),
+	}
+}
+
+func formatReminderChannel(channel storage.Channel) pb.ReminderChannel {
+	for c, value := range reminderChannels {
+		if value == channel {
+			return c
+		}
+	}
+
+	return pb.ReminderChannel_REMINDER_CHANNEL_UNSPECIFIED
+}
+
+func formatResponseReminder(reminder storage.Reminder) *pb.Reminder {
+	res := &pb.Reminder{
+		Id:       reminder.ID,
+		EventId:  reminder.EventID,
+		Offset:   durationpb.New(reminder.Offset),
+		Channel:  formatReminderChannel(reminder.Channel),
+		Message:  reminder.Message,
+		RemindAt: timestamppb.New(reminder.RemindAt),
+	}
+
+	if reminder.FiredAt != nil {
+		res.FiredAt = timestamppb.New(*reminder.FiredAt)
+	}
+
+	return res
+}
+
+func formatNotificationStatus(status storage.NotificationStatus) pb.NotificationStatus {
+	for s, value := range notificationStatuses {
+		if value == status {
+			return s
+		}
+	}
+
+	return pb.NotificationStatus_NOTIFICATION_STATUS_UNSPECIFIED
+}
+
+func formatResponseNotification(notification storage.Notification) *pb.Notification {
+	res := &pb.Notification{
+		Id:          notification.ID,
+		EventId:     notification.EventID,
+		ReminderId:  notification.ReminderID,
+		UserId:      notification.UserID,
+		Channel:     formatReminderChannel(notification.Channel),
+		ScheduledAt: timestamppb.New(notification.ScheduledAt),
+		Status:      formatNotificationStatus(notification.Status),
+		Attempts:    int32(notification.Attempts),
+		LastError:   notification.LastError,
+		CreatedAt:   timestamppb.New(notification.CreatedAt),
+	}
+
+	if notification.SentAt != nil {
+		res.SentAt = timestamppb.New(*notification.SentAt)
+	}
+
+	return res
+}
+
+func formatResponseAuditRecord(record storage.AuditRecord) *pb.AuditRecord {
+	res := &pb.AuditRecord{
+		Id:        record.ID,
+		EventId:   record.EventID,
+		ActorId:   record.ActorID,
+		Operation: auditOperations[record.Operation],
+		CreatedAt: timestamppb.New(record.CreatedAt),
+		Changes:   make([]*pb.AuditChange, 0, len(record.Changes)),
+	}
+
+	for _, c := range record.Changes {
+		res.Changes = append(res.Changes, &pb.AuditChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+
+	return res
+}
+
+func parseAuditOperation(op pb.AuditOperation) storage.AuditOperation {
+	for value, o := range auditOperations {
+		if o == op {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func formatResponseWebhook(webhook storage.Webhook) *pb.Webhook {
+	res := &pb.Webhook{
+		Id:         webhook.ID,
+		Url:        webhook.URL,
+		Operations: make([]pb.AuditOperation, 0, len(webhook.Operations)),
+	}
+
+	for _, op := range webhook.Operations {
+		res.Operations = append(res.Operations, auditOperations[op])
+	}
+
+	return res
+}
+
+func formatResponseDelivery(delivery storage.WebhookDelivery) *pb.WebhookDelivery {
+	res := &pb.WebhookDelivery{
+		Id:            delivery.ID,
+		WebhookId:     delivery.WebhookID,
+		EventId:       delivery.EventID,
+		Operation:     auditOperations[delivery.Operation],
+		Status:        deliveryStatuses[delivery.Status],
+		Attempts:      int32(delivery.Attempts),
+		NextAttemptAt: timestamppb.New(delivery.NextAttemptAt),
+		LastError:     delivery.LastError,
+		CreatedAt:     timestamppb.New(delivery.CreatedAt),
+	}
+
+	if delivery.DeliveredAt != nil {
+		res.DeliveredAt = timestamppb.New(*delivery.DeliveredAt)
+	}
+
+	return res
+}
+
+func formatResponseIntervals(intervals []storage.Interval) []*pb.TimeInterval {
+	res := make([]*pb.TimeInterval, 0, len(intervals))
+
+	for _, i := range intervals {
+		res = append(res, &pb.TimeInterval{Start: timestamppb.New(i.Start), End: timestamppb.New(i.End)})
+	}
+
+	return res
+}
+
+func formatResponseSettings(settings storage.UserSettings) *pb.Settings {
+	return &pb.Settings{
+		TimeZone:       settings.TimeZone,
+		FirstDayOfWeek: formatDayOfWeek(settings.FirstDayOfWeek),
+	}
+}
+
 func formatResponseEvent(event storage.Event) *pb.Event {
-	return &pb.Event{
-		Id:           event.ID,
-		Title:        event.Title,
-		StartsAt:     timestamppb.New(event.StartsAt),
-		Duration:     durationpb.New(event.Duration),
-		Description:  event.Description,
-		OwnerId:      event.OwnerID,
-		NotifyBefore: durationpb.New(event.NotifyBefore),
+	res := &pb.Event{
+		Id:          event.ID,
+		Title:       event.Title,
+		StartsAt:    timestamppb.New(event.StartsAt),
+		Duration:    durationpb.New(event.Duration),
+		Description: event.Description,
+		OwnerId:     event.OwnerID,
+		CalendarId:  event.CalendarID,
+		Tags:        event.Tags,
+		Color:       event.Color,
+		AllDay:      event.AllDay,
 	}
+
+	if event.AllDay {
+		res.StartDate = event.StartsAt.Format(dateLayout)
+		res.EndDate = event.EndDate().Format(dateLayout)
+	}
+
+	if event.DeletedAt != nil {
+		res.DeletedAt = timestamppb.New(*event.DeletedAt)
+	}
+
+	return res
 }
 
 func formatResponseEvents(events []storage.Event) []*pb.Event {
@@ -0,0 +1,54 @@
+package internalhttp
+
+import "net/http"
+
+// cacheControlWriter marks responses carrying validators as revalidated before reuse
+// and drops the body of 304 Not Modified.
+type cacheControlWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	notModified bool
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	header := w.Header()
+
+	if header.Get("ETag") != "" {
+		header.Set("Cache-Control", "private, no-cache")
+		header.Add("Vary", "X-User-Id")
+	}
+
+	if code == http.StatusNotModified {
+		w.notModified = true
+
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheControlWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	if w.notModified {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// conditionalMiddleware completes conditional responses of lists, the service derives ETag
+// and Last-Modified from the newest change of events the list shows and answers If-None-Match
+// and If-Modified-Since before listing them. Responses depend on the user, so they are only kept
+// by the client, revalidated before reuse and vary by the user ID header.
+func conditionalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&cacheControlWriter{ResponseWriter: w}, r)
+	})
+}
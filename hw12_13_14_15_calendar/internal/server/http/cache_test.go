@@ -0,0 +1,110 @@
+package internalhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+// gatewayHandler replies the way the gateway forwards a list, the service tells whether it's not modified.
+func gatewayHandler(notModified bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		w.Header().Set("Content-Type", "application/json")
+
+		if notModified {
+			w.WriteHeader(http.StatusNotModified)
+		}
+
+		_, _ = io.WriteString(w, `{"events":[]}`)
+	})
+}
+
+func TestConditionalMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		notModified  bool
+		expected     int
+		expectedBody string
+	}{
+		{"modified", false, http.StatusOK, `{"events":[]}`},
+		{"not modified", true, http.StatusNotModified, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			conditionalMiddleware(gatewayHandler(tt.notModified)).ServeHTTP(
+				rec, httptest.NewRequest(http.MethodGet, "/events?date=2022-01-10", nil),
+			)
+
+			require.Equal(t, tt.expected, rec.Code)
+			require.Equal(t, tt.expectedBody, rec.Body.String())
+			require.Equal(t, `"1"`, rec.Header().Get("ETag"))
+			require.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+			require.Equal(t, "X-User-Id", rec.Header().Get("Vary"))
+
+			if tt.notModified {
+				require.Empty(t, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestConditionalMiddlewareErrors(t *testing.T) {
+	handler := conditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "not found\n", rec.Body.String())
+	require.Empty(t, rec.Header().Get("Cache-Control"))
+	require.Empty(t, rec.Header().Get("Vary"))
+}
+
+func TestOutgoingHeaderMatcher(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+		ok       bool
+	}{
+		{internalgrpc.ETagMetadataKey, "ETag", true},
+		{internalgrpc.LastModifiedMetadataKey, "Last-Modified", true},
+		{internalgrpc.NotModifiedMetadataKey, "", false},
+		{"x-custom", runtime.MetadataHeaderPrefix + "x-custom", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			header, ok := outgoingHeaderMatcher(tt.key)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, header)
+		})
+	}
+}
+
+func TestForwardNotModified(t *testing.T) {
+	forward := func(md metadata.MD) int {
+		ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{HeaderMD: md})
+		rec := httptest.NewRecorder()
+
+		require.NoError(t, forwardNotModified(ctx, rec, nil))
+
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, forward(metadata.Pairs(internalgrpc.ETagMetadataKey, `"1"`)))
+	require.Equal(t, http.StatusNotModified, forward(metadata.Pairs(internalgrpc.NotModifiedMetadataKey, "true")))
+}
@@ -0,0 +1,35 @@
+package internalhttp
+
+import (
+	"embed"
+	"net/http"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/api"
+)
+
+const docsPath = "/docs/"
+
+// docsPolicy lets the page load nothing but itself, Swagger UI styles only embed images as data URLs.
+const docsPolicy = "default-src 'self'; img-src 'self' data:"
+
+// docs keep the Swagger UI page along with the UI itself, so it works offline as well.
+//
+//go:embed docs
+var docs embed.FS
+
+// docsHandler serves Swagger UI at /docs/ and the OpenAPI document it shows at /docs/openapi.json.
+func docsHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle(docsPath, http.FileServer(http.FS(docs)))
+	mux.HandleFunc(docsPath+"openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(api.OpenAPI)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", docsPolicy)
+
+		mux.ServeHTTP(w, r)
+	})
+}
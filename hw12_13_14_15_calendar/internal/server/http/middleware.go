@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
@@ -34,3 +36,151 @@ func loggingMiddleware(next http.Handler, logger app.Logger) http.Handler {
 		))
 	})
 }
+
+// CORS configures cross-origin requests of browsers, they are refused while AllowedOrigins is empty.
+type CORS struct {
+	// AllowedOrigins are matched exactly, "*" allows any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders are request headers besides the ones browsers always allow, matched ignoring case.
+	AllowedHeaders []string
+	// ExposedHeaders are response headers scripts can read besides the ones browsers always expose.
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses, zero leaves it to them.
+	MaxAge time.Duration
+}
+
+func (c CORS) allowsOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsPreflight checks the method and headers the actual request is going to have.
+func (c CORS) allowsPreflight(r *http.Request) bool {
+	if !containsFold(c.AllowedMethods, r.Header.Get("Access-Control-Request-Method")) {
+		return false
+	}
+
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if h = strings.TrimSpace(h); h != "" && !containsFold(c.AllowedHeaders, h) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsMiddleware answers preflight requests of allowed origins itself and lets browsers
+// read responses to their actual requests, requests of other origins are left without CORS headers.
+func corsMiddleware(next http.Handler, cors CORS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !cors.allowsOrigin(origin) || (preflight && !cors.allowsPreflight(r)) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		// the wildcard can't go along with credentials, the origin itself is allowed then.
+		if containsFold(cors.AllowedOrigins, "*") && !cors.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if cors.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(cors.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
+			}
+
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+
+		if len(cors.AllowedHeaders) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+		}
+
+		if cors.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(int(cors.MaxAge.Seconds())))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// securityHeadersMiddleware keeps browsers from sniffing content types, framing responses
+// and running anything a response may contain. Handlers serving pages override the policy.
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyLimitMiddleware refuses requests declaring a body over the limit and cuts off the ones sending more,
+// zero limit lets any body through.
+func bodyLimitMiddleware(next http.Handler, limit int64) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,155 @@
+package internalhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "ok")
+})
+
+func TestCORSMiddleware(t *testing.T) {
+	cors := CORS{
+		AllowedOrigins: []string{"https://calendar.example"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "X-User-Id"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	serve := func(cors CORS, method string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/events", nil)
+		req.Header = header
+		rec := httptest.NewRecorder()
+
+		corsMiddleware(okHandler, cors).ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	preflight := http.Header{
+		"Origin":                         {"https://calendar.example"},
+		"Access-Control-Request-Method":  {"POST"},
+		"Access-Control-Request-Headers": {"content-type, x-user-id"},
+	}
+
+	t.Run("preflight", func(t *testing.T) {
+		rec := serve(cors, http.MethodOptions, preflight)
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Empty(t, rec.Body.String())
+		require.Equal(t, "https://calendar.example", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+		require.Equal(t, "Content-Type, X-User-Id", rec.Header().Get("Access-Control-Allow-Headers"))
+		require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("refused preflight", func(t *testing.T) {
+		for _, header := range []http.Header{
+			{"Origin": {"https://evil.example"}, "Access-Control-Request-Method": {"POST"}},
+			{"Origin": {"https://calendar.example"}, "Access-Control-Request-Method": {"DELETE"}},
+			{
+				"Origin":                         {"https://calendar.example"},
+				"Access-Control-Request-Method":  {"POST"},
+				"Access-Control-Request-Headers": {"X-Debug"},
+			},
+		} {
+			rec := serve(cors, http.MethodOptions, header)
+			require.Equal(t, http.StatusForbidden, rec.Code)
+			require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	})
+
+	t.Run("actual request", func(t *testing.T) {
+		rec := serve(cors, http.MethodGet, http.Header{"Origin": {"https://calendar.example"}})
+		require.Equal(t, "ok", rec.Body.String())
+		require.Equal(t, "https://calendar.example", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "ETag", rec.Header().Get("Access-Control-Expose-Headers"))
+		require.Equal(t, []string{"Origin"}, rec.Header().Values("Vary"))
+	})
+
+	t.Run("other origin", func(t *testing.T) {
+		rec := serve(cors, http.MethodGet, http.Header{"Origin": {"https://evil.example"}})
+		require.Equal(t, "ok", rec.Body.String())
+		require.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("same origin", func(t *testing.T) {
+		rec := serve(cors, http.MethodGet, http.Header{})
+		require.Equal(t, "ok", rec.Body.String())
+		require.Empty(t, rec.Header().Get("Vary"))
+	})
+
+	t.Run("any origin", func(t *testing.T) {
+		wildcard := cors
+		wildcard.AllowedOrigins = []string{"*"}
+
+		rec := serve(wildcard, http.MethodOptions, preflight)
+		require.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+		// credentials can't be sent to the wildcard.
+		wildcard.AllowCredentials = true
+
+		rec = serve(wildcard, http.MethodOptions, preflight)
+		require.Equal(t, "https://calendar.example", rec.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	rec := httptest.NewRecorder()
+	securityHeadersMiddleware(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	require.Equal(t, "no-referrer", rec.Header().Get("Referrer-Policy"))
+	require.Equal(t, "default-src 'none'; frame-ancestors 'none'", rec.Header().Get("Content-Security-Policy"))
+
+	// pages set policies of their own.
+	rec = httptest.NewRecorder()
+	securityHeadersMiddleware(docsHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+
+	require.Equal(t, docsPolicy, rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = w.Write(body)
+	})
+
+	serve := func(limit int64, body io.Reader) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		bodyLimitMiddleware(echo, limit).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events", body))
+
+		return rec
+	}
+
+	rec := serve(4, strings.NewReader("1234"))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "1234", rec.Body.String())
+
+	rec = serve(4, strings.NewReader("12345"))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// bodies of unknown length are cut off while read.
+	rec = serve(4, io.MultiReader(strings.NewReader("12345")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "http: request body too large\n", rec.Body.String())
+
+	rec = serve(0, strings.NewReader("12345"))
+	require.Equal(t, "12345", rec.Body.String())
+}
@@ -2,18 +2,27 @@ package internalhttp
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 )
 
 type Server struct {
 	httpAddress string
 	grpcAddress string
 	logger      Logger
+	opts        Options
 	server      *http.Server
+	listener    net.Listener
+	ctx         context.Context
+	cancelFn    context.CancelFunc
 }
 
 type Logger interface {
@@ -21,36 +30,149 @@ type Logger interface {
 	Error(msg string)
 }
 
-func NewServer(httpAddress, grpcAddress string, logger Logger) *Server {
-	return &Server{httpAddress, grpcAddress, logger, nil}
+// Options configure how the server treats requests of browsers and clients in general.
+type Options struct {
+	CORS CORS
+	// MaxBodySize bounds request bodies in bytes, zero means no limit.
+	MaxBodySize int64
 }
 
-func (s *Server) Start(ctx context.Context) error {
-	conn, err := grpc.DialContext(ctx, s.grpcAddress, grpc.WithBlock(), grpc.WithInsecure())
+func NewServer(httpAddress, grpcAddress string, logger Logger, opts Options) *Server {
+	ctx, cancelFn := context.WithCancel(context.Background())
+
+	return &Server{
+		httpAddress: httpAddress,
+		grpcAddress: grpcAddress,
+		logger:      logger,
+		opts:        opts,
+		server:      &http.Server{Addr: httpAddress},
+		ctx:         ctx,
+		cancelFn:    cancelFn,
+	}
+}
+
+// Listen binds the server address ahead of Run, so the actual address of port 0 is known.
+func (s *Server) Listen() error {
+	if s.listener != nil {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", s.httpAddress)
 	if err != nil {
 		return err
 	}
 
-	mux := runtime.NewServeMux()
+	s.listener = lis
+
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.httpAddress
+	}
 
-	if err = pb.RegisterCalendarServiceHandler(ctx, mux, conn); err != nil {
+	return s.listener.Addr().String()
+}
+
+// Run proxies requests to the gRPC server and serves the API docs, it is ready once connected to the gRPC server.
+func (s *Server) Run(ready func()) error {
+	if err := s.Listen(); err != nil {
 		return err
 	}
 
-	s.server = &http.Server{
-		Addr:    s.httpAddress,
-		Handler: loggingMiddleware(mux, s.logger),
+	conn, err := grpc.DialContext(s.ctx, s.grpcAddress, grpc.WithBlock(), grpc.WithInsecure())
+	if err != nil {
+		// stopped while still connecting.
+		if s.ctx.Err() != nil {
+			return nil
+		}
+
+		return err
 	}
 
-	if err := s.server.ListenAndServe(); err != nil {
+	gateway := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithForwardResponseOption(forwardNotModified),
+	)
+
+	// the connection is closed along with s.ctx.
+	if err = pb.RegisterCalendarServiceHandler(s.ctx, gateway, conn); err != nil {
 		return err
 	}
 
-	<-ctx.Done()
+	mux := http.NewServeMux()
+	mux.Handle(docsPath, docsHandler())
+	mux.Handle("/events", conditionalMiddleware(gateway))
+	mux.Handle("/", gateway)
+
+	s.server.Handler = loggingMiddleware(
+		securityHeadersMiddleware(corsMiddleware(bodyLimitMiddleware(mux, s.opts.MaxBodySize), s.opts.CORS)),
+		s.logger,
+	)
+
+	ready()
+
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
 
-	return s.Stop(ctx)
+	return nil
 }
 
+// Stop waits for in-flight requests to finish, the connections left when ctx is done are closed.
 func (s *Server) Stop(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	defer s.cancelFn()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+
+		return err
+	}
+
+	return nil
+}
+
+// headerMatcher forwards the user ID header and list validators along with the headers
+// grpc-gateway passes by default.
+func headerMatcher(key string) (string, bool) {
+	for _, mdKey := range []string{
+		internalgrpc.UserIDMetadataKey,
+		internalgrpc.IfNoneMatchMetadataKey,
+		internalgrpc.IfModifiedSinceMetadataKey,
+	} {
+		if strings.EqualFold(key, mdKey) {
+			return mdKey, true
+		}
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher sends list validators as the HTTP headers they are, the not-modified mark
+// turns into the status instead, other metadata is prefixed as grpc-gateway does by default.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	switch key {
+	case internalgrpc.ETagMetadataKey:
+		return "ETag", true
+	case internalgrpc.LastModifiedMetadataKey:
+		return "Last-Modified", true
+	case internalgrpc.NotModifiedMetadataKey:
+		return "", false
+	default:
+		return runtime.MetadataHeaderPrefix + key, true
+	}
+}
+
+// forwardNotModified replies 304 Not Modified when the service found the client has the response already.
+func forwardNotModified(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	md, ok := runtime.ServerMetadataFromContext(ctx)
+
+	if ok && len(md.HeaderMD.Get(internalgrpc.NotModifiedMetadataKey)) > 0 {
+		w.WriteHeader(http.StatusNotModified)
+	}
+
+	return nil
 }
@@ -0,0 +1,26 @@
+package storage
+
+type AttendeeRole string
+
+const (
+	RoleRequired AttendeeRole = "required"
+	RoleOptional AttendeeRole = "optional"
+)
+
+// AttendeeStatus is the attendee's response to the invitation.
+type AttendeeStatus string
+
+const (
+	StatusNeedsAction AttendeeStatus = "needs_action"
+	StatusAccepted    AttendeeStatus = "accepted"
+	StatusDeclined    AttendeeStatus = "declined"
+	StatusTentative   AttendeeStatus = "tentative"
+)
+
+type Attendee struct {
+	EventID string         `db:"event_id"`
+	UserID  string         `db:"user_id"`
+	Email   string         `db:"email"`
+	Role    AttendeeRole   `db:"role"`
+	Status  AttendeeStatus `db:"status"`
+}
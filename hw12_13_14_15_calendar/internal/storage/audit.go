@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditOperation is the kind of change made to an event.
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+)
+
+// AuditRecord is an entry of the append-only history of event changes.
+type AuditRecord struct {
+	ID      string `db:"id"`
+	EventID string `db:"event_id"`
+	// ActorID is empty for anonymous changes.
+	ActorID   string         `db:"actor_id"`
+	Operation AuditOperation `db:"operation"`
+	CreatedAt time.Time      `db:"created_at"`
+	Changes   Changes        `db:"changes"`
+}
+
+// ChangeLog is written in the same transaction as the change of events it tells about,
+// so there is no change without its history and notices about it.
+type ChangeLog struct {
+	Audit []AuditRecord
+	// Notifications tell attendees about the change, the outbox messages publish them.
+	Notifications []Notification
+	Outbox        []OutboxMessage
+}
+
+// LogTrashed returns the log of the event moved to the trash along with its calendar.
+type LogTrashed func(event Event, attendees []Attendee) (ChangeLog, error)
+
+// Change is a field of the event before and after the operation formatted as text,
+// the value is empty while there is no event.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Changes are kept as a JSON array by SQL storage.
+type Changes []Change
+
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		c = Changes{}
+	}
+
+	b, err := json.Marshal(c)
+
+	return string(b), err
+}
+
+func (c *Changes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	default:
+		return fmt.Errorf("unsupported changes type %T", src)
+	}
+}
+
+// DiffEvents returns the fields which differ between two versions of an event,
+// the zero event stands for the one which is not there yet or anymore.
+func DiffEvents(before, after Event) Changes {
+	var changes Changes
+
+	for _, f := range []struct {
+		name          string
+		before, after string
+	}{
+		{"title", before.Title, after.Title},
+		{"starts_at", formatTime(before.StartsAt), formatTime(after.StartsAt)},
+		{"duration", formatDuration(before), formatDuration(after)},
+		{"description", before.Description, after.Description},
+		{"owner_id", before.OwnerID, after.OwnerID},
+		{"calendar_id", before.CalendarID, after.CalendarID},
+		{"tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", ")},
+		{"color", before.Color, after.Color},
+		{"all_day", formatAllDay(before), formatAllDay(after)},
+	} {
+		if f.before != f.after {
+			changes = append(changes, Change{Field: f.name, Before: f.before, After: f.after})
+		}
+	}
+
+	return changes
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatAllDay is empty for timed events, so the field is only there once events become all-day or stop being so.
+func formatAllDay(event Event) string {
+	if !event.AllDay {
+		return ""
+	}
+
+	return "true"
+}
+
+// formatDuration keeps zero durations of existing events apart from missing events.
+func formatDuration(event Event) string {
+	if event.ID == "" {
+		return ""
+	}
+
+	return event.Duration.String()
+}
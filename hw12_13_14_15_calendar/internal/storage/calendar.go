@@ -0,0 +1,26 @@
+package storage
+
+type Calendar struct {
+	ID       string `db:"id"`
+	OwnerID  string `db:"owner_id"`
+	Name     string `db:"name"`
+	Color    string `db:"color"`
+	TimeZone string `db:"time_zone"`
+}
+
+// AccessLevel is what a calendar is shared for, every level includes the previous ones.
+type AccessLevel string
+
+const (
+	AccessFreeBusy AccessLevel = "free_busy"
+	AccessRead     AccessLevel = "read"
+	AccessWrite    AccessLevel = "write"
+	// AccessOwner is what the calendar owner has, it is never shared.
+	AccessOwner AccessLevel = "owner"
+)
+
+type CalendarShare struct {
+	CalendarID string      `db:"calendar_id"`
+	UserID     string      `db:"user_id"`
+	Access     AccessLevel `db:"access"`
+}
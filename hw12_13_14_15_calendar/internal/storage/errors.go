@@ -0,0 +1,17 @@
+package storage
+
+import "errors"
+
+var (
+	ErrEventAlreadyExists    = errors.New("event already exists")
+	ErrEventNotFound         = errors.New("event not found")
+	ErrUserSettingsNotFound  = errors.New("user settings not found")
+	ErrAttendeeNotFound      = errors.New("attendee not found")
+	ErrCalendarNotFound      = errors.New("calendar not found")
+	ErrShareNotFound         = errors.New("calendar share not found")
+	ErrReminderNotFound      = errors.New("reminder not found")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	ErrNotificationNotFound  = errors.New("notification not found")
+)
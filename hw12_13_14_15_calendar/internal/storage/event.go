@@ -3,11 +3,74 @@ package storage
 import "time"
 
 type Event struct {
-	ID           string        `db:"id"`
-	Title        string        `db:"title"`
-	StartsAt     time.Time     `db:"starts_at"`
-	Duration     time.Duration `db:"duration"`
-	Description  string        `db:"description"`
-	OwnerID      string        `db:"owner_id"`
-	NotifyBefore time.Duration `db:"notify_before"`
+	ID          string        `db:"id"`
+	Title       string        `db:"title"`
+	StartsAt    time.Time     `db:"starts_at"`
+	Duration    time.Duration `db:"duration"`
+	Description string        `db:"description"`
+	OwnerID     string        `db:"owner_id"`
+	// CalendarID is empty for events created before calendars or outside of them.
+	CalendarID string `db:"calendar_id"`
+	// Tags are lowercase and sorted, they're kept apart from the event row in SQL.
+	Tags []string `db:"-"`
+	// Color is a hex RGB color like #1e90ff, empty for the color of the calendar.
+	Color string `db:"color"`
+	// AllDay events start at UTC midnight of their first date and last whole days,
+	// so they cover the same dates whatever the time zone they are listed in.
+	AllDay bool `db:"all_day"`
+	// DeletedAt is set while the event is in the trash.
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// EndDate returns the last date of an all-day event in the location of its start.
+func (e Event) EndDate() time.Time {
+	return e.StartsAt.Add(e.Duration).AddDate(0, 0, -1)
+}
+
+// EventFilter narrows listed events down.
+type EventFilter struct {
+	// UserID keeps events the user owns, is invited to or can read in calendars,
+	// all events are listed when it's empty.
+	UserID string
+	// CalendarID keeps events of the calendar.
+	CalendarID string
+	// Tags keeps events tagged with any or, depending on TagsMatch, all of the tags,
+	// they're expected to be unique and lowercase like the event ones.
+	Tags      []string
+	TagsMatch TagsMatch
+}
+
+// TagsMatch tells how many of the filter tags events need.
+type TagsMatch string
+
+const (
+	// TagsAny keeps events having at least one of the tags, it's the default.
+	TagsAny TagsMatch = "any"
+	// TagsAll keeps events having every one of the tags.
+	TagsAll TagsMatch = "all"
+)
+
+// HasTags reports whether the event tags satisfy the filter ones.
+func (f EventFilter) HasTags(tags []string) bool {
+	if len(f.Tags) == 0 {
+		return true
+	}
+
+	matched := 0
+
+	for _, tag := range f.Tags {
+		for _, t := range tags {
+			if t == tag {
+				matched++
+
+				break
+			}
+		}
+	}
+
+	if f.TagsMatch == TagsAll {
+		return matched == len(f.Tags)
+	}
+
+	return matched > 0
 }
@@ -0,0 +1,18 @@
+package storage
+
+import "time"
+
+// Interval is the time range [Start, End).
+type Interval struct {
+	Start time.Time `db:"starts_at"`
+	End   time.Time `db:"ends_at"`
+}
+
+// BusyInterval is the time the user is taken by an event they own or attend.
+type BusyInterval struct {
+	UserID string `db:"user_id"`
+	Interval
+	// OwnerID owns the event, dates of all-day ones are the days of their time zone.
+	OwnerID string `db:"owner_id"`
+	AllDay  bool   `db:"all_day"`
+}
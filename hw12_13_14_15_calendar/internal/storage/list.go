@@ -0,0 +1,53 @@
+package storage
+
+// Lists of events are marked changed along with every change which may add, remove or alter an event
+// listed there, so clients can tell whether the list they have is up to date without listing events again.
+// A scope names lists marked together: lists of every event, of events a user sees and of a calendar.
+const allEventsScope = "all"
+
+// UserScope covers events the user owns, attends or sees in own and shared calendars
+// along with settings of the user and access they are given.
+func UserScope(userID string) string {
+	return "user:" + userID
+}
+
+// CalendarScope covers events of the calendar along with the calendar itself.
+func CalendarScope(calendarID string) string {
+	return "calendar:" + calendarID
+}
+
+// ListScopes returns scopes of the list of the user, the calendar or both,
+// lists of neither are lists of every event.
+func ListScopes(userID, calendarID string) []string {
+	var scopes []string
+
+	if userID != "" {
+		scopes = append(scopes, UserScope(userID))
+	}
+
+	if calendarID != "" {
+		scopes = append(scopes, CalendarScope(calendarID))
+	}
+
+	if len(scopes) == 0 {
+		scopes = append(scopes, allEventsScope)
+	}
+
+	return scopes
+}
+
+// EventScopes returns scopes of lists the event may appear in, userIDs are the other users who see it:
+// attendees, the owner of its calendar and users the calendar is shared with.
+func EventScopes(event Event, userIDs ...string) []string {
+	scopes := []string{allEventsScope, UserScope(event.OwnerID)}
+
+	if event.CalendarID != "" {
+		scopes = append(scopes, CalendarScope(event.CalendarID))
+	}
+
+	for _, userID := range userIDs {
+		scopes = append(scopes, UserScope(userID))
+	}
+
+	return scopes
+}
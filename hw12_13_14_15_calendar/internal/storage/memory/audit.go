@@ -0,0 +1,95 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// auditCapacity is how many latest audit records are kept.
+const auditCapacity = 10000
+
+// auditLog is a ring buffer of audit records, once it's full the oldest records are overwritten.
+// Records are written to the WAL along with changes they tell about and snapshotted.
+type auditLog struct {
+	records []storage.AuditRecord
+	// next is where the next record goes, the oldest one is there as well once the buffer is full.
+	next int
+}
+
+func newAuditLog(capacity int) *auditLog {
+	return &auditLog{records: make([]storage.AuditRecord, 0, capacity)}
+}
+
+func (l *auditLog) append(record storage.AuditRecord) {
+	if len(l.records) < cap(l.records) {
+		l.records = append(l.records, record)
+
+		return
+	}
+
+	l.records[l.next] = record
+	l.next = (l.next + 1) % len(l.records)
+}
+
+// all returns every record in the order they were appended.
+func (l *auditLog) all() []storage.AuditRecord {
+	records := make([]storage.AuditRecord, 0, len(l.records))
+
+	for i := range l.records {
+		records = append(records, l.records[(l.next+i)%len(l.records)])
+	}
+
+	return records
+}
+
+// list returns records of the event in the order they were appended.
+func (l *auditLog) list(eventID string) []storage.AuditRecord {
+	records := []storage.AuditRecord{}
+
+	for _, r := range l.all() {
+		if r.EventID == eventID {
+			records = append(records, r)
+		}
+	}
+
+	return records
+}
+
+func (s *Storage) AppendAuditRecord(ctx context.Context, r storage.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(record{Op: opAppendAudit, Audit: auditRecords(r)})
+}
+
+// auditRecords returns copies of the records with times in UTC.
+func auditRecords(records ...storage.AuditRecord) []storage.AuditRecord {
+	res := make([]storage.AuditRecord, 0, len(records))
+
+	for _, r := range records {
+		r.CreatedAt = r.CreatedAt.UTC()
+		res = append(res, r)
+	}
+
+	return res
+}
+
+// ListAuditRecords returns the history of the event, the oldest record first.
+func (s *Storage) ListAuditRecords(ctx context.Context, eventID string) ([]storage.AuditRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := s.audit.list(eventID)
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+
+		return records[i].ID < records[j].ID
+	})
+
+	return records, nil
+}
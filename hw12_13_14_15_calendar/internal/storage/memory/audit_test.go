@@ -0,0 +1,28 @@
+package memorystorage
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogOverwritesOldest(t *testing.T) {
+	l := newAuditLog(3)
+
+	for i := 0; i < 5; i++ {
+		l.append(storage.AuditRecord{ID: strconv.Itoa(i), EventID: "event"})
+	}
+
+	l.append(storage.AuditRecord{ID: "other", EventID: "other"})
+
+	ids := []string{}
+
+	for _, r := range l.list("event") {
+		ids = append(ids, r.ID)
+	}
+
+	require.Equal(t, []string{"3", "4"}, ids)
+	require.Len(t, l.list("other"), 1)
+}
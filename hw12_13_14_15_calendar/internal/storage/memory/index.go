@@ -0,0 +1,209 @@
+package memorystorage
+
+import "time"
+
+// intervalIndex is an AVL tree of event intervals ordered by start time,
+// every node also tracks the latest end within its subtree to prune overlap lookups.
+type intervalIndex struct {
+	root *intervalNode
+	size int
+}
+
+type intervalNode struct {
+	id          string
+	start, end  time.Time
+	maxEnd      time.Time
+	height      int
+	left, right *intervalNode
+}
+
+func (idx *intervalIndex) insert(id string, start, end time.Time) {
+	if end.Before(start) {
+		end = start
+	}
+
+	idx.root = insertNode(idx.root, &intervalNode{id: id, start: start, end: end, maxEnd: end, height: 1})
+	idx.size++
+}
+
+func (idx *intervalIndex) remove(id string, start time.Time) {
+	var removed bool
+
+	idx.root, removed = removeNode(idx.root, id, start)
+
+	if removed {
+		idx.size--
+	}
+}
+
+// startingBetween calls fn in start order for every interval starting within [from, to).
+func (idx *intervalIndex) startingBetween(from, to time.Time, fn func(id string)) {
+	walkStarting(idx.root, from, to, fn)
+}
+
+// overlapping calls fn in start order for every interval sharing some time with [from, to),
+// zero-length intervals count when they fall within the range.
+func (idx *intervalIndex) overlapping(from, to time.Time, fn func(id string)) {
+	walkOverlapping(idx.root, from, to, fn)
+}
+
+func walkStarting(n *intervalNode, from, to time.Time, fn func(id string)) {
+	if n == nil {
+		return
+	}
+
+	if n.start.After(from) || n.start.Equal(from) {
+		walkStarting(n.left, from, to, fn)
+
+		if n.start.Before(to) {
+			fn(n.id)
+		}
+	}
+
+	if n.start.Before(to) {
+		walkStarting(n.right, from, to, fn)
+	}
+}
+
+func walkOverlapping(n *intervalNode, from, to time.Time, fn func(id string)) {
+	if n == nil || n.maxEnd.Before(from) {
+		return
+	}
+
+	walkOverlapping(n.left, from, to, fn)
+
+	if !n.start.Before(to) {
+		return
+	}
+
+	if n.end.After(from) || !n.start.Before(from) {
+		fn(n.id)
+	}
+
+	walkOverlapping(n.right, from, to, fn)
+}
+
+func nodeLess(id string, start time.Time, n *intervalNode) bool {
+	return start.Before(n.start) || (start.Equal(n.start) && id < n.id)
+}
+
+func insertNode(n, node *intervalNode) *intervalNode {
+	if n == nil {
+		return node
+	}
+
+	if nodeLess(node.id, node.start, n) {
+		n.left = insertNode(n.left, node)
+	} else {
+		n.right = insertNode(n.right, node)
+	}
+
+	return rebalance(n)
+}
+
+func removeNode(n *intervalNode, id string, start time.Time) (*intervalNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	var removed bool
+
+	switch {
+	case n.id == id && n.start.Equal(start):
+		if n.left == nil {
+			return n.right, true
+		}
+
+		if n.right == nil {
+			return n.left, true
+		}
+
+		successor := n.right
+
+		for successor.left != nil {
+			successor = successor.left
+		}
+
+		n.right, _ = removeNode(n.right, successor.id, successor.start)
+		n.id, n.start, n.end = successor.id, successor.start, successor.end
+		removed = true
+	case nodeLess(id, start, n):
+		n.left, removed = removeNode(n.left, id, start)
+	default:
+		n.right, removed = removeNode(n.right, id, start)
+	}
+
+	return rebalance(n), removed
+}
+
+func height(n *intervalNode) int {
+	if n == nil {
+		return 0
+	}
+
+	return n.height
+}
+
+func update(n *intervalNode) {
+	n.height = 1 + maxInt(height(n.left), height(n.right))
+	n.maxEnd = n.end
+
+	if n.left != nil && n.left.maxEnd.After(n.maxEnd) {
+		n.maxEnd = n.left.maxEnd
+	}
+
+	if n.right != nil && n.right.maxEnd.After(n.maxEnd) {
+		n.maxEnd = n.right.maxEnd
+	}
+}
+
+func rotateLeft(n *intervalNode) *intervalNode {
+	r := n.right
+	n.right = r.left
+	r.left = n
+
+	update(n)
+	update(r)
+
+	return r
+}
+
+func rotateRight(n *intervalNode) *intervalNode {
+	l := n.left
+	n.left = l.right
+	l.right = n
+
+	update(n)
+	update(l)
+
+	return l
+}
+
+func rebalance(n *intervalNode) *intervalNode {
+	update(n)
+
+	switch balance := height(n.left) - height(n.right); {
+	case balance > 1:
+		if height(n.left.left) < height(n.left.right) {
+			n.left = rotateLeft(n.left)
+		}
+
+		return rotateRight(n)
+	case balance < -1:
+		if height(n.right.right) < height(n.right.left) {
+			n.right = rotateRight(n.right)
+		}
+
+		return rotateLeft(n)
+	default:
+		return n
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,132 @@
+package memorystorage
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pioz/faker"
+	"github.com/stretchr/testify/require"
+)
+
+type testInterval struct {
+	id         string
+	start, end time.Time
+}
+
+func collect(walk func(from, to time.Time, fn func(id string)), from, to time.Time) []string {
+	ids := []string{}
+
+	walk(from, to, func(id string) {
+		ids = append(ids, id)
+	})
+
+	return ids
+}
+
+func expected(intervals map[string]testInterval, match func(i testInterval) bool) []string {
+	matched := []testInterval{}
+
+	for _, i := range intervals {
+		if match(i) {
+			matched = append(matched, i)
+		}
+	}
+
+	sort.Slice(matched, func(a, b int) bool {
+		return matched[a].start.Before(matched[b].start) ||
+			(matched[a].start.Equal(matched[b].start) && matched[a].id < matched[b].id)
+	})
+
+	ids := make([]string, 0, len(matched))
+
+	for _, i := range matched {
+		ids = append(ids, i.id)
+	}
+
+	return ids
+}
+
+func requireBalanced(t *testing.T, n *intervalNode) int {
+	t.Helper()
+
+	if n == nil {
+		return 0
+	}
+
+	left, right := requireBalanced(t, n.left), requireBalanced(t, n.right)
+
+	require.LessOrEqual(t, left-right, 1)
+	require.LessOrEqual(t, right-left, 1)
+	require.Equal(t, 1+maxInt(left, right), n.height)
+
+	return n.height
+}
+
+func TestIntervalIndex(t *testing.T) {
+	base := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	intervals := make(map[string]testInterval)
+	idx := &intervalIndex{}
+
+	for i := 0; i < 2000; i++ {
+		start := base.Add(time.Duration(rand.Intn(30*24)) * time.Hour)
+		end := start.Add(time.Duration(rand.Intn(72)) * time.Hour)
+
+		if i%10 == 0 {
+			end = start
+		}
+
+		interval := testInterval{faker.UUID(), start, end}
+		intervals[interval.id] = interval
+		idx.insert(interval.id, interval.start, interval.end)
+	}
+
+	removed := 0
+
+	for id, interval := range intervals {
+		if removed == 500 {
+			break
+		}
+
+		idx.remove(id, interval.start)
+		delete(intervals, id)
+		removed++
+	}
+
+	require.Equal(t, len(intervals), idx.size)
+	requireBalanced(t, idx.root)
+
+	for i := 0; i < 200; i++ {
+		from := base.Add(time.Duration(rand.Intn(32*24)-24) * time.Hour)
+		to := from.Add(time.Duration(rand.Intn(7*24)) * time.Hour)
+
+		require.Equal(t, expected(intervals, func(i testInterval) bool {
+			return !i.start.Before(from) && i.start.Before(to)
+		}), collect(idx.startingBetween, from, to))
+
+		require.Equal(t, expected(intervals, func(i testInterval) bool {
+			return i.start.Before(to) && (i.end.After(from) || !i.start.Before(from))
+		}), collect(idx.overlapping, from, to))
+	}
+}
+
+func TestIntervalIndexBoundaries(t *testing.T) {
+	day := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	nextDay := day.AddDate(0, 0, 1)
+	idx := &intervalIndex{}
+
+	idx.insert("before", day.Add(-time.Hour), day)
+	idx.insert("spanning", day.Add(-time.Hour), nextDay.Add(time.Hour))
+	idx.insert("at-start", day, day)
+	idx.insert("at-end", nextDay, nextDay.Add(time.Hour))
+
+	require.Equal(t, []string{"at-start"}, collect(idx.startingBetween, day, nextDay))
+	require.Equal(t, []string{"spanning", "at-start"}, collect(idx.overlapping, day, nextDay))
+
+	idx.remove("spanning", day.Add(-time.Hour))
+	idx.remove("unknown", day)
+
+	require.Equal(t, 3, idx.size)
+	require.Equal(t, []string{"at-start"}, collect(idx.overlapping, day, nextDay))
+}
@@ -0,0 +1,113 @@
+package memorystorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// LastListChange returns when lists of the scopes were marked changed last, the zero time if never.
+func (s *Storage) LastListChange(ctx context.Context, scopes []string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var changedAt time.Time
+
+	for _, scope := range scopes {
+		if t := s.listChanges[scope]; t.After(changedAt) {
+			changedAt = t
+		}
+	}
+
+	return changedAt, nil
+}
+
+// markLists marks lists of the scopes changed at the time, marks never move back.
+// Records logged before lists were marked carry no time and mark nothing.
+func (s *Storage) markLists(scopes []string, changedAt time.Time) {
+	for _, scope := range scopes {
+		if changedAt.After(s.listChanges[scope]) {
+			s.listChanges[scope] = changedAt
+		}
+	}
+}
+
+// changedScopes returns scopes of lists the record changes, it's called before the record is applied,
+// so removed attendees and shares are still there to tell whose lists lose events.
+// Purged events are out of every list since they were moved to the trash.
+func (s *Storage) changedScopes(rec record) []string {
+	switch rec.Op {
+	case opCreateEvent:
+		return s.eventScopes(*rec.Event)
+	case opUpdateEvent:
+		// the event may move to another calendar.
+		return append(s.eventScopes(s.events[rec.ID]), s.eventScopes(*rec.Event)...)
+	case opTrashEvent:
+		return s.eventScopes(s.events[rec.ID])
+	case opRestoreEvent:
+		return s.eventScopes(s.trash[rec.ID])
+	case opSaveAttendee:
+		return append(s.attendeeEventScopes(rec.ID), storage.UserScope(rec.Attendee.UserID))
+	case opRemoveAttendee:
+		return s.attendeeEventScopes(rec.ID)
+	case opSaveUserSettings:
+		return []string{storage.UserScope(rec.ID)}
+	case opSaveCalendar:
+		return []string{storage.CalendarScope(rec.ID)}
+	case opDeleteCalendar:
+		scopes := s.calendarScopes(rec.ID)
+
+		for _, event := range rec.Events {
+			scopes = append(scopes, s.eventScopes(event)...)
+		}
+
+		return scopes
+	case opSaveShare:
+		return []string{storage.UserScope(rec.Share.UserID)}
+	case opRemoveShare:
+		return []string{storage.UserScope(rec.UserID)}
+	default:
+		return nil
+	}
+}
+
+// eventScopes returns scopes of lists the event may appear in.
+func (s *Storage) eventScopes(event storage.Event) []string {
+	var userIDs []string
+
+	for userID := range s.attendees[event.ID] {
+		userIDs = append(userIDs, userID)
+	}
+
+	if calendar, ok := s.calendars[event.CalendarID]; ok {
+		userIDs = append(userIDs, calendar.OwnerID)
+
+		for userID := range s.shares[calendar.ID] {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	return storage.EventScopes(event, userIDs...)
+}
+
+// attendeeEventScopes returns scopes of lists the event attendees are changed of may appear in, trashed or not.
+func (s *Storage) attendeeEventScopes(eventID string) []string {
+	event, ok := s.events[eventID]
+	if !ok {
+		event = s.trash[eventID]
+	}
+
+	return s.eventScopes(event)
+}
+
+// calendarScopes returns scopes of lists of the calendar, its owner and users it's shared with.
+func (s *Storage) calendarScopes(id string) []string {
+	scopes := []string{storage.CalendarScope(id), storage.UserScope(s.calendars[id].OwnerID)}
+
+	for userID := range s.shares[id] {
+		scopes = append(scopes, storage.UserScope(userID))
+	}
+
+	return scopes
+}
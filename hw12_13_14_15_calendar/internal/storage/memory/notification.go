@@ -0,0 +1,126 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (s *Storage) GetNotification(ctx context.Context, id string) (storage.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notification, ok := s.notifications[id]
+	if !ok {
+		return notification, storage.ErrNotificationNotFound
+	}
+
+	return notification, nil
+}
+
+// UpdateNotification saves the outcome of an attempt, the payload is never changed.
+func (s *Storage) UpdateNotification(ctx context.Context, notification storage.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, ok := s.notifications[notification.ID]
+	if !ok {
+		return storage.ErrNotificationNotFound
+	}
+
+	prev.Status = notification.Status
+	prev.Attempts = notification.Attempts
+	prev.LastError = notification.LastError
+	prev.SentAt = notification.SentAt
+	normalizeNotification(&prev)
+
+	return s.commit(record{Op: opSaveNotification, ID: prev.ID, Notifications: []storage.Notification{prev}})
+}
+
+// ResendNotification makes the notification pending again and writes the message publishing it to the outbox
+// with a single record.
+func (s *Storage) ResendNotification(ctx context.Context, id string, message storage.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[id]
+	if !ok {
+		return storage.ErrNotificationNotFound
+	}
+
+	notification.Status = storage.NotificationPending
+
+	return s.commit(record{
+		Op:            opSaveNotification,
+		ID:            id,
+		Notifications: []storage.Notification{notification},
+		Outbox:        outboxMessages([]storage.OutboxMessage{message}),
+	})
+}
+
+// ListNotifications returns notifications passing the filter, the latest scheduled first.
+func (s *Storage) ListNotifications(
+	ctx context.Context, filter storage.NotificationFilter,
+) ([]storage.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notifications := []storage.Notification{}
+
+	for _, n := range s.notifications {
+		if filter.Matches(n) {
+			notifications = append(notifications, n)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].ScheduledAt.Equal(notifications[j].ScheduledAt) {
+			return notifications[i].ScheduledAt.After(notifications[j].ScheduledAt)
+		}
+
+		return notifications[i].ID < notifications[j].ID
+	})
+
+	return notifications, nil
+}
+
+// notificationRecords returns copies of the notifications with times in UTC.
+func notificationRecords(notifications []storage.Notification) []storage.Notification {
+	records := make([]storage.Notification, 0, len(notifications))
+
+	for _, n := range notifications {
+		normalizeNotification(&n)
+		records = append(records, n)
+	}
+
+	return records
+}
+
+func (s *Storage) putNotifications(notifications []storage.Notification, messages []storage.OutboxMessage) {
+	for _, n := range notifications {
+		s.notifications[n.ID] = n
+	}
+
+	for _, m := range messages {
+		s.outbox[m.ID] = m
+	}
+}
+
+func (s *Storage) removeNotifications(eventID string) {
+	for id, n := range s.notifications {
+		if n.EventID == eventID {
+			delete(s.notifications, id)
+		}
+	}
+}
+
+func normalizeNotification(notification *storage.Notification) {
+	notification.ScheduledAt = notification.ScheduledAt.UTC()
+	notification.CreatedAt = notification.CreatedAt.UTC()
+
+	if notification.SentAt != nil {
+		sentAt := notification.SentAt.UTC()
+		notification.SentAt = &sentAt
+	}
+}
@@ -0,0 +1,112 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// FireReminder marks the reminder fired and writes its notifications along with outbox messages publishing them
+// with a single record, so they are published once the reminder is not due anymore and never otherwise.
+func (s *Storage) FireReminder(
+	ctx context.Context, id string, firedAt time.Time,
+	notifications []storage.Notification, messages []storage.OutboxMessage,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reminder, ok := s.reminders[id]
+	if !ok {
+		return storage.ErrReminderNotFound
+	}
+
+	firedAt = firedAt.UTC()
+	reminder.FiredAt = &firedAt
+
+	return s.commit(record{
+		Op:            opFireReminder,
+		ID:            id,
+		Reminder:      &reminder,
+		Notifications: notificationRecords(notifications),
+		Outbox:        outboxMessages(messages),
+	})
+}
+
+// ListPendingOutbox returns up to limit messages which haven't been sent yet in the order they were written.
+func (s *Storage) ListPendingOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	messages := []storage.OutboxMessage{}
+
+	for _, m := range s.outbox {
+		if m.SentAt == nil {
+			messages = append(messages, m)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+
+		return messages[i].ID < messages[j].ID
+	})
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) MarkOutboxSent(ctx context.Context, id string, sentAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, ok := s.outbox[id]
+	if !ok {
+		return storage.ErrOutboxMessageNotFound
+	}
+
+	sentAt = sentAt.UTC()
+	message.SentAt = &sentAt
+
+	return s.commit(record{Op: opSaveOutbox, ID: id, Outbox: []storage.OutboxMessage{message}})
+}
+
+// PurgeSentOutbox deletes messages sent before the time and returns how many there were.
+func (s *Storage) PurgeSentOutbox(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+
+	for id, m := range s.outbox {
+		if m.SentAt == nil || !m.SentAt.Before(before) {
+			continue
+		}
+
+		if err := s.commit(record{Op: opDeleteOutbox, ID: id}); err != nil {
+			return purged, err
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// outboxMessages copies the messages with times in UTC.
+func outboxMessages(messages []storage.OutboxMessage) []storage.OutboxMessage {
+	outbox := make([]storage.OutboxMessage, 0, len(messages))
+
+	for _, m := range messages {
+		m.CreatedAt = m.CreatedAt.UTC()
+		outbox = append(outbox, m)
+	}
+
+	return outbox
+}
@@ -0,0 +1,233 @@
+package memorystorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot"
+)
+
+// NewPersistent restores the storage from the snapshot and write-ahead log kept in dir
+// and logs every following change there. A positive snapshotInterval compacts the log periodically.
+func NewPersistent(dir string, snapshotInterval time.Duration) (*Storage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	s := New()
+	s.dir = dir
+	s.seq = snap.Seq
+
+	for _, e := range snap.Events {
+		if e.DeletedAt != nil {
+			s.trash[e.ID] = e
+
+			continue
+		}
+
+		s.putEvent(e.ID, e)
+	}
+
+	for _, settings := range snap.Settings {
+		s.settings[settings.UserID] = settings
+	}
+
+	for _, attendee := range snap.Attendees {
+		s.putAttendee(attendee)
+	}
+
+	for _, calendar := range snap.Calendars {
+		s.calendars[calendar.ID] = calendar
+	}
+
+	for _, share := range snap.Shares {
+		s.putShare(share)
+	}
+
+	for _, reminder := range snap.Reminders {
+		s.putReminder(reminder)
+	}
+
+	for _, webhook := range snap.Webhooks {
+		s.webhooks[webhook.ID] = webhook
+	}
+
+	for _, delivery := range snap.Deliveries {
+		s.deliveries[delivery.ID] = delivery
+	}
+
+	for _, message := range snap.Outbox {
+		s.outbox[message.ID] = message
+	}
+
+	for _, notification := range snap.Notifications {
+		s.notifications[notification.ID] = notification
+	}
+
+	for _, r := range snap.Audit {
+		s.audit.append(r)
+	}
+
+	for scope, changedAt := range snap.ListChanges {
+		s.listChanges[scope] = changedAt
+	}
+
+	w, records, err := openWAL(filepath.Join(dir, walFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range records {
+		// the log may still hold records the snapshot already covers if we crashed before it was reset.
+		if rec.Seq <= s.seq {
+			continue
+		}
+
+		s.apply(rec)
+		s.seq = rec.Seq
+	}
+
+	s.wal = w
+
+	if snapshotInterval > 0 {
+		s.done = make(chan struct{})
+		s.wg.Add(1)
+
+		go s.runSnapshots(snapshotInterval)
+	}
+
+	return s, nil
+}
+
+func (s *Storage) runSnapshots(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			// a failed snapshot is retried on the next tick, the log still holds every change meanwhile.
+			_ = s.snapshot()
+		}
+	}
+}
+
+// snapshot dumps the whole state and resets the log they were collected from.
+func (s *Storage) snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := snapshot{
+		Seq:           s.seq,
+		Events:        make([]storage.Event, 0, len(s.events)+len(s.trash)),
+		Settings:      make([]storage.UserSettings, 0, len(s.settings)),
+		Attendees:     []storage.Attendee{},
+		Calendars:     make([]storage.Calendar, 0, len(s.calendars)),
+		Shares:        []storage.CalendarShare{},
+		Reminders:     make([]storage.Reminder, 0, len(s.reminders)),
+		Webhooks:      make([]storage.Webhook, 0, len(s.webhooks)),
+		Deliveries:    make([]storage.WebhookDelivery, 0, len(s.deliveries)),
+		Outbox:        make([]storage.OutboxMessage, 0, len(s.outbox)),
+		Notifications: make([]storage.Notification, 0, len(s.notifications)),
+		Audit:         s.audit.all(),
+		ListChanges:   s.listChanges,
+	}
+
+	// trashed events are told apart by the deletion time.
+	for _, events := range []map[string]storage.Event{s.events, s.trash} {
+		for _, e := range events {
+			snap.Events = append(snap.Events, e)
+		}
+	}
+
+	for _, settings := range s.settings {
+		snap.Settings = append(snap.Settings, settings)
+	}
+
+	for _, attendees := range s.attendees {
+		for _, attendee := range attendees {
+			snap.Attendees = append(snap.Attendees, attendee)
+		}
+	}
+
+	for _, calendar := range s.calendars {
+		snap.Calendars = append(snap.Calendars, calendar)
+	}
+
+	for _, shares := range s.shares {
+		for _, share := range shares {
+			snap.Shares = append(snap.Shares, share)
+		}
+	}
+
+	for _, reminder := range s.reminders {
+		snap.Reminders = append(snap.Reminders, reminder)
+	}
+
+	for _, webhook := range s.webhooks {
+		snap.Webhooks = append(snap.Webhooks, webhook)
+	}
+
+	for _, delivery := range s.deliveries {
+		snap.Deliveries = append(snap.Deliveries, delivery)
+	}
+
+	for _, message := range s.outbox {
+		snap.Outbox = append(snap.Outbox, message)
+	}
+
+	for _, notification := range s.notifications {
+		snap.Notifications = append(snap.Notifications, notification)
+	}
+
+	if err := writeSnapshot(filepath.Join(s.dir, snapshotFileName), snap); err != nil {
+		return err
+	}
+
+	return s.wal.truncate(0)
+}
+
+// Close stops periodic snapshots and compacts the log one last time.
+// Calls after the first one return what the first one did.
+func (s *Storage) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.close()
+	})
+
+	return s.closeErr
+}
+
+func (s *Storage) close() error {
+	if s.wal == nil {
+		return nil
+	}
+
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+	}
+
+	if err := s.snapshot(); err != nil {
+		s.wal.close()
+
+		return err
+	}
+
+	return s.wal.close()
+}
@@ -0,0 +1,399 @@
+package memorystorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pioz/faker"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type PersistentStorageTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *PersistentStorageTestSuite) BeforeTest(suiteName, testName string) {
+	s.dir = s.T().TempDir()
+}
+
+func (s *PersistentStorageTestSuite) open() *Storage {
+	storage, err := NewPersistent(s.dir, 0)
+	s.Require().NoError(err)
+
+	return storage
+}
+
+func (s *PersistentStorageTestSuite) fill(st *Storage) []storage.Event {
+	startsAt := time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC)
+	events := []storage.Event{
+		{ID: faker.UUID(), StartsAt: startsAt},
+		{ID: faker.UUID(), StartsAt: startsAt.Add(time.Hour)},
+		{ID: faker.UUID(), StartsAt: startsAt.Add(2 * time.Hour)},
+	}
+
+	for _, e := range events {
+		s.Require().NoError(st.CreateEvent(context.TODO(), e, storage.ChangeLog{}))
+	}
+
+	events[1].Description = faker.String()
+
+	s.Require().NoError(st.UpdateEvent(context.TODO(), events[1].ID, events[1], storage.ChangeLog{}))
+	s.Require().NoError(st.DeleteEvent(context.TODO(), events[2].ID, storage.ChangeLog{}))
+
+	return events[:2]
+}
+
+func (s *PersistentStorageTestSuite) TestReplayLog() {
+	st := s.open()
+	events := s.fill(st)
+
+	// simulate a crash: the log is left as is, without a final snapshot.
+	s.Require().NoError(st.wal.close())
+
+	restored := s.open()
+	defer restored.Close(context.TODO())
+
+	require.ElementsMatch(s.T(), events, mapValues(restored.events))
+	require.Equal(s.T(), uint64(5), restored.seq)
+}
+
+func (s *PersistentStorageTestSuite) TestRestoreSnapshot() {
+	st := s.open()
+	events := s.fill(st)
+
+	s.Require().NoError(st.Close(context.TODO()))
+	// the lifecycle manager and cleanups may both close the storage.
+	s.Require().NoError(st.Close(context.TODO()))
+
+	info, err := os.Stat(filepath.Join(s.dir, walFileName))
+	s.Require().NoError(err)
+	require.Zero(s.T(), info.Size())
+
+	restored := s.open()
+	defer restored.Close(context.TODO())
+
+	require.ElementsMatch(s.T(), events, mapValues(restored.events))
+
+	event := storage.Event{ID: faker.UUID()}
+
+	require.NoError(s.T(), restored.CreateEvent(context.TODO(), event, storage.ChangeLog{}))
+	require.Equal(s.T(), uint64(6), restored.seq)
+}
+
+func (s *PersistentStorageTestSuite) TestSkipSnapshottedRecords() {
+	st := s.open()
+	events := s.fill(st)
+
+	// simulate a crash between writing the snapshot and resetting the log.
+	s.Require().NoError(writeSnapshot(filepath.Join(s.dir, snapshotFileName), snapshot{Seq: st.seq, Events: events}))
+	s.Require().NoError(st.wal.close())
+
+	restored := s.open()
+	defer restored.Close(context.TODO())
+
+	require.ElementsMatch(s.T(), events, mapValues(restored.events))
+}
+
+func (s *PersistentStorageTestSuite) TestTruncatedTail() {
+	st := s.open()
+	events := s.fill(st)
+	size := st.wal.size
+
+	s.Require().NoError(st.CreateEvent(context.TODO(), storage.Event{ID: faker.UUID()}, storage.ChangeLog{}))
+	s.Require().NoError(st.wal.close())
+
+	path := filepath.Join(s.dir, walFileName)
+
+	// cut the last record in the middle, as an interrupted write would.
+	s.Require().NoError(os.Truncate(path, size+walHeaderSize+3))
+
+	restored := s.open()
+
+	require.ElementsMatch(s.T(), events, mapValues(restored.events))
+	require.Equal(s.T(), size, restored.wal.size)
+
+	event := storage.Event{ID: faker.UUID()}
+
+	s.Require().NoError(restored.CreateEvent(context.TODO(), event, storage.ChangeLog{}))
+	s.Require().NoError(restored.wal.close())
+
+	restored = s.open()
+	defer restored.Close(context.TODO())
+
+	require.ElementsMatch(s.T(), append(events, event), mapValues(restored.events))
+}
+
+func (s *PersistentStorageTestSuite) TestCorruptedRecord() {
+	st := s.open()
+	events := s.fill(st)
+	size := st.wal.size
+
+	s.Require().NoError(st.CreateEvent(context.TODO(), storage.Event{ID: faker.UUID()}, storage.ChangeLog{}))
+	s.Require().NoError(st.wal.close())
+
+	path := filepath.Join(s.dir, walFileName)
+	data, err := os.ReadFile(path)
+	s.Require().NoError(err)
+
+	data[len(data)-2] ^= 0xff
+
+	s.Require().NoError(os.WriteFile(path, data, 0o600))
+
+	restored := s.open()
+	defer restored.Close(context.TODO())
+
+	require.ElementsMatch(s.T(), events, mapValues(restored.events))
+	require.Equal(s.T(), size, restored.wal.size)
+}
+
+func (s *PersistentStorageTestSuite) TestCorruptedSnapshot() {
+	st := s.open()
+	s.fill(st)
+	s.Require().NoError(st.Close(context.TODO()))
+
+	path := filepath.Join(s.dir, snapshotFileName)
+	data, err := os.ReadFile(path)
+	s.Require().NoError(err)
+
+	data[len(data)-2] ^= 0xff
+
+	s.Require().NoError(os.WriteFile(path, data, 0o600))
+
+	_, err = NewPersistent(s.dir, 0)
+	require.ErrorIs(s.T(), err, errSnapshotCorrupted)
+}
+
+func (s *PersistentStorageTestSuite) TestPeriodicSnapshot() {
+	st, err := NewPersistent(s.dir, 10*time.Millisecond)
+	s.Require().NoError(err)
+
+	defer func() {
+		s.Require().NoError(st.Close(context.TODO()))
+		s.Require().NoError(st.Close(context.TODO()))
+	}()
+
+	events := s.fill(st)
+
+	require.Eventually(s.T(), func() bool {
+		snap, err := readSnapshot(filepath.Join(s.dir, snapshotFileName))
+
+		// the deleted event is snapshotted as well, it's in the trash.
+		return err == nil && snap.Seq == 5 && len(snap.Events) == len(events)+1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func (s *PersistentStorageTestSuite) TestRestoreAttendees() {
+	st := s.open()
+	events := s.fill(st)
+	attendees := []storage.Attendee{
+		{EventID: events[0].ID, UserID: faker.UUID(), Role: storage.RoleRequired, Status: storage.StatusNeedsAction},
+		{EventID: events[0].ID, UserID: faker.UUID(), Role: storage.RoleOptional, Status: storage.StatusNeedsAction},
+		{EventID: events[1].ID, UserID: faker.UUID(), Role: storage.RoleRequired, Status: storage.StatusNeedsAction},
+	}
+
+	for _, a := range attendees {
+		s.Require().NoError(st.SaveAttendee(context.TODO(), a))
+	}
+
+	s.Require().NoError(st.SetAttendeeStatus(context.TODO(), events[0].ID, attendees[0].UserID, storage.StatusAccepted))
+	s.Require().NoError(st.RemoveAttendee(context.TODO(), events[0].ID, attendees[1].UserID))
+
+	attendees[0].Status = storage.StatusAccepted
+	expected := map[string][]storage.Attendee{
+		events[0].ID: {attendees[0]},
+		events[1].ID: {attendees[2]},
+	}
+
+	requireAttendees := func(st *Storage) {
+		for eventID, eventAttendees := range expected {
+			actual, err := st.ListAttendees(context.TODO(), eventID)
+			s.Require().NoError(err)
+			require.Equal(s.T(), eventAttendees, actual)
+		}
+	}
+
+	// replayed from the log first, then restored from the snapshot.
+	s.Require().NoError(st.wal.close())
+
+	restored := s.open()
+	requireAttendees(restored)
+	s.Require().NoError(restored.Close(context.TODO()))
+
+	restored = s.open()
+	defer restored.Close(context.TODO())
+
+	requireAttendees(restored)
+}
+
+func (s *PersistentStorageTestSuite) TestRestoreCalendars() {
+	st := s.open()
+	events := s.fill(st)
+	ownerID, userID := faker.UUID(), faker.UUID()
+	work := storage.Calendar{ID: faker.UUID(), OwnerID: ownerID, Name: "Work", Color: "#ff0000"}
+	home := storage.Calendar{ID: faker.UUID(), OwnerID: ownerID, Name: "Home"}
+	share := storage.CalendarShare{CalendarID: work.ID, UserID: userID, Access: storage.AccessRead}
+
+	s.Require().NoError(st.CreateCalendar(context.TODO(), work))
+	s.Require().NoError(st.CreateCalendar(context.TODO(), home))
+	s.Require().NoError(st.SaveShare(context.TODO(), share))
+
+	events[0].CalendarID = home.ID
+	s.Require().NoError(st.UpdateEvent(context.TODO(), events[0].ID, events[0], storage.ChangeLog{}))
+
+	record := storage.AuditRecord{
+		ID:        faker.UUID(),
+		EventID:   events[0].ID,
+		ActorID:   ownerID,
+		Operation: storage.AuditDelete,
+		CreatedAt: time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC),
+		Changes:   storage.DiffEvents(events[0], storage.Event{}),
+	}
+
+	notice := storage.Notification{
+		ID:          faker.UUID(),
+		EventID:     events[0].ID,
+		UserID:      userID,
+		Channel:     storage.ChannelLog,
+		ScheduledAt: record.CreatedAt,
+		Status:      storage.NotificationPending,
+		Payload:     `{"message":"cancelled"}`,
+		CreatedAt:   record.CreatedAt,
+	}
+	message := storage.OutboxMessage{ID: faker.UUID(), Payload: notice.Payload, CreatedAt: notice.CreatedAt}
+
+	_, err := st.DeleteCalendar(context.TODO(), home.ID, func(storage.Event, []storage.Attendee) (
+		storage.ChangeLog, error,
+	) {
+		return storage.ChangeLog{
+			Audit:         []storage.AuditRecord{record},
+			Notifications: []storage.Notification{notice},
+			Outbox:        []storage.OutboxMessage{message},
+		}, nil
+	})
+	s.Require().NoError(err)
+
+	requireCalendars := func(st *Storage) {
+		calendars, err := st.ListCalendars(context.TODO(), ownerID)
+		s.Require().NoError(err)
+		require.Equal(s.T(), []storage.Calendar{work}, calendars)
+
+		shares, err := st.ListShares(context.TODO(), work.ID)
+		s.Require().NoError(err)
+		require.Equal(s.T(), []storage.CalendarShare{share}, shares)
+
+		_, err = st.GetEvent(context.TODO(), events[0].ID)
+		require.ErrorIs(s.T(), err, storage.ErrEventNotFound)
+
+		trashed, err := st.GetDeletedEvent(context.TODO(), events[0].ID)
+		s.Require().NoError(err)
+		require.Equal(s.T(), home.ID, trashed.CalendarID)
+		require.NotNil(s.T(), trashed.DeletedAt)
+
+		records, err := st.ListAuditRecords(context.TODO(), events[0].ID)
+		s.Require().NoError(err)
+		require.Equal(s.T(), []storage.AuditRecord{record}, records)
+
+		notification, err := st.GetNotification(context.TODO(), notice.ID)
+		s.Require().NoError(err)
+		require.Equal(s.T(), notice, notification)
+
+		pending, err := st.ListPendingOutbox(context.TODO(), 10)
+		s.Require().NoError(err)
+		require.Equal(s.T(), []storage.OutboxMessage{message}, pending)
+	}
+
+	// replayed from the log first, then restored from the snapshot.
+	s.Require().NoError(st.wal.close())
+
+	restored := s.open()
+	requireCalendars(restored)
+	s.Require().NoError(restored.Close(context.TODO()))
+
+	restored = s.open()
+	defer restored.Close(context.TODO())
+
+	requireCalendars(restored)
+}
+
+func (s *PersistentStorageTestSuite) TestRestoreListChanges() {
+	st := s.open()
+	events := s.fill(st)
+
+	s.Require().NoError(st.SaveAttendee(context.TODO(), storage.Attendee{EventID: events[0].ID, UserID: faker.UUID()}))
+
+	changes := make(map[string]time.Time, len(st.listChanges))
+
+	for scope, changedAt := range st.listChanges {
+		changes[scope] = changedAt
+	}
+
+	// simulate a crash: changes are replayed from the log along with the records marking them.
+	s.Require().NoError(st.wal.close())
+
+	replayed := s.open()
+	require.Equal(s.T(), changes, replayed.listChanges)
+	s.Require().NoError(replayed.Close(context.TODO()))
+
+	restored := s.open()
+	defer restored.Close(context.TODO())
+
+	require.Equal(s.T(), changes, restored.listChanges)
+}
+
+func (s *PersistentStorageTestSuite) TestRestoreTrash() {
+	st := s.open()
+	events := s.fill(st)
+
+	s.Require().NoError(st.DeleteEvent(context.TODO(), events[0].ID, storage.ChangeLog{}))
+	s.Require().NoError(st.RestoreEvent(context.TODO(), events[0].ID, storage.ChangeLog{}))
+	s.Require().NoError(st.DeleteEvent(context.TODO(), events[1].ID, storage.ChangeLog{}))
+
+	trashed, err := st.ListDeletedEvents(context.TODO(), storage.EventFilter{})
+	s.Require().NoError(err)
+	s.Require().Len(trashed, 2)
+
+	requireTrash := func(st *Storage) {
+		deleted, err := st.ListDeletedEvents(context.TODO(), storage.EventFilter{})
+		s.Require().NoError(err)
+		require.Equal(s.T(), trashed, deleted)
+
+		_, err = st.GetEvent(context.TODO(), events[0].ID)
+		s.Require().NoError(err)
+	}
+
+	// replayed from the log first, then restored from the snapshot.
+	s.Require().NoError(st.wal.close())
+
+	restored := s.open()
+	requireTrash(restored)
+	s.Require().NoError(restored.Close(context.TODO()))
+
+	restored = s.open()
+	defer restored.Close(context.TODO())
+
+	requireTrash(restored)
+
+	purged, err := restored.PurgeDeletedEvents(context.TODO(), time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+	require.Equal(s.T(), 2, purged)
+}
+
+func mapValues(events map[string]storage.Event) []storage.Event {
+	res := make([]storage.Event, 0, len(events))
+
+	for _, e := range events {
+		res = append(res, e)
+	}
+
+	return res
+}
+
+func TestPersistentStorage(t *testing.T) {
+	suite.Run(t, new(PersistentStorageTestSuite))
+}
@@ -0,0 +1,157 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// Words of titles weigh more in ranking than words of descriptions.
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// searchIndex is an inverted index of words of event titles and descriptions,
+// the words are also kept sorted to look up the ones starting with a prefix.
+type searchIndex struct {
+	// postings keep the weight of the word in every event it occurs in.
+	postings map[string]map[string]float64
+	words    []string
+	// docs keep words of every event to remove them.
+	docs map[string][]string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string][]string),
+	}
+}
+
+func (idx *searchIndex) insert(id string, event storage.Event) {
+	idx.remove(id)
+
+	weights := make(map[string]float64)
+
+	for _, w := range storage.SearchTerms(event.Title) {
+		weights[w] += titleWeight
+	}
+
+	for _, w := range storage.SearchTerms(event.Description) {
+		weights[w] += descriptionWeight
+	}
+
+	words := make([]string, 0, len(weights))
+
+	for w, weight := range weights {
+		postings, ok := idx.postings[w]
+		if !ok {
+			postings = make(map[string]float64)
+			idx.postings[w] = postings
+
+			i := sort.SearchStrings(idx.words, w)
+			idx.words = append(idx.words, "")
+			copy(idx.words[i+1:], idx.words[i:])
+			idx.words[i] = w
+		}
+
+		postings[id] = weight
+		words = append(words, w)
+	}
+
+	idx.docs[id] = words
+}
+
+func (idx *searchIndex) remove(id string) {
+	for _, w := range idx.docs[id] {
+		postings := idx.postings[w]
+		delete(postings, id)
+
+		if len(postings) == 0 {
+			delete(idx.postings, w)
+
+			i := sort.SearchStrings(idx.words, w)
+			idx.words = append(idx.words[:i], idx.words[i+1:]...)
+		}
+	}
+
+	delete(idx.docs, id)
+}
+
+// search scores events having a word starting with every term, a term scores the weights of all such words.
+func (idx *searchIndex) search(terms []string) map[string]float64 {
+	var scores map[string]float64
+
+	for _, t := range terms {
+		matches := make(map[string]float64)
+
+		for i := sort.SearchStrings(idx.words, t); i < len(idx.words) && strings.HasPrefix(idx.words[i], t); i++ {
+			for id, weight := range idx.postings[idx.words[i]] {
+				matches[id] += weight
+			}
+		}
+
+		// events have to match every term.
+		if scores != nil {
+			for id := range matches {
+				if score, ok := scores[id]; ok {
+					matches[id] += score
+				} else {
+					delete(matches, id)
+				}
+			}
+		}
+
+		scores = matches
+	}
+
+	return scores
+}
+
+// SearchEvents ranks events with the inverted index, ties go in start order.
+func (s *Storage) SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scores := s.search.search(storage.SearchTerms(query.Text))
+	events := make([]storage.Event, 0, len(scores))
+
+	for id := range scores {
+		event := s.events[id]
+
+		if !query.From.IsZero() && event.StartsAt.Before(query.From) {
+			continue
+		}
+
+		if !query.To.IsZero() && !event.StartsAt.Before(query.To) {
+			continue
+		}
+
+		if s.matches(event, query.Filter) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+
+		if scores[a.ID] != scores[b.ID] {
+			return scores[a.ID] > scores[b.ID]
+		}
+
+		if !a.StartsAt.Equal(b.StartsAt) {
+			return a.StartsAt.Before(b.StartsAt)
+		}
+
+		return a.ID < b.ID
+	})
+
+	if len(events) > query.Limit {
+		events = events[:query.Limit]
+	}
+
+	return events, nil
+}
@@ -0,0 +1,37 @@
+package memorystorage
+
+import (
+	"testing"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchIndex(t *testing.T) {
+	idx := newSearchIndex()
+
+	idx.insert("1", storage.Event{Title: "Standup standup", Description: "team"})
+	idx.insert("2", storage.Event{Title: "Team meeting", Description: "standup notes"})
+	idx.insert("3", storage.Event{Title: "Stand"})
+
+	require.Equal(t, []string{"meeting", "notes", "stand", "standup", "team"}, idx.words)
+
+	// a term scores every word it's a prefix of.
+	require.Equal(t, map[string]float64{"1": 1, "2": 0.4, "3": 1}, idx.search([]string{"stand"}))
+	require.Equal(t, map[string]float64{"1": 1.4, "2": 1.4}, idx.search([]string{"standup", "team"}))
+	require.Empty(t, idx.search([]string{"standup", "meeting", "retro"}))
+
+	idx.insert("2", storage.Event{Title: "Retro"})
+	idx.remove("3")
+	idx.remove("4")
+
+	require.Equal(t, []string{"retro", "standup", "team"}, idx.words)
+	require.Equal(t, map[string]float64{"1": 1}, idx.search([]string{"stand"}))
+
+	idx.remove("1")
+	idx.remove("2")
+
+	require.Empty(t, idx.words)
+	require.Empty(t, idx.postings)
+	require.Empty(t, idx.docs)
+}
@@ -0,0 +1,113 @@
+package memorystorage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+var errSnapshotCorrupted = errors.New("snapshot is corrupted")
+
+type snapshot struct {
+	Seq           uint64                    `json:"seq"`
+	Events        []storage.Event           `json:"events"`
+	Settings      []storage.UserSettings    `json:"settings"`
+	Attendees     []storage.Attendee        `json:"attendees"`
+	Calendars     []storage.Calendar        `json:"calendars"`
+	Shares        []storage.CalendarShare   `json:"shares"`
+	Reminders     []storage.Reminder        `json:"reminders"`
+	Webhooks      []storage.Webhook         `json:"webhooks"`
+	Deliveries    []storage.WebhookDelivery `json:"deliveries"`
+	Outbox        []storage.OutboxMessage   `json:"outbox"`
+	Notifications []storage.Notification    `json:"notifications"`
+	// Audit is in the order records were appended.
+	Audit       []storage.AuditRecord `json:"audit"`
+	ListChanges map[string]time.Time  `json:"listChanges"`
+}
+
+// readSnapshot loads the snapshot at path, a missing file means there is nothing to restore yet.
+func readSnapshot(path string) (snapshot, error) {
+	var snap snapshot
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	} else if err != nil {
+		return snap, err
+	}
+
+	if len(data) < 4 || crc32.Checksum(data[4:], crcTable) != binary.BigEndian.Uint32(data[:4]) {
+		return snap, errSnapshotCorrupted
+	}
+
+	if err := json.Unmarshal(data[4:], &snap); err != nil {
+		return snap, err
+	}
+
+	return snap, nil
+}
+
+// writeSnapshot replaces the snapshot at path atomically and durably,
+// so a crash in the middle leaves the previous snapshot in place.
+func writeSnapshot(path string, snap snapshot) error {
+	payload, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	data := make([]byte, 4+len(payload))
+
+	binary.BigEndian.PutUint32(data[:4], crc32.Checksum(payload, crcTable))
+	copy(data[4:], payload)
+
+	tmpPath := path + ".tmp"
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// the rename is only durable once the directory is synced, the log must not be reset before that.
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	if err := dir.Sync(); err != nil {
+		dir.Close()
+
+		return err
+	}
+
+	return dir.Close()
+}
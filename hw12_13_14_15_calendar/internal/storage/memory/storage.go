@@ -2,99 +2,869 @@ package memorystorage
 
 import (
 	"context"
-	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
 )
 
-var (
-	errEventAlreadyExists = errors.New("event already exists")
-	errEventNotFound      = errors.New("event not found")
-)
-
 type Storage struct {
 	events map[string]storage.Event
-	mu     sync.RWMutex
+	index  intervalIndex
+	search *searchIndex
+	// trash keeps deleted events out of the index until they are restored or purged.
+	trash    map[string]storage.Event
+	settings map[string]storage.UserSettings
+	// attendees are kept per event and then per user.
+	attendees map[string]map[string]storage.Attendee
+	calendars map[string]storage.Calendar
+	// shares are kept per calendar and then per user.
+	shares    map[string]map[string]storage.CalendarShare
+	reminders map[string]storage.Reminder
+	// notify indexes reminders which haven't fired yet by the time they fire.
+	notify     intervalIndex
+	webhooks   map[string]storage.Webhook
+	deliveries map[string]storage.WebhookDelivery
+	outbox     map[string]storage.OutboxMessage
+	// notifications are kept until their event is purged.
+	notifications map[string]storage.Notification
+	audit         *auditLog
+	mu            sync.RWMutex
+	seq           uint64
+	wal           *wal
+	dir           string
+	done          chan struct{}
+	wg            sync.WaitGroup
+	closeOnce     sync.Once
+	closeErr      error
+	// listChanges are times lists of scopes were marked changed at.
+	listChanges map[string]time.Time
 }
 
 func New() *Storage {
 	return &Storage{
-		events: make(map[string]storage.Event),
+		events:        make(map[string]storage.Event),
+		search:        newSearchIndex(),
+		trash:         make(map[string]storage.Event),
+		settings:      make(map[string]storage.UserSettings),
+		attendees:     make(map[string]map[string]storage.Attendee),
+		calendars:     make(map[string]storage.Calendar),
+		shares:        make(map[string]map[string]storage.CalendarShare),
+		reminders:     make(map[string]storage.Reminder),
+		webhooks:      make(map[string]storage.Webhook),
+		deliveries:    make(map[string]storage.WebhookDelivery),
+		outbox:        make(map[string]storage.OutboxMessage),
+		notifications: make(map[string]storage.Notification),
+		audit:         newAuditLog(auditCapacity),
+		listChanges:   make(map[string]time.Time),
 	}
 }
 
-func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) error {
+func (s *Storage) CreateEvent(ctx context.Context, event storage.Event, log storage.ChangeLog) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, ok := s.events[event.ID]; ok {
-		return errEventAlreadyExists
+		return storage.ErrEventAlreadyExists
 	}
 
-	s.events[event.ID] = event
+	if _, ok := s.trash[event.ID]; ok {
+		return storage.ErrEventAlreadyExists
+	}
 
-	return nil
+	return s.commit(logged(record{Op: opCreateEvent, ID: event.ID, Event: &event}, log))
 }
 
-func (s *Storage) UpdateEvent(ctx context.Context, id string, event storage.Event) error {
+func (s *Storage) UpdateEvent(ctx context.Context, id string, event storage.Event, log storage.ChangeLog) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, ok := s.events[id]; !ok {
-		return errEventNotFound
+		return storage.ErrEventNotFound
 	}
 
-	s.events[id] = event
+	return s.commit(logged(record{Op: opUpdateEvent, ID: id, Event: &event}, log))
+}
 
-	return nil
+// DeleteEvent moves the event to the trash, it's kept along with attendees and reminders until purged.
+func (s *Storage) DeleteEvent(ctx context.Context, id string, log storage.ChangeLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return storage.ErrEventNotFound
+	}
+
+	deletedAt := time.Now().UTC()
+	event.DeletedAt = &deletedAt
+
+	return s.commit(logged(record{Op: opTrashEvent, ID: id, Event: &event}, log))
 }
 
-func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
+// RestoreEvent takes the event out of the trash, out of its calendar as well once the calendar is deleted.
+func (s *Storage) RestoreEvent(ctx context.Context, id string, log storage.ChangeLog) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.events[id]; !ok {
-		return errEventNotFound
+	if _, ok := s.trash[id]; !ok {
+		return storage.ErrEventNotFound
 	}
 
-	delete(s.events, id)
+	return s.commit(logged(record{Op: opRestoreEvent, ID: id}, log))
+}
+
+// PurgeDeletedEvents permanently deletes events trashed before the time and returns how many there were.
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+
+	for id, event := range s.trash {
+		if !event.DeletedAt.Before(before) {
+			continue
+		}
+
+		if err := s.commit(record{Op: opDeleteEvent, ID: id}); err != nil {
+			return purged, err
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// commit logs the change ahead of applying it, the caller must hold the write lock.
+func (s *Storage) commit(rec record) error {
+	rec.Seq = s.seq + 1
+	rec.ChangedAt = time.Now().UTC()
+
+	if s.wal != nil {
+		if err := s.wal.append(rec); err != nil {
+			return err
+		}
+	}
+
+	s.seq = rec.Seq
+	s.apply(rec)
 
 	return nil
 }
 
-func (s *Storage) ListDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+func (s *Storage) apply(rec record) {
+	scopes := s.changedScopes(rec)
+
+	switch rec.Op {
+	case opCreateEvent:
+		s.putEvent(rec.ID, *rec.Event)
+	case opUpdateEvent:
+		prev := s.events[rec.ID]
+		s.putEvent(rec.ID, *rec.Event)
+
+		if !prev.StartsAt.Equal(rec.Event.StartsAt) {
+			s.rescheduleReminders(rec.ID)
+		}
+	case opTrashEvent:
+		s.removeEvent(rec.ID)
+		s.trash[rec.ID] = *rec.Event
+	case opRestoreEvent:
+		event := s.trash[rec.ID]
+		event.DeletedAt = nil
+
+		if _, ok := s.calendars[event.CalendarID]; !ok {
+			event.CalendarID = ""
+		}
+
+		delete(s.trash, rec.ID)
+		s.putEvent(rec.ID, event)
+	case opDeleteEvent:
+		s.purgeEvent(rec.ID)
+	case opSaveUserSettings:
+		s.settings[rec.ID] = *rec.Settings
+	case opSaveAttendee:
+		s.putAttendee(*rec.Attendee)
+	case opRemoveAttendee:
+		delete(s.attendees[rec.ID], rec.UserID)
+	case opSaveCalendar:
+		s.calendars[rec.ID] = *rec.Calendar
+	case opDeleteCalendar:
+		s.removeCalendar(rec.ID, rec.Events)
+	case opSaveShare:
+		s.putShare(*rec.Share)
+	case opRemoveShare:
+		delete(s.shares[rec.ID], rec.UserID)
+	case opSaveReminder:
+		s.putReminder(*rec.Reminder)
+	case opFireReminder:
+		s.putReminder(*rec.Reminder)
+	case opDeleteOutbox:
+		delete(s.outbox, rec.ID)
+	case opDeleteReminder:
+		s.removeReminder(rec.ID)
+	case opSaveWebhook:
+		s.webhooks[rec.ID] = *rec.Webhook
+	case opDeleteWebhook:
+		s.removeWebhook(rec.ID)
+	case opSaveDelivery:
+		s.deliveries[rec.ID] = *rec.Delivery
+	}
+
+	// notifications, outbox messages and audit records are saved along with whatever operation carries them.
+	s.putNotifications(rec.Notifications, rec.Outbox)
+
+	for _, r := range rec.Audit {
+		s.audit.append(r)
+	}
+
+	s.markLists(scopes, rec.ChangedAt)
+}
+
+// logged returns the record carrying the logs of the change along,
+// so they are committed or lost together with the change itself.
+func logged(rec record, logs ...storage.ChangeLog) record {
+	for _, log := range logs {
+		rec.Audit = append(rec.Audit, auditRecords(log.Audit...)...)
+		rec.Notifications = append(rec.Notifications, notificationRecords(log.Notifications)...)
+		rec.Outbox = append(rec.Outbox, outboxMessages(log.Outbox)...)
+	}
+
+	return rec
+}
+
+func (s *Storage) putEvent(id string, event storage.Event) {
+	s.removeEvent(id)
+
+	// the caller keeps the slice, so the stored event gets a copy of its own.
+	if event.Tags != nil {
+		event.Tags = append([]string(nil), event.Tags...)
+	}
+
+	s.events[id] = event
+	s.index.insert(id, event.StartsAt, event.StartsAt.Add(event.Duration))
+	s.search.insert(id, event)
+}
+
+func (s *Storage) removeEvent(id string) {
+	if prev, ok := s.events[id]; ok {
+		s.index.remove(id, prev.StartsAt)
+		s.search.remove(id)
+		delete(s.events, id)
+	}
+}
+
+func (s *Storage) putReminder(reminder storage.Reminder) {
+	s.removeReminder(reminder.ID)
+
+	s.reminders[reminder.ID] = reminder
+
+	if reminder.FiredAt == nil {
+		s.notify.insert(reminder.ID, reminder.RemindAt, reminder.RemindAt)
+	}
+}
+
+func (s *Storage) removeReminder(id string) {
+	if prev, ok := s.reminders[id]; ok {
+		if prev.FiredAt == nil {
+			s.notify.remove(id, prev.RemindAt)
+		}
+
+		delete(s.reminders, id)
+	}
+}
+
+// eventReminders returns IDs of reminders of the event.
+func (s *Storage) eventReminders(eventID string) []string {
+	var ids []string
+
+	for id, reminder := range s.reminders {
+		if reminder.EventID == eventID {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// rescheduleReminders moves reminders of the event after its start and arms them again.
+func (s *Storage) rescheduleReminders(eventID string) {
+	event := s.events[eventID]
+
+	for _, id := range s.eventReminders(eventID) {
+		reminder := s.reminders[id]
+		reminder.RemindAt = storage.RemindAt(event, reminder)
+		reminder.FiredAt = nil
+
+		s.putReminder(reminder)
+	}
+}
+
+func (s *Storage) removeReminders(eventID string) {
+	for _, id := range s.eventReminders(eventID) {
+		s.removeReminder(id)
+	}
+}
+
+func (s *Storage) putAttendee(attendee storage.Attendee) {
+	attendees, ok := s.attendees[attendee.EventID]
+	if !ok {
+		attendees = make(map[string]storage.Attendee)
+		s.attendees[attendee.EventID] = attendees
+	}
+
+	attendees[attendee.UserID] = attendee
+}
+
+func (s *Storage) putShare(share storage.CalendarShare) {
+	shares, ok := s.shares[share.CalendarID]
+	if !ok {
+		shares = make(map[string]storage.CalendarShare)
+		s.shares[share.CalendarID] = shares
+	}
+
+	shares[share.UserID] = share
+}
+
+// purgeEvent removes the event wherever it is along with its attendees, reminders and notifications.
+func (s *Storage) purgeEvent(id string) {
+	s.removeEvent(id)
+	s.removeReminders(id)
+	s.removeNotifications(id)
+	delete(s.trash, id)
+	delete(s.attendees, id)
+}
+
+// removeCalendar removes the calendar along with its shares and moves its events to the trash.
+func (s *Storage) removeCalendar(id string, trashed []storage.Event) {
+	for _, event := range trashed {
+		s.removeEvent(event.ID)
+		s.trash[event.ID] = event
+	}
+
+	delete(s.shares, id)
+	delete(s.calendars, id)
+}
+
+func (s *Storage) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.events[id]
+	if !ok {
+		return event, storage.ErrEventNotFound
+	}
+
+	return event, nil
+}
+
+// GetDeletedEvent returns the event if it's in the trash.
+func (s *Storage) GetDeletedEvent(ctx context.Context, id string) (storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	event, ok := s.trash[id]
+	if !ok {
+		return event, storage.ErrEventNotFound
+	}
+
+	return event, nil
+}
+
+// ListDeletedEvents returns events in the trash matching the filter, the recently deleted ones first.
+func (s *Storage) ListDeletedEvents(ctx context.Context, filter storage.EventFilter) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]storage.Event, 0)
+
+	for _, event := range s.trash {
+		if s.matches(event, filter) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].DeletedAt.Equal(*events[j].DeletedAt) {
+			return events[i].DeletedAt.After(*events[j].DeletedAt)
+		}
+
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+func (s *Storage) ListDayEvents(
+	ctx context.Context, date time.Time, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.DayRange(date)
+
+	return s.listEventsBetween(from, to, filter)
+}
+
+func (s *Storage) ListWeekEvents(
+	ctx context.Context, date time.Time, firstDay time.Weekday, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.WeekRange(date, firstDay)
+
+	return s.listEventsBetween(from, to, filter)
+}
+
+func (s *Storage) ListMonthEvents(
+	ctx context.Context, date time.Time, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.MonthRange(date)
+
+	return s.listEventsBetween(from, to, filter)
+}
+
+// CreateReminder adds the reminder to the event, when it fires is derived from the event start.
+func (s *Storage) CreateReminder(ctx context.Context, reminder storage.Reminder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, ok := s.events[reminder.EventID]
+	if !ok {
+		return storage.ErrEventNotFound
+	}
+
+	reminder.RemindAt = storage.RemindAt(event, reminder)
+
+	return s.commit(record{Op: opSaveReminder, ID: reminder.ID, Reminder: &reminder})
+}
+
+func (s *Storage) DeleteReminder(ctx context.Context, eventID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reminder, ok := s.reminders[id]; !ok || reminder.EventID != eventID {
+		return storage.ErrReminderNotFound
+	}
+
+	return s.commit(record{Op: opDeleteReminder, ID: id})
+}
+
+// ListReminders returns reminders of the event in the order they fire.
+func (s *Storage) ListReminders(ctx context.Context, eventID string) ([]storage.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var reminders []storage.Reminder
+
+	for _, id := range s.eventReminders(eventID) {
+		reminders = append(reminders, s.reminders[id])
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		if !reminders[i].RemindAt.Equal(reminders[j].RemindAt) {
+			return reminders[i].RemindAt.Before(reminders[j].RemindAt)
+		}
+
+		return reminders[i].ID < reminders[j].ID
+	})
+
+	return reminders, nil
+}
+
+// ListDueReminders returns reminders due within [from, to) which haven't fired yet in the order they fire.
+// Reminders of events in the trash are left out.
+func (s *Storage) ListDueReminders(ctx context.Context, from, to time.Time) ([]storage.Reminder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var reminders []storage.Reminder
+
+	s.notify.startingBetween(from, to, func(id string) {
+		reminder := s.reminders[id]
+
+		if _, trashed := s.trash[reminder.EventID]; !trashed {
+			reminders = append(reminders, reminder)
+		}
+	})
+
+	return reminders, nil
+}
+
+func (s *Storage) MarkReminderFired(ctx context.Context, id string, firedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reminder, ok := s.reminders[id]
+	if !ok {
+		return storage.ErrReminderNotFound
+	}
+
+	firedAt = firedAt.UTC()
+	reminder.FiredAt = &firedAt
+
+	return s.commit(record{Op: opSaveReminder, ID: id, Reminder: &reminder})
+}
+
+func (s *Storage) GetUserSettings(ctx context.Context, userID string) (storage.UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.settings[userID]
+	if !ok {
+		return settings, storage.ErrUserSettingsNotFound
+	}
+
+	return settings, nil
+}
+
+func (s *Storage) SaveUserSettings(ctx context.Context, settings storage.UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(record{Op: opSaveUserSettings, ID: settings.UserID, Settings: &settings})
+}
+
+// SaveAttendee invites the attendee or updates the role and email of the invited one, keeping their response.
+func (s *Storage) SaveAttendee(ctx context.Context, attendee storage.Attendee) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.events[attendee.EventID]; !ok {
+		return storage.ErrEventNotFound
+	}
+
+	if prev, ok := s.attendees[attendee.EventID][attendee.UserID]; ok {
+		attendee.Status = prev.Status
+	}
+
+	return s.commit(record{Op: opSaveAttendee, ID: attendee.EventID, Attendee: &attendee})
+}
+
+func (s *Storage) SetAttendeeStatus(ctx context.Context, eventID, userID string, status storage.AttendeeStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attendee, ok := s.attendees[eventID][userID]
+	if !ok {
+		return storage.ErrAttendeeNotFound
+	}
+
+	attendee.Status = status
+
+	return s.commit(record{Op: opSaveAttendee, ID: eventID, Attendee: &attendee})
+}
+
+func (s *Storage) RemoveAttendee(ctx context.Context, eventID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attendees[eventID][userID]; !ok {
+		return storage.ErrAttendeeNotFound
+	}
+
+	return s.commit(record{Op: opRemoveAttendee, ID: eventID, UserID: userID})
+}
+
+func (s *Storage) GetAttendee(ctx context.Context, eventID, userID string) (storage.Attendee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attendee, ok := s.attendees[eventID][userID]
+	if !ok {
+		return attendee, storage.ErrAttendeeNotFound
+	}
+
+	return attendee, nil
+}
+
+// ListAttendees returns attendees of the event ordered by user id.
+func (s *Storage) ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return s.listEventsBetween(from, from.AddDate(0, 0, 1))
+	return s.listAttendees(eventID), nil
 }
 
-func (s *Storage) ListWeekEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	offset := (int(time.Monday) - int(date.Weekday()) - 7) % 7
-	weekStart := date.AddDate(0, 0, offset)
-	from := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, date.Location())
+func (s *Storage) listAttendees(eventID string) []storage.Attendee {
+	attendees := make([]storage.Attendee, 0, len(s.attendees[eventID]))
 
-	return s.listEventsBetween(from, from.AddDate(0, 0, 7))
+	for _, a := range s.attendees[eventID] {
+		attendees = append(attendees, a)
+	}
+
+	sort.Slice(attendees, func(i, j int) bool {
+		return attendees[i].UserID < attendees[j].UserID
+	})
+
+	return attendees
 }
 
-func (s *Storage) ListMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	from := time.Date(date.Year(), date.Month(), 0, 0, 0, 0, 0, date.Location())
+// ListBusyIntervals returns intervals of events overlapping [from, to) the users own or attend,
+// unless they declined, ordered by user and start time. Events without duration take no time.
+// All-day events are matched a day around the range, their dates fall on other times in time zones of owners.
+func (s *Storage) ListBusyIntervals(
+	ctx context.Context, userIDs []string, from, to time.Time,
+) ([]storage.BusyInterval, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var intervals []storage.BusyInterval
 
-	return s.listEventsBetween(from, from.AddDate(0, 1, 0))
+	collect := func(allDay bool) func(id string) {
+		return func(id string) {
+			event := s.events[id]
+			if event.Duration <= 0 || event.AllDay != allDay {
+				return
+			}
+
+			interval := storage.Interval{Start: event.StartsAt, End: event.StartsAt.Add(event.Duration)}
+
+			for _, userID := range userIDs {
+				if s.busy(event, userID) {
+					intervals = append(intervals, storage.BusyInterval{
+						UserID: userID, Interval: interval, OwnerID: event.OwnerID, AllDay: event.AllDay,
+					})
+				}
+			}
+		}
+	}
+
+	fromDate, toDate := storage.AllDayRange(from, to)
+
+	s.index.overlapping(from, to, collect(false))
+	s.index.overlapping(fromDate, toDate, collect(true))
+
+	sort.SliceStable(intervals, func(i, j int) bool {
+		if intervals[i].UserID != intervals[j].UserID {
+			return intervals[i].UserID < intervals[j].UserID
+		}
+
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	return intervals, nil
 }
 
-func (s *Storage) listEventsBetween(from, to time.Time) ([]storage.Event, error) {
+func (s *Storage) busy(event storage.Event, userID string) bool {
+	if event.OwnerID == userID {
+		return true
+	}
+
+	attendee, ok := s.attendees[event.ID][userID]
+
+	return ok && attendee.Status != storage.StatusDeclined
+}
+
+// listEventsBetween returns events overlapping [from, to) ordered by start time,
+// all-day ones are matched by dates the period covers rather than by its time.
+func (s *Storage) listEventsBetween(from, to time.Time, filter storage.EventFilter) ([]storage.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	var events []storage.Event
 
-	for _, e := range s.events {
-		if e.StartsAt.Before(to) && e.StartsAt.After(from) {
-			events = append(events, e)
+	s.index.overlapping(from, to, func(id string) {
+		if event := s.events[id]; !event.AllDay && s.matches(event, filter) {
+			events = append(events, event)
+		}
+	})
+
+	fromDate, toDate := storage.DatesRange(from, to)
+
+	s.index.overlapping(fromDate, toDate, func(id string) {
+		if event := s.events[id]; event.AllDay && s.matches(event, filter) {
+			events = append(events, event)
+		}
+	})
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt) {
+			return events[i].StartsAt.Before(events[j].StartsAt)
+		}
+
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+func (s *Storage) matches(event storage.Event, filter storage.EventFilter) bool {
+	if filter.CalendarID != "" && event.CalendarID != filter.CalendarID {
+		return false
+	}
+
+	if !filter.HasTags(event.Tags) {
+		return false
+	}
+
+	if filter.UserID == "" || event.OwnerID == filter.UserID {
+		return true
+	}
+
+	if _, invited := s.attendees[event.ID][filter.UserID]; invited {
+		return true
+	}
+
+	if calendar, ok := s.calendars[event.CalendarID]; ok && calendar.OwnerID == filter.UserID {
+		return true
+	}
+
+	share, ok := s.shares[event.CalendarID][filter.UserID]
+
+	return ok && share.Access != storage.AccessFreeBusy
+}
+
+func (s *Storage) CreateCalendar(ctx context.Context, calendar storage.Calendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(record{Op: opSaveCalendar, ID: calendar.ID, Calendar: &calendar})
+}
+
+func (s *Storage) UpdateCalendar(ctx context.Context, calendar storage.Calendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calendars[calendar.ID]; !ok {
+		return storage.ErrCalendarNotFound
+	}
+
+	return s.commit(record{Op: opSaveCalendar, ID: calendar.ID, Calendar: &calendar})
+}
+
+// DeleteCalendar deletes the calendar along with its shares and moves its events to the trash,
+// the log of every trashed event is written along. It returns events as they were before the deletion.
+func (s *Storage) DeleteCalendar(
+	ctx context.Context, id string, logTrashed storage.LogTrashed,
+) ([]storage.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calendars[id]; !ok {
+		return nil, storage.ErrCalendarNotFound
+	}
+
+	deletedAt := time.Now().UTC()
+	rec := record{Op: opDeleteCalendar, ID: id}
+
+	var events []storage.Event
+
+	for _, event := range s.events {
+		if event.CalendarID == id {
+			events = append(events, event)
 		}
 	}
 
+	// the change log is written in the order the SQL storage trashes events in.
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt) {
+			return events[i].StartsAt.Before(events[j].StartsAt)
+		}
+
+		return events[i].ID < events[j].ID
+	})
+
+	for _, event := range events {
+		log, err := logTrashed(event, s.listAttendees(event.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		rec = logged(rec, log)
+
+		event.DeletedAt = &deletedAt
+		rec.Events = append(rec.Events, event)
+	}
+
+	if err := s.commit(rec); err != nil {
+		return nil, err
+	}
+
 	return events, nil
 }
+
+func (s *Storage) GetCalendar(ctx context.Context, id string) (storage.Calendar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	calendar, ok := s.calendars[id]
+	if !ok {
+		return calendar, storage.ErrCalendarNotFound
+	}
+
+	return calendar, nil
+}
+
+// ListCalendars returns calendars the user owns or has been shared ordered by name.
+func (s *Storage) ListCalendars(ctx context.Context, userID string) ([]storage.Calendar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var calendars []storage.Calendar
+
+	for id, calendar := range s.calendars {
+		if _, shared := s.shares[id][userID]; shared || calendar.OwnerID == userID {
+			calendars = append(calendars, calendar)
+		}
+	}
+
+	sort.Slice(calendars, func(i, j int) bool {
+		if calendars[i].Name != calendars[j].Name {
+			return calendars[i].Name < calendars[j].Name
+		}
+
+		return calendars[i].ID < calendars[j].ID
+	})
+
+	return calendars, nil
+}
+
+// SaveShare shares the calendar with the user or changes the access they were given.
+func (s *Storage) SaveShare(ctx context.Context, share storage.CalendarShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.calendars[share.CalendarID]; !ok {
+		return storage.ErrCalendarNotFound
+	}
+
+	return s.commit(record{Op: opSaveShare, ID: share.CalendarID, Share: &share})
+}
+
+func (s *Storage) RemoveShare(ctx context.Context, calendarID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.shares[calendarID][userID]; !ok {
+		return storage.ErrShareNotFound
+	}
+
+	return s.commit(record{Op: opRemoveShare, ID: calendarID, UserID: userID})
+}
+
+func (s *Storage) GetShare(ctx context.Context, calendarID, userID string) (storage.CalendarShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	share, ok := s.shares[calendarID][userID]
+	if !ok {
+		return share, storage.ErrShareNotFound
+	}
+
+	return share, nil
+}
+
+// ListShares returns shares of the calendar ordered by user id.
+func (s *Storage) ListShares(ctx context.Context, calendarID string) ([]storage.CalendarShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var shares []storage.CalendarShare
+
+	for _, share := range s.shares[calendarID] {
+		shares = append(shares, share)
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].UserID < shares[j].UserID
+	})
+
+	return shares, nil
+}
@@ -0,0 +1,91 @@
+package memorystorage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// go test -run=^$ -bench=ListEvents -benchmem .
+
+const benchEventsCount = 1_000_000
+
+var benchYear = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newBenchStorage(b *testing.B) *Storage {
+	b.Helper()
+
+	s := New()
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < benchEventsCount; i++ {
+		event := storage.Event{
+			ID:       fmt.Sprintf("%08d", i),
+			StartsAt: benchYear.Add(time.Duration(r.Int63n(int64(365 * 24 * time.Hour)))),
+			Duration: time.Duration(r.Int63n(int64(3 * time.Hour))),
+		}
+
+		if err := s.CreateEvent(context.TODO(), event, storage.ChangeLog{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+// scanEventsBetween is the former full scan implementation kept as a baseline.
+func (s *Storage) scanEventsBetween(from, to time.Time) ([]storage.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []storage.Event
+
+	for _, e := range s.events {
+		if e.StartsAt.Before(to) && e.StartsAt.After(from) {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+func BenchmarkListEvents(b *testing.B) {
+	s := newBenchStorage(b)
+	date := benchYear.AddDate(0, 6, 0)
+	periods := []struct {
+		name     string
+		from, to time.Time
+	}{
+		{"day", date, date.AddDate(0, 0, 1)},
+		{"week", date, date.AddDate(0, 0, 7)},
+		{"month", date, date.AddDate(0, 1, 0)},
+	}
+
+	for _, p := range periods {
+		p := p
+
+		b.Run("scan/"+p.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.scanEventsBetween(p.from, p.to)
+			}
+		})
+
+		b.Run("index/"+p.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.listEventsBetween(p.from, p.to, storage.EventFilter{})
+			}
+		})
+
+		b.Run("overlap/"+p.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.mu.RLock()
+				s.index.overlapping(p.from, p.to, func(id string) {})
+				s.mu.RUnlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package memorystorage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	opCreateEvent = "create_event"
+	opUpdateEvent = "update_event"
+	// opDeleteEvent deletes the event for good, opTrashEvent only moves it to the trash.
+	opDeleteEvent  = "delete_event"
+	opTrashEvent   = "trash_event"
+	opRestoreEvent = "restore_event"
+
+	opSaveUserSettings = "save_user_settings"
+
+	opSaveAttendee   = "save_attendee"
+	opRemoveAttendee = "remove_attendee"
+
+	opSaveCalendar   = "save_calendar"
+	opDeleteCalendar = "delete_calendar"
+	opSaveShare      = "save_share"
+	opRemoveShare    = "remove_share"
+
+	opSaveReminder   = "save_reminder"
+	opDeleteReminder = "delete_reminder"
+	// opFireReminder saves the fired reminder along with its notifications and outbox messages.
+	opFireReminder = "fire_reminder"
+
+	opSaveOutbox   = "save_outbox"
+	opDeleteOutbox = "delete_outbox"
+
+	// opSaveNotification saves notifications along with outbox messages publishing them again, if any.
+	opSaveNotification = "save_notification"
+
+	opAppendAudit = "append_audit"
+
+	opSaveWebhook   = "save_webhook"
+	opDeleteWebhook = "delete_webhook"
+	opSaveDelivery  = "save_delivery"
+)
+
+const (
+	// every record is prefixed with its payload length and crc32 checksum.
+	walHeaderSize = 8
+	// anything larger can only be a garbage length read from a torn tail.
+	walMaxRecordSize = 16 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type record struct {
+	Seq      uint64                   `json:"seq"`
+	Op       string                   `json:"op"`
+	ID       string                   `json:"id"`
+	Event    *storage.Event           `json:"event,omitempty"`
+	Settings *storage.UserSettings    `json:"settings,omitempty"`
+	Attendee *storage.Attendee        `json:"attendee,omitempty"`
+	Calendar *storage.Calendar        `json:"calendar,omitempty"`
+	Share    *storage.CalendarShare   `json:"share,omitempty"`
+	Reminder *storage.Reminder        `json:"reminder,omitempty"`
+	Webhook  *storage.Webhook         `json:"webhook,omitempty"`
+	Delivery *storage.WebhookDelivery `json:"delivery,omitempty"`
+	// Events are moved to the trash along with the deleted calendar.
+	Events        []storage.Event         `json:"events,omitempty"`
+	Outbox        []storage.OutboxMessage `json:"outbox,omitempty"`
+	Notifications []storage.Notification  `json:"notifications,omitempty"`
+	// Audit records are appended to the history whatever the operation is.
+	Audit []storage.AuditRecord `json:"audit,omitempty"`
+	// UserID identifies the attendee or the share removed from the event or the calendar ID.
+	UserID string `json:"userId,omitempty"`
+	// ChangedAt is when lists the change touches are marked changed at.
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type wal struct {
+	file *os.File
+	size int64
+}
+
+// openWAL opens the log at path and returns the records it holds.
+// A torn or corrupted tail left by a crash is cut off, so appends continue right after the last intact record.
+func openWAL(path string) (*wal, []record, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, size, err := readRecords(file)
+	if err != nil {
+		file.Close()
+
+		return nil, nil, err
+	}
+
+	w := &wal{file, size}
+
+	if err := w.truncate(size); err != nil {
+		file.Close()
+
+		return nil, nil, err
+	}
+
+	return w, records, nil
+}
+
+func readRecords(r io.Reader) ([]record, int64, error) {
+	var (
+		records []record
+		offset  int64
+	)
+
+	reader := bufio.NewReader(r)
+	header := make([]byte, walHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return records, offset, ignoreTornRead(err)
+		}
+
+		size := binary.BigEndian.Uint32(header[:4])
+		checksum := binary.BigEndian.Uint32(header[4:])
+
+		if size > walMaxRecordSize {
+			return records, offset, nil
+		}
+
+		payload := make([]byte, size)
+
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return records, offset, ignoreTornRead(err)
+		}
+
+		if crc32.Checksum(payload, crcTable) != checksum {
+			return records, offset, nil
+		}
+
+		var rec record
+
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return records, offset, nil
+		}
+
+		records = append(records, rec)
+		offset += walHeaderSize + int64(size)
+	}
+}
+
+func ignoreTornRead(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+
+	return err
+}
+
+func (w *wal) append(rec record) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, walHeaderSize+len(payload))
+
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:walHeaderSize], crc32.Checksum(payload, crcTable))
+	copy(buf[walHeaderSize:], payload)
+
+	if _, err := w.file.Write(buf); err != nil {
+		return w.rollback(err)
+	}
+
+	// the operation is committed only once the record reaches the disk, a crash must not take it away.
+	if err := w.file.Sync(); err != nil {
+		return w.rollback(err)
+	}
+
+	w.size += int64(len(buf))
+
+	return nil
+}
+
+// rollback drops a partially written record, otherwise every record after it becomes unreadable.
+func (w *wal) rollback(err error) error {
+	if truncErr := w.truncate(w.size); truncErr != nil {
+		return truncErr
+	}
+
+	return err
+}
+
+func (w *wal) truncate(size int64) error {
+	if err := w.file.Truncate(size); err != nil {
+		return err
+	}
+
+	if _, err := w.file.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+
+	w.size = size
+
+	return nil
+}
+
+func (w *wal) close() error {
+	if err := w.file.Sync(); err != nil {
+		w.file.Close()
+
+		return err
+	}
+
+	return w.file.Close()
+}
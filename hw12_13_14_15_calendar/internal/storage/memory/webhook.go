@@ -0,0 +1,165 @@
+package memorystorage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(record{Op: opSaveWebhook, ID: webhook.ID, Webhook: &webhook})
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+
+	return s.commit(record{Op: opDeleteWebhook, ID: id})
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return webhook, storage.ErrWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+// ListWebhooks returns webhooks of the user ordered by URL.
+func (s *Storage) ListWebhooks(ctx context.Context, ownerID string) ([]storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []storage.Webhook{}
+
+	for _, w := range s.webhooks {
+		if w.OwnerID == ownerID {
+			webhooks = append(webhooks, w)
+		}
+	}
+
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].URL != webhooks[j].URL {
+			return webhooks[i].URL < webhooks[j].URL
+		}
+
+		return webhooks[i].ID < webhooks[j].ID
+	})
+
+	return webhooks, nil
+}
+
+func (s *Storage) CreateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+
+	normalizeDelivery(&delivery)
+
+	return s.commit(record{Op: opSaveDelivery, ID: delivery.ID, Delivery: &delivery})
+}
+
+// UpdateWebhookDelivery saves the outcome of an attempt, the payload is never changed.
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, ok := s.deliveries[delivery.ID]
+	if !ok {
+		return storage.ErrDeliveryNotFound
+	}
+
+	prev.Status = delivery.Status
+	prev.Attempts = delivery.Attempts
+	prev.NextAttemptAt = delivery.NextAttemptAt
+	prev.LastError = delivery.LastError
+	prev.DeliveredAt = delivery.DeliveredAt
+	normalizeDelivery(&prev)
+
+	return s.commit(record{Op: opSaveDelivery, ID: prev.ID, Delivery: &prev})
+}
+
+// ListDueWebhookDeliveries returns pending deliveries due by the time in the order they are due.
+func (s *Storage) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]storage.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deliveries []storage.WebhookDelivery
+
+	for _, d := range s.deliveries {
+		if d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+		}
+
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+// ListWebhookDeliveries returns deliveries of the webhook, the latest first.
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]storage.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []storage.WebhookDelivery{}
+
+	for _, d := range s.deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+
+		return deliveries[i].ID < deliveries[j].ID
+	})
+
+	return deliveries, nil
+}
+
+func (s *Storage) removeWebhook(id string) {
+	delete(s.webhooks, id)
+
+	for deliveryID, d := range s.deliveries {
+		if d.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+}
+
+func normalizeDelivery(delivery *storage.WebhookDelivery) {
+	delivery.NextAttemptAt = delivery.NextAttemptAt.UTC()
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+
+	if delivery.DeliveredAt != nil {
+		deliveredAt := delivery.DeliveredAt.UTC()
+		delivery.DeliveredAt = &deliveredAt
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import "time"
+
+// NotificationStatus is where the delivery of a notification is at.
+type NotificationStatus string
+
+const (
+	NotificationPending NotificationStatus = "pending"
+	NotificationSent    NotificationStatus = "sent"
+	// NotificationFailed is final until the notification is sent again by hand.
+	NotificationFailed NotificationStatus = "failed"
+)
+
+// Notification records a reminder of the event fired to one of its recipients, or a notice about a change of the event,
+// and how its delivery went.
+type Notification struct {
+	ID      string `db:"id"`
+	EventID string `db:"event_id"`
+	// ReminderID is empty for notices about changes.
+	ReminderID string  `db:"reminder_id"`
+	UserID     string  `db:"user_id"`
+	Channel    Channel `db:"channel"`
+	// ScheduledAt is when the reminder was due.
+	ScheduledAt time.Time          `db:"scheduled_at"`
+	Status      NotificationStatus `db:"status"`
+	// Attempts counts delivery attempts made so far.
+	Attempts int `db:"attempts"`
+	// LastError tells why the last attempt failed.
+	LastError string `db:"last_error"`
+	// Payload is the encoded notification published on every attempt, storages don't look into it.
+	Payload   string     `db:"payload"`
+	CreatedAt time.Time  `db:"created_at"`
+	SentAt    *time.Time `db:"sent_at"`
+}
+
+// NotificationFilter narrows listed notifications down, empty fields match anything.
+type NotificationFilter struct {
+	EventID string
+	UserID  string
+	Status  NotificationStatus
+}
+
+// Matches tells whether the notification passes the filter.
+func (f NotificationFilter) Matches(n Notification) bool {
+	return (f.EventID == "" || f.EventID == n.EventID) &&
+		(f.UserID == "" || f.UserID == n.UserID) &&
+		(f.Status == "" || f.Status == n.Status)
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "time"
+
+// OutboxMessage is a notification waiting to be published,
+// it's written in the same transaction as the change it tells about.
+type OutboxMessage struct {
+	ID string `db:"id"`
+	// Payload is the encoded notification, storages don't look into it.
+	Payload   string    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+	// SentAt is set once the message has been published.
+	SentAt *time.Time `db:"sent_at"`
+}
@@ -0,0 +1,39 @@
+package storage
+
+import "time"
+
+// DayRange returns bounds of the day containing date in its location.
+func DayRange(date time.Time) (from, to time.Time) {
+	from = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	return from, from.AddDate(0, 0, 1)
+}
+
+// WeekRange returns bounds of the week, starting on firstDay, containing date in its location.
+func WeekRange(date time.Time, firstDay time.Weekday) (from, to time.Time) {
+	offset := (int(date.Weekday()) - int(firstDay) + 7) % 7
+	from = time.Date(date.Year(), date.Month(), date.Day()-offset, 0, 0, 0, 0, date.Location())
+
+	return from, from.AddDate(0, 0, 7)
+}
+
+// MonthRange returns bounds of the month containing date in its location.
+func MonthRange(date time.Time) (from, to time.Time) {
+	from = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+
+	return from, from.AddDate(0, 1, 0)
+}
+
+// DatesRange returns dates the period covers in its location as UTC midnights, the way all-day events are kept.
+func DatesRange(from, to time.Time) (fromDate, toDate time.Time) {
+	fromDate = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	return fromDate, toDate
+}
+
+// AllDayRange widens the range by a day on both sides, so it takes every all-day event
+// that may overlap the range in some time zone, as those are kept in UTC.
+func AllDayRange(from, to time.Time) (fromDate, toDate time.Time) {
+	return from.UTC().AddDate(0, 0, -1), to.UTC().AddDate(0, 0, 1)
+}
@@ -0,0 +1,31 @@
+package storage
+
+import "time"
+
+// Channel is how a reminder is delivered.
+type Channel string
+
+const (
+	ChannelLog     Channel = "log"
+	ChannelWebhook Channel = "webhook"
+	ChannelEmail   Channel = "email"
+)
+
+// Reminder notifies the owner and attendees of the event through the channel the offset before it starts.
+type Reminder struct {
+	ID      string        `db:"id"`
+	EventID string        `db:"event_id"`
+	Offset  time.Duration `db:"remind_offset"`
+	Channel Channel       `db:"channel"`
+	// Message replaces the default notification text if set.
+	Message string `db:"message"`
+	// RemindAt is derived from the event start on write.
+	RemindAt time.Time `db:"remind_at"`
+	// FiredAt is set once the reminder has fired, moving the event arms it again.
+	FiredAt *time.Time `db:"fired_at"`
+}
+
+// RemindAt returns when the reminder of the event fires.
+func RemindAt(event Event, reminder Reminder) time.Time {
+	return event.StartsAt.Add(-reminder.Offset).UTC()
+}
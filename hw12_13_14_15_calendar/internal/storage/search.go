@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchQuery looks events up by words of their titles and descriptions.
+type SearchQuery struct {
+	// Text matches events having every word of it as a prefix of some word, case is ignored.
+	Text string
+	// From and To bound the start of events, zero ones leave the range open.
+	From, To time.Time
+	Filter   EventFilter
+	// Limit bounds the number of found events, the best matching ones are kept.
+	Limit int
+}
+
+// SearchTerms splits the text into unique lowercase words made of letters and digits.
+func SearchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	seen := make(map[string]bool, len(words))
+
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+
+	return terms
+}
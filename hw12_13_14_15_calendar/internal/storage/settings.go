@@ -0,0 +1,9 @@
+package storage
+
+import "time"
+
+type UserSettings struct {
+	UserID         string       `db:"user_id"`
+	TimeZone       string       `db:"time_zone"`
+	FirstDayOfWeek time.Weekday `db:"first_day_of_week"`
+}
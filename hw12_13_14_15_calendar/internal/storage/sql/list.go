@@ -0,0 +1,114 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+// LastListChange returns when lists of the scopes were marked changed last, the zero time if never.
+func (s *Storage) LastListChange(ctx context.Context, scopes []string) (time.Time, error) {
+	var changedAt time.Time
+
+	if len(scopes) == 0 {
+		return changedAt, nil
+	}
+
+	query, args, err := sqlx.In("select changed_at from list_changes where scope in (?)", scopes)
+	if err != nil {
+		return changedAt, err
+	}
+
+	var marks []time.Time
+
+	if err := s.db.SelectContext(ctx, &marks, s.db.Rebind(query), args...); err != nil {
+		return changedAt, err
+	}
+
+	for _, t := range marks {
+		if t.After(changedAt) {
+			changedAt = t
+		}
+	}
+
+	return changedAt.UTC(), nil
+}
+
+// markLists marks lists of the scopes changed now within the transaction making the change, marks never move back.
+func markLists(ctx context.Context, tx *sqlx.Tx, scopes []string) error {
+	changedAt := time.Now().UTC()
+
+	for _, scope := range scopes {
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			insert into list_changes (scope, changed_at) values (?, ?)
+			on conflict (scope) do update
+			set changed_at=excluded.changed_at where list_changes.changed_at < excluded.changed_at
+		`), scope, changedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// eventScopes returns scopes of lists the event may appear in, trashed or not,
+// there are none once the event is gone.
+func eventScopes(ctx context.Context, db sqlx.ExtContext, eventID string) ([]string, error) {
+	var event storage.Event
+
+	err := sqlx.GetContext(ctx, db, &event, db.Rebind("select "+eventColumns+" from events where id=?"), eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var userIDs []string
+
+	err = sqlx.SelectContext(ctx, db, &userIDs, db.Rebind(`
+		select user_id from attendees where event_id=?
+		union select owner_id from calendars where id=?
+		union select user_id from calendar_shares where calendar_id=?
+	`), event.ID, event.CalendarID, event.CalendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	return storage.EventScopes(event, userIDs...), nil
+}
+
+// calendarScopes returns scopes of lists of the calendar, its owner and users it's shared with.
+func calendarScopes(ctx context.Context, db sqlx.ExtContext, id string) ([]string, error) {
+	var userIDs []string
+
+	err := sqlx.SelectContext(ctx, db, &userIDs, db.Rebind(`
+		select owner_id from calendars where id=?
+		union select user_id from calendar_shares where calendar_id=?
+	`), id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{storage.CalendarScope(id)}
+
+	for _, userID := range userIDs {
+		scopes = append(scopes, storage.UserScope(userID))
+	}
+
+	return scopes, nil
+}
+
+// markEventLists marks lists the event appears in along with lists of the scopes given.
+func markEventLists(ctx context.Context, tx *sqlx.Tx, eventID string, scopes []string) error {
+	listed, err := eventScopes(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	return markLists(ctx, tx, append(scopes, listed...))
+}
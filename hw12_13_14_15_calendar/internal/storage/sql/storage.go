@@ -2,22 +2,57 @@ package sqlstorage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
 )
 
+const (
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite"
+)
+
+// eventColumns lists columns mapped to storage.Event, ends_at is derived from them on write.
+const eventColumns = "id, title, starts_at, duration, description, owner_id, " +
+	"coalesce(calendar_id, '') as calendar_id, color, all_day, deleted_at"
+
+const (
+	attendeeColumns = "event_id, user_id, email, role, status"
+	calendarColumns = "id, owner_id, name, color, time_zone"
+	shareColumns    = "calendar_id, user_id, access"
+	reminderColumns = "id, event_id, remind_offset, channel, message, remind_at, fired_at"
+	auditColumns    = "id, event_id, actor_id, operation, created_at, changes"
+	webhookColumns  = "id, owner_id, url, secret, operations"
+	outboxColumns   = "id, payload, created_at, sent_at"
+	deliveryColumns = "id, webhook_id, event_id, operation, payload, status, attempts, " +
+		"next_attempt_at, last_error, created_at, delivered_at"
+	notificationColumns = "id, event_id, reminder_id, user_id, channel, scheduled_at, status, attempts, " +
+		"last_error, payload, created_at, sent_at"
+)
+
 type Storage struct {
 	db *sqlx.DB
 }
 
-func New(dsn string) (*Storage, error) {
-	db, err := sqlx.Open("postgres", dsn)
+func init() {
+	sqlx.BindDriver(SQLiteDriver, sqlx.QUESTION)
+}
+
+func New(driver, dsn string) (*Storage, error) {
+	db, err := sqlx.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	if driver == SQLiteDriver {
+		// sqlite allows a single writer, so serialize access instead of failing with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	}
+
 	return &Storage{db}, nil
 }
 
@@ -29,60 +64,1467 @@ func (s *Storage) Close(ctx context.Context) error {
 	return s.db.Close()
 }
 
-func (s *Storage) CreateEvent(ctx context.Context, event storage.Event) error {
-	_, err := s.db.NamedExecContext(ctx, `
+func (s *Storage) CreateEvent(ctx context.Context, event storage.Event, log storage.ChangeLog) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
 		insert into events (
-			id, title, starts_at, duration, description, owner_id, notify_before
+			id, title, starts_at, duration, description, owner_id, ends_at, calendar_id, color, all_day
 		) values (
-			:id, :title, :starts_at, :duration, :description, :owner_id, :notify_before
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 		)
-	`, &event)
+		on conflict (id) do nothing
+	`), event.ID, event.Title, event.StartsAt.UTC(), event.Duration, event.Description, event.OwnerID,
+		endsAt(event), nullString(event.CalendarID), event.Color, event.AllDay)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := checkAffected(res, storage.ErrEventAlreadyExists); err != nil {
+		return err
+	}
+
+	if err := insertTags(ctx, tx, event.ID, event.Tags); err != nil {
+		return err
+	}
+
+	if err := markEventLists(ctx, tx, event.ID, nil); err != nil {
+		return err
+	}
+
+	if err := writeChangeLog(ctx, tx, log); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Storage) UpdateEvent(ctx context.Context, id string, event storage.Event) error {
-	_, err := s.db.ExecContext(ctx, `
+// UpdateEvent replaces the event, moving it in time arms its reminders again.
+func (s *Storage) UpdateEvent(ctx context.Context, id string, event storage.Event, log storage.ChangeLog) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	var startsAt time.Time
+
+	err = tx.GetContext(ctx, &startsAt, tx.Rebind("select starts_at from events where id=? and deleted_at is null"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrEventNotFound
+	} else if err != nil {
+		return err
+	}
+
+	// the event may move to another calendar, so lists it leaves are marked as well.
+	scopes, err := eventScopes(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
 		update events
-		set title=?, starts_at=?, duration=?, description=?, owner_id=?, notify_before=?
+		set title=?, starts_at=?, duration=?, description=?, owner_id=?, ends_at=?, calendar_id=?, color=?, all_day=?
 		where id=?
-	`, event.Title, event.StartsAt, event.Duration, event.Description, event.OwnerID, event.NotifyBefore, id)
+	`), event.Title, event.StartsAt.UTC(), event.Duration, event.Description, event.OwnerID,
+		endsAt(event), nullString(event.CalendarID), event.Color, event.AllDay, id)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if _, err := tx.ExecContext(ctx, tx.Rebind("delete from event_tags where event_id=?"), id); err != nil {
+		return err
+	}
+
+	if err := insertTags(ctx, tx, id, event.Tags); err != nil {
+		return err
+	}
+
+	if !startsAt.Equal(event.StartsAt) {
+		if err := rescheduleReminders(ctx, tx, id, event); err != nil {
+			return err
+		}
+	}
+
+	if err := markEventLists(ctx, tx, id, scopes); err != nil {
+		return err
+	}
+
+	if err := writeChangeLog(ctx, tx, log); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Storage) DeleteEvent(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, "delete from events where id=?", id)
+func insertTags(ctx context.Context, tx *sqlx.Tx, eventID string, tags []string) error {
+	for _, tag := range tags {
+		_, err := tx.ExecContext(ctx, tx.Rebind("insert into event_tags (event_id, tag) values (?, ?)"), eventID, tag)
+		if err != nil {
+			return err
+		}
+	}
 
-	return err
+	return nil
+}
+
+// rescheduleReminders moves reminders of the event after its start and arms them again.
+func rescheduleReminders(ctx context.Context, tx *sqlx.Tx, id string, event storage.Event) error {
+	reminders := []storage.Reminder{}
+
+	err := tx.SelectContext(ctx, &reminders, tx.Rebind("select "+reminderColumns+" from reminders where event_id=?"), id)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range reminders {
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			update reminders set remind_at=?, fired_at=null where id=?
+		`), storage.RemindAt(event, r), r.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteEvent moves the event to the trash, it's kept along with attendees and reminders until purged.
+func (s *Storage) DeleteEvent(ctx context.Context, id string, log storage.ChangeLog) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		update events set deleted_at=? where id=? and deleted_at is null
+	`), time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrEventNotFound); err != nil {
+		return err
+	}
+
+	if err := markEventLists(ctx, tx, id, nil); err != nil {
+		return err
+	}
+
+	if err := writeChangeLog(ctx, tx, log); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *Storage) ListDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+// RestoreEvent takes the event out of the trash, out of its calendar as well once the calendar is deleted.
+func (s *Storage) RestoreEvent(ctx context.Context, id string, log storage.ChangeLog) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		update events set deleted_at=null, calendar_id=(select id from calendars where id = events.calendar_id)
+		where id=? and deleted_at is not null
+	`), id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrEventNotFound); err != nil {
+		return err
+	}
+
+	if err := markEventLists(ctx, tx, id, nil); err != nil {
+		return err
+	}
+
+	if err := writeChangeLog(ctx, tx, log); err != nil {
+		return err
+	}
 
-	return s.listEventsBetween(ctx, from, from.AddDate(0, 0, 1))
+	return tx.Commit()
 }
 
-func (s *Storage) ListWeekEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	offset := (int(time.Monday) - int(date.Weekday()) - 7) % 7
-	weekStart := date.AddDate(0, 0, offset)
-	from := time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, date.Location())
+// PurgeDeletedEvents permanently deletes events trashed before the time and returns how many there were.
+func (s *Storage) PurgeDeletedEvents(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("delete from events where deleted_at < ?"), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := res.RowsAffected()
 
-	return s.listEventsBetween(ctx, from, from.AddDate(0, 0, 7))
+	return int(purged), err
 }
 
-func (s *Storage) ListMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
-	from := time.Date(date.Year(), date.Month(), 0, 0, 0, 0, 0, date.Location())
+func (s *Storage) GetEvent(ctx context.Context, id string) (storage.Event, error) {
+	return s.getEvent(ctx, "select "+eventColumns+" from events where id=? and deleted_at is null", id)
+}
 
-	return s.listEventsBetween(ctx, from, from.AddDate(0, 1, 0))
+// GetDeletedEvent returns the event if it's in the trash.
+func (s *Storage) GetDeletedEvent(ctx context.Context, id string) (storage.Event, error) {
+	return s.getEvent(ctx, "select "+eventColumns+" from events where id=? and deleted_at is not null", id)
 }
 
-func (s *Storage) listEventsBetween(ctx context.Context, from, to time.Time) ([]storage.Event, error) {
-	events := []storage.Event{}
+func (s *Storage) getEvent(ctx context.Context, query, id string) (storage.Event, error) {
+	var event storage.Event
+
+	err := s.db.GetContext(ctx, &event, s.db.Rebind(query), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return event, storage.ErrEventNotFound
+	} else if err != nil {
+		return event, err
+	}
+
+	normalizeEvent(&event)
+
+	events := []storage.Event{event}
+
+	if err := loadTags(ctx, s.db, events); err != nil {
+		return event, err
+	}
+
+	return events[0], nil
+}
+
+// ListDeletedEvents returns events in the trash matching the filter, the recently deleted ones first.
+func (s *Storage) ListDeletedEvents(ctx context.Context, filter storage.EventFilter) ([]storage.Event, error) {
+	query, args := filterEvents("select "+eventColumns+" from events where deleted_at is not null", nil, filter)
+
+	return s.selectEvents(ctx, s.db.Rebind(query+"order by deleted_at desc, id"), args...)
+}
+
+// SearchEvents ranks events with the full-text index of the database, titles weigh more than descriptions.
+func (s *Storage) SearchEvents(ctx context.Context, query storage.SearchQuery) ([]storage.Event, error) {
+	terms := storage.SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return []storage.Event{}, nil
+	}
+
+	var (
+		q, rank string
+		args    []interface{}
+	)
+
+	if s.db.DriverName() == SQLiteDriver {
+		prefixes := make([]string, 0, len(terms))
+
+		// terms are made of letters and digits only, so quoting them is enough to escape the syntax.
+		for _, t := range terms {
+			prefixes = append(prefixes, `"`+t+`"*`)
+		}
+
+		q = `
+			with matches as (
+				select event_id, bm25(events_search, 0, 10, 1) as score
+				from events_search where events_search match ?
+			)
+			select ` + eventColumns + ` from events
+			join matches on matches.event_id = events.id
+			where deleted_at is null
+		`
+		// bm25 is lower for better matches.
+		rank = "matches.score"
+		args = []interface{}{strings.Join(prefixes, " ")}
+	} else {
+		prefixes := make([]string, 0, len(terms))
+
+		for _, t := range terms {
+			prefixes = append(prefixes, t+":*")
+		}
+
+		q = `
+			with search_query as (select to_tsquery('simple', ?) as terms)
+			select ` + eventColumns + ` from events, search_query
+			where search @@ search_query.terms and deleted_at is null
+		`
+		rank = "ts_rank(search, search_query.terms) desc"
+		args = []interface{}{strings.Join(prefixes, " & ")}
+	}
+
+	if !query.From.IsZero() {
+		q += "and starts_at >= ?\n"
+		args = append(args, query.From.UTC())
+	}
+
+	if !query.To.IsZero() {
+		q += "and starts_at < ?\n"
+		args = append(args, query.To.UTC())
+	}
+
+	q, args = filterEvents(q, args, query.Filter)
+	q += "order by " + rank + ", starts_at, id limit ?"
+
+	return s.selectEvents(ctx, s.db.Rebind(q), append(args, query.Limit)...)
+}
+
+func (s *Storage) ListDayEvents(
+	ctx context.Context, date time.Time, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.DayRange(date)
+
+	return s.listEventsBetween(ctx, from, to, filter)
+}
+
+func (s *Storage) ListWeekEvents(
+	ctx context.Context, date time.Time, firstDay time.Weekday, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.WeekRange(date, firstDay)
+
+	return s.listEventsBetween(ctx, from, to, filter)
+}
+
+func (s *Storage) ListMonthEvents(
+	ctx context.Context, date time.Time, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	from, to := storage.MonthRange(date)
+
+	return s.listEventsBetween(ctx, from, to, filter)
+}
+
+func (s *Storage) GetUserSettings(ctx context.Context, userID string) (storage.UserSettings, error) {
+	var settings storage.UserSettings
+
+	err := s.db.GetContext(ctx, &settings, s.db.Rebind("select * from user_settings where user_id=?"), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, storage.ErrUserSettingsNotFound
+	}
+
+	return settings, err
+}
+
+func (s *Storage) SaveUserSettings(ctx context.Context, settings storage.UserSettings) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.NamedExecContext(ctx, `
+		insert into user_settings (
+			user_id, time_zone, first_day_of_week
+		) values (
+			:user_id, :time_zone, :first_day_of_week
+		)
+		on conflict (user_id) do update
+		set time_zone=excluded.time_zone, first_day_of_week=excluded.first_day_of_week
+	`, &settings)
+	if err != nil {
+		return err
+	}
+
+	// lists are grouped by days of the user time zone.
+	if err := markLists(ctx, tx, []string{storage.UserScope(settings.UserID)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveAttendee invites the attendee or updates the role and email of the invited one, keeping their response.
+func (s *Storage) SaveAttendee(ctx context.Context, attendee storage.Attendee) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	// selecting the row from the event makes a missing event distinguishable from any other failure.
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		insert into attendees (
+			`+attendeeColumns+`
+		)
+		select id, ?, ?, ?, ? from events where id=? and deleted_at is null
+		on conflict (event_id, user_id) do update
+		set email=excluded.email, role=excluded.role
+	`), attendee.UserID, attendee.Email, attendee.Role, attendee.Status, attendee.EventID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrEventNotFound); err != nil {
+		return err
+	}
+
+	if err := markEventLists(ctx, tx, attendee.EventID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) SetAttendeeStatus(ctx context.Context, eventID, userID string, status storage.AttendeeStatus) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		update attendees set status=? where event_id=? and user_id=?
+	`), status, eventID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrAttendeeNotFound); err != nil {
+		return err
+	}
+
+	if err := markEventLists(ctx, tx, eventID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) RemoveAttendee(ctx context.Context, eventID, userID string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	// scopes are taken before the removal, so the list of the removed attendee is marked as well.
+	scopes, err := eventScopes(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, tx.Rebind("delete from attendees where event_id=? and user_id=?"), eventID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrAttendeeNotFound); err != nil {
+		return err
+	}
+
+	if err := markLists(ctx, tx, scopes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) GetAttendee(ctx context.Context, eventID, userID string) (storage.Attendee, error) {
+	var attendee storage.Attendee
+
+	err := s.db.GetContext(ctx, &attendee, s.db.Rebind(`
+		select `+attendeeColumns+` from attendees where event_id=? and user_id=?
+	`), eventID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return attendee, storage.ErrAttendeeNotFound
+	}
+
+	return attendee, err
+}
 
-	if err := s.db.SelectContext(ctx, &events, "select * from events where starts_at between ? and ?", from, to); err != nil {
+// ListAttendees returns attendees of the event ordered by user id.
+func (s *Storage) ListAttendees(ctx context.Context, eventID string) ([]storage.Attendee, error) {
+	return listAttendees(ctx, s.db, eventID)
+}
+
+func listAttendees(ctx context.Context, db sqlx.ExtContext, eventID string) ([]storage.Attendee, error) {
+	attendees := []storage.Attendee{}
+
+	err := sqlx.SelectContext(ctx, db, &attendees, db.Rebind(`
+		select `+attendeeColumns+` from attendees where event_id=? order by user_id
+	`), eventID)
+	if err != nil {
 		return nil, err
 	}
 
-	return events, nil
+	return attendees, nil
+}
+
+// listEventsBetween returns events overlapping [from, to) ordered by start time,
+// all-day ones are matched by dates the period covers rather than by its time.
+func (s *Storage) listEventsBetween(
+	ctx context.Context, from, to time.Time, filter storage.EventFilter,
+) ([]storage.Event, error) {
+	fromDate, toDate := storage.DatesRange(from, to)
+
+	query, args := filterEvents(`
+		select `+eventColumns+` from events
+		where deleted_at is null and (
+			not all_day and starts_at < ? and (ends_at > ? or starts_at >= ?)
+			or all_day and starts_at < ? and ends_at > ?
+		)
+	`, []interface{}{to.UTC(), from.UTC(), from.UTC(), toDate, fromDate}, filter)
+
+	return s.selectEvents(ctx, s.db.Rebind(query+"order by starts_at, id"), args...)
+}
+
+// filterEvents narrows the query down by the filter, the query is expected to end with a where clause.
+func filterEvents(query string, args []interface{}, filter storage.EventFilter) (string, []interface{}) {
+	query += "\n"
+
+	if filter.UserID != "" {
+		query += `
+			and (owner_id = ? or exists (
+				select 1 from attendees where attendees.event_id = events.id and attendees.user_id = ?
+			) or calendar_id in (
+				select id from calendars where owner_id = ?
+				union all
+				select calendar_id from calendar_shares where user_id = ? and access <> ?
+			))
+		`
+		args = append(args, filter.UserID, filter.UserID, filter.UserID, filter.UserID, storage.AccessFreeBusy)
+	}
+
+	if filter.CalendarID != "" {
+		query += "and calendar_id = ?\n"
+		args = append(args, filter.CalendarID)
+	}
+
+	if len(filter.Tags) > 0 {
+		// tags of an event are unique, so having all of the tags is having as many of them as there are.
+		op, want := ">", 0
+		if filter.TagsMatch == storage.TagsAll {
+			op, want = "=", len(filter.Tags)
+		}
+
+		query += "and (select count(*) from event_tags where event_tags.event_id = events.id and tag in (" +
+			strings.TrimSuffix(strings.Repeat("?, ", len(filter.Tags)), ", ") + ")) " + op + " ?\n"
+
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+
+		args = append(args, want)
+	}
+
+	return query, args
+}
+
+// CreateReminder adds the reminder to the event, when it fires is derived from the event start.
+func (s *Storage) CreateReminder(ctx context.Context, reminder storage.Reminder) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	var event storage.Event
+
+	err = tx.GetContext(ctx, &event, tx.Rebind(`
+		select `+eventColumns+` from events where id=? and deleted_at is null
+	`), reminder.EventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrEventNotFound
+	} else if err != nil {
+		return err
+	}
+
+	reminder.RemindAt = storage.RemindAt(event, reminder)
+
+	_, err = tx.NamedExecContext(ctx, `
+		insert into reminders (
+			`+reminderColumns+`
+		) values (
+			:id, :event_id, :remind_offset, :channel, :message, :remind_at, :fired_at
+		)
+	`, &reminder)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) DeleteReminder(ctx context.Context, eventID, id string) error {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("delete from reminders where event_id=? and id=?"), eventID, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrReminderNotFound)
+}
+
+// ListReminders returns reminders of the event in the order they fire.
+func (s *Storage) ListReminders(ctx context.Context, eventID string) ([]storage.Reminder, error) {
+	return s.selectReminders(ctx, s.db.Rebind(`
+		select `+reminderColumns+` from reminders where event_id=? order by remind_at, id
+	`), eventID)
+}
+
+// ListDueReminders returns reminders due within [from, to) which haven't fired yet in the order they fire.
+// Reminders of events in the trash are left out.
+func (s *Storage) ListDueReminders(ctx context.Context, from, to time.Time) ([]storage.Reminder, error) {
+	return s.selectReminders(ctx, s.db.Rebind(`
+		select `+reminderColumns+` from reminders
+		where remind_at >= ? and remind_at < ? and fired_at is null
+			and event_id in (select id from events where deleted_at is null)
+		order by remind_at, id
+	`), from.UTC(), to.UTC())
+}
+
+func (s *Storage) MarkReminderFired(ctx context.Context, id string, firedAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("update reminders set fired_at=? where id=?"), firedAt.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrReminderNotFound)
+}
+
+// FireReminder marks the reminder fired and writes its notifications along with outbox messages publishing them
+// in one transaction, so they are published once the reminder is not due anymore and never otherwise.
+func (s *Storage) FireReminder(
+	ctx context.Context, id string, firedAt time.Time,
+	notifications []storage.Notification, messages []storage.OutboxMessage,
+) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind("update reminders set fired_at=? where id=?"), firedAt.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrReminderNotFound); err != nil {
+		return err
+	}
+
+	if err := insertNotifications(ctx, tx, notifications...); err != nil {
+		return err
+	}
+
+	if err := insertOutbox(ctx, tx, messages...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertNotifications(ctx context.Context, tx *sqlx.Tx, notifications ...storage.Notification) error {
+	for i := range notifications {
+		notification := notifications[i]
+		normalizeNotification(&notification)
+
+		_, err := tx.NamedExecContext(ctx, `
+			insert into notifications (
+				`+notificationColumns+`
+			) values (
+				:id, :event_id, :reminder_id, :user_id, :channel, :scheduled_at, :status, :attempts,
+				:last_error, :payload, :created_at, :sent_at
+			)
+		`, &notification)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func insertOutbox(ctx context.Context, tx *sqlx.Tx, messages ...storage.OutboxMessage) error {
+	for i := range messages {
+		message := messages[i]
+		normalizeOutboxMessage(&message)
+
+		_, err := tx.NamedExecContext(ctx, `
+			insert into outbox (
+				`+outboxColumns+`
+			) values (
+				:id, :payload, :created_at, :sent_at
+			)
+		`, &message)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListPendingOutbox returns up to limit messages which haven't been sent yet in the order they were written.
+func (s *Storage) ListPendingOutbox(ctx context.Context, limit int) ([]storage.OutboxMessage, error) {
+	messages := []storage.OutboxMessage{}
+
+	err := s.db.SelectContext(ctx, &messages, s.db.Rebind(`
+		select `+outboxColumns+` from outbox where sent_at is null order by created_at, id limit ?
+	`), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range messages {
+		normalizeOutboxMessage(&messages[i])
+	}
+
+	return messages, nil
+}
+
+func (s *Storage) MarkOutboxSent(ctx context.Context, id string, sentAt time.Time) error {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("update outbox set sent_at=? where id=?"), sentAt.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrOutboxMessageNotFound)
+}
+
+// PurgeSentOutbox deletes messages sent before the time and returns how many there were.
+func (s *Storage) PurgeSentOutbox(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("delete from outbox where sent_at < ?"), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := res.RowsAffected()
+
+	return int(purged), err
+}
+
+func (s *Storage) selectReminders(ctx context.Context, query string, args ...interface{}) ([]storage.Reminder, error) {
+	reminders := []storage.Reminder{}
+
+	if err := s.db.SelectContext(ctx, &reminders, query, args...); err != nil {
+		return nil, err
+	}
+
+	for i := range reminders {
+		reminders[i].RemindAt = reminders[i].RemindAt.UTC()
+
+		if firedAt := reminders[i].FiredAt; firedAt != nil {
+			*firedAt = firedAt.UTC()
+		}
+	}
+
+	return reminders, nil
+}
+
+// ListBusyIntervals returns intervals of events overlapping [from, to) the users own or attend,
+// unless they declined, ordered by user and start time. Events without duration take no time.
+// All-day events are matched a day around the range, their dates fall on other times in time zones of owners.
+func (s *Storage) ListBusyIntervals(
+	ctx context.Context, userIDs []string, from, to time.Time,
+) ([]storage.BusyInterval, error) {
+	intervals := []storage.BusyInterval{}
+
+	if len(userIDs) == 0 {
+		return intervals, nil
+	}
+
+	fromDate, toDate := storage.AllDayRange(from, to)
+
+	// both parts are served by indexes on the user first: events by owner and start, attendees by user.
+	query, args, err := sqlx.In(`
+		select owner_id as user_id, starts_at, ends_at, owner_id, all_day from events
+		where owner_id in (?) and ends_at > starts_at and deleted_at is null and (
+			not all_day and starts_at < ? and ends_at > ?
+			or all_day and starts_at < ? and ends_at > ?
+		)
+		union all
+		select attendees.user_id, events.starts_at, events.ends_at, events.owner_id, events.all_day from attendees
+		join events on events.id = attendees.event_id
+		where attendees.user_id in (?) and attendees.status <> ?
+			and events.ends_at > events.starts_at and events.deleted_at is null and (
+				not events.all_day and events.starts_at < ? and events.ends_at > ?
+				or events.all_day and events.starts_at < ? and events.ends_at > ?
+			)
+		order by user_id, starts_at
+	`, userIDs, to.UTC(), from.UTC(), toDate, fromDate,
+		userIDs, storage.StatusDeclined, to.UTC(), from.UTC(), toDate, fromDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.SelectContext(ctx, &intervals, s.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	for i := range intervals {
+		intervals[i].Start = intervals[i].Start.UTC()
+		intervals[i].End = intervals[i].End.UTC()
+	}
+
+	return intervals, nil
+}
+
+func (s *Storage) CreateCalendar(ctx context.Context, calendar storage.Calendar) error {
+	_, err := s.db.NamedExecContext(ctx, `
+		insert into calendars (
+			`+calendarColumns+`
+		) values (
+			:id, :owner_id, :name, :color, :time_zone
+		)
+	`, &calendar)
+
+	return err
+}
+
+func (s *Storage) UpdateCalendar(ctx context.Context, calendar storage.Calendar) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.NamedExecContext(ctx, `
+		update calendars set owner_id=:owner_id, name=:name, color=:color, time_zone=:time_zone where id=:id
+	`, &calendar)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrCalendarNotFound); err != nil {
+		return err
+	}
+
+	// lists of the calendar are grouped by days of its time zone.
+	if err := markLists(ctx, tx, []string{storage.CalendarScope(calendar.ID)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCalendar deletes the calendar along with its shares and moves its events to the trash,
+// the log of every trashed event is written along. It returns events as they were before the deletion.
+func (s *Storage) DeleteCalendar(
+	ctx context.Context, id string, logTrashed storage.LogTrashed,
+) ([]storage.Event, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	events, err := selectEvents(ctx, tx, tx.Rebind(`
+		select `+eventColumns+` from events where calendar_id=? and deleted_at is null order by starts_at, id
+	`), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(`
+		update events set deleted_at=? where calendar_id=? and deleted_at is null
+	`), time.Now().UTC(), id); err != nil {
+		return nil, err
+	}
+
+	// scopes are taken before the calendar and its shares are gone.
+	scopes, err := calendarScopes(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		listed, err := eventScopes(ctx, tx, event.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		scopes = append(scopes, listed...)
+
+		attendees, err := listAttendees(ctx, tx, event.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		log, err := logTrashed(event, attendees)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeChangeLog(ctx, tx, log); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, tx.Rebind("delete from calendars where id=?"), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAffected(res, storage.ErrCalendarNotFound); err != nil {
+		return nil, err
+	}
+
+	if err := markLists(ctx, tx, scopes); err != nil {
+		return nil, err
+	}
+
+	return events, tx.Commit()
+}
+
+func (s *Storage) GetCalendar(ctx context.Context, id string) (storage.Calendar, error) {
+	var calendar storage.Calendar
+
+	err := s.db.GetContext(ctx, &calendar, s.db.Rebind("select "+calendarColumns+" from calendars where id=?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return calendar, storage.ErrCalendarNotFound
+	}
+
+	return calendar, err
+}
+
+// ListCalendars returns calendars the user owns or has been shared ordered by name.
+func (s *Storage) ListCalendars(ctx context.Context, userID string) ([]storage.Calendar, error) {
+	calendars := []storage.Calendar{}
+
+	err := s.db.SelectContext(ctx, &calendars, s.db.Rebind(`
+		select `+calendarColumns+` from calendars
+		where owner_id = ? or id in (select calendar_id from calendar_shares where user_id = ?)
+		order by name, id
+	`), userID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return calendars, nil
+}
+
+// SaveShare shares the calendar with the user or changes the access they were given.
+func (s *Storage) SaveShare(ctx context.Context, share storage.CalendarShare) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		insert into calendar_shares (
+			`+shareColumns+`
+		)
+		select id, ?, ? from calendars where id=?
+		on conflict (calendar_id, user_id) do update
+		set access=excluded.access
+	`), share.UserID, share.Access, share.CalendarID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrCalendarNotFound); err != nil {
+		return err
+	}
+
+	if err := markLists(ctx, tx, []string{storage.UserScope(share.UserID)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) RemoveShare(ctx context.Context, calendarID, userID string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`
+		delete from calendar_shares where calendar_id=? and user_id=?
+	`), calendarID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrShareNotFound); err != nil {
+		return err
+	}
+
+	if err := markLists(ctx, tx, []string{storage.UserScope(userID)}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) GetShare(ctx context.Context, calendarID, userID string) (storage.CalendarShare, error) {
+	var share storage.CalendarShare
+
+	err := s.db.GetContext(ctx, &share, s.db.Rebind(`
+		select `+shareColumns+` from calendar_shares where calendar_id=? and user_id=?
+	`), calendarID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return share, storage.ErrShareNotFound
+	}
+
+	return share, err
+}
+
+// ListShares returns shares of the calendar ordered by user id.
+func (s *Storage) ListShares(ctx context.Context, calendarID string) ([]storage.CalendarShare, error) {
+	shares := []storage.CalendarShare{}
+
+	err := s.db.SelectContext(ctx, &shares, s.db.Rebind(`
+		select `+shareColumns+` from calendar_shares where calendar_id=? order by user_id
+	`), calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (s *Storage) AppendAuditRecord(ctx context.Context, record storage.AuditRecord) error {
+	return insertAuditRecord(ctx, s.db, record)
+}
+
+func insertAuditRecord(ctx context.Context, db sqlx.ExtContext, record storage.AuditRecord) error {
+	record.CreatedAt = record.CreatedAt.UTC()
+
+	_, err := sqlx.NamedExecContext(ctx, db, `
+		insert into audit_records (
+			`+auditColumns+`
+		) values (
+			:id, :event_id, :actor_id, :operation, :created_at, :changes
+		)
+	`, &record)
+
+	return err
+}
+
+// writeChangeLog writes the log within the transaction making the change.
+func writeChangeLog(ctx context.Context, tx *sqlx.Tx, log storage.ChangeLog) error {
+	for _, record := range log.Audit {
+		if err := insertAuditRecord(ctx, tx, record); err != nil {
+			return err
+		}
+	}
+
+	if err := insertNotifications(ctx, tx, log.Notifications...); err != nil {
+		return err
+	}
+
+	return insertOutbox(ctx, tx, log.Outbox...)
+}
+
+// ListAuditRecords returns the history of the event, the oldest record first.
+func (s *Storage) ListAuditRecords(ctx context.Context, eventID string) ([]storage.AuditRecord, error) {
+	records := []storage.AuditRecord{}
+
+	err := s.db.SelectContext(ctx, &records, s.db.Rebind(`
+		select `+auditColumns+` from audit_records where event_id=? order by created_at, id
+	`), eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		records[i].CreatedAt = records[i].CreatedAt.UTC()
+	}
+
+	return records, nil
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	_, err := s.db.NamedExecContext(ctx, `
+		insert into webhooks (
+			`+webhookColumns+`
+		) values (
+			:id, :owner_id, :url, :secret, :operations
+		)
+	`, &webhook)
+
+	return err
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("delete from webhooks where id=?"), id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrWebhookNotFound)
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	var webhook storage.Webhook
+
+	err := s.db.GetContext(ctx, &webhook, s.db.Rebind("select "+webhookColumns+" from webhooks where id=?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook, storage.ErrWebhookNotFound
+	}
+
+	return webhook, err
+}
+
+// ListWebhooks returns webhooks of the user ordered by URL.
+func (s *Storage) ListWebhooks(ctx context.Context, ownerID string) ([]storage.Webhook, error) {
+	webhooks := []storage.Webhook{}
+
+	err := s.db.SelectContext(ctx, &webhooks, s.db.Rebind(`
+		select `+webhookColumns+` from webhooks where owner_id=? order by url, id
+	`), ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) CreateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	normalizeDelivery(&delivery)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	var exists bool
+
+	err = tx.GetContext(ctx, &exists, tx.Rebind("select true from webhooks where id=?"), delivery.WebhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrWebhookNotFound
+	} else if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExecContext(ctx, `
+		insert into webhook_deliveries (
+			`+deliveryColumns+`
+		) values (
+			:id, :webhook_id, :event_id, :operation, :payload, :status, :attempts,
+			:next_attempt_at, :last_error, :created_at, :delivered_at
+		)
+	`, &delivery)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateWebhookDelivery saves the outcome of an attempt, the payload is never changed.
+func (s *Storage) UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	normalizeDelivery(&delivery)
+
+	res, err := s.db.NamedExecContext(ctx, `
+		update webhook_deliveries set
+			status=:status, attempts=:attempts, next_attempt_at=:next_attempt_at,
+			last_error=:last_error, delivered_at=:delivered_at
+		where id=:id
+	`, &delivery)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrDeliveryNotFound)
+}
+
+// ListDueWebhookDeliveries returns pending deliveries due by the time in the order they are due.
+func (s *Storage) ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]storage.WebhookDelivery, error) {
+	return s.selectDeliveries(ctx, s.db.Rebind(`
+		select `+deliveryColumns+` from webhook_deliveries
+		where status=? and next_attempt_at <= ?
+		order by next_attempt_at, id
+	`), storage.DeliveryPending, now.UTC())
+}
+
+// ListWebhookDeliveries returns deliveries of the webhook, the latest first.
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID string) ([]storage.WebhookDelivery, error) {
+	return s.selectDeliveries(ctx, s.db.Rebind(`
+		select `+deliveryColumns+` from webhook_deliveries
+		where webhook_id=?
+		order by created_at desc, id
+	`), webhookID)
+}
+
+func (s *Storage) selectDeliveries(
+	ctx context.Context, query string, args ...interface{},
+) ([]storage.WebhookDelivery, error) {
+	deliveries := []storage.WebhookDelivery{}
+
+	if err := s.db.SelectContext(ctx, &deliveries, query, args...); err != nil {
+		return nil, err
+	}
+
+	for i := range deliveries {
+		normalizeDelivery(&deliveries[i])
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) GetNotification(ctx context.Context, id string) (storage.Notification, error) {
+	var notification storage.Notification
+
+	err := s.db.GetContext(ctx, &notification, s.db.Rebind(
+		"select "+notificationColumns+" from notifications where id=?",
+	), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notification, storage.ErrNotificationNotFound
+	}
+
+	normalizeNotification(&notification)
+
+	return notification, err
+}
+
+// UpdateNotification saves the outcome of an attempt, the payload is never changed.
+func (s *Storage) UpdateNotification(ctx context.Context, notification storage.Notification) error {
+	normalizeNotification(&notification)
+
+	res, err := s.db.NamedExecContext(ctx, `
+		update notifications set
+			status=:status, attempts=:attempts, last_error=:last_error, sent_at=:sent_at
+		where id=:id
+	`, &notification)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, storage.ErrNotificationNotFound)
+}
+
+// ResendNotification makes the notification pending again and writes the message publishing it to the outbox
+// in one transaction.
+func (s *Storage) ResendNotification(ctx context.Context, id string, message storage.OutboxMessage) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// it's a no-op once the transaction is committed.
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind("update notifications set status=? where id=?"),
+		storage.NotificationPending, id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res, storage.ErrNotificationNotFound); err != nil {
+		return err
+	}
+
+	if err := insertOutbox(ctx, tx, message); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListNotifications returns notifications passing the filter, the latest scheduled first.
+func (s *Storage) ListNotifications(
+	ctx context.Context, filter storage.NotificationFilter,
+) ([]storage.Notification, error) {
+	query := "select " + notificationColumns + " from notifications where true\n"
+
+	var args []interface{}
+
+	if filter.EventID != "" {
+		query += "and event_id = ?\n"
+		args = append(args, filter.EventID)
+	}
+
+	if filter.UserID != "" {
+		query += "and user_id = ?\n"
+		args = append(args, filter.UserID)
+	}
+
+	if filter.Status != "" {
+		query += "and status = ?\n"
+		args = append(args, filter.Status)
+	}
+
+	notifications := []storage.Notification{}
+
+	err := s.db.SelectContext(ctx, &notifications, s.db.Rebind(query+"order by scheduled_at desc, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range notifications {
+		normalizeNotification(&notifications[i])
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) selectEvents(ctx context.Context, query string, args ...interface{}) ([]storage.Event, error) {
+	return selectEvents(ctx, s.db, query, args...)
+}
+
+func selectEvents(ctx context.Context, db sqlx.ExtContext, query string, args ...interface{}) ([]storage.Event, error) {
+	events := []storage.Event{}
+
+	if err := sqlx.SelectContext(ctx, db, &events, query, args...); err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		normalizeEvent(&events[i])
+	}
+
+	if err := loadTags(ctx, db, events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// loadTags fills tags of the events in with a single query.
+func loadTags(ctx context.Context, db sqlx.ExtContext, events []storage.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(events))
+	positions := make(map[string]int, len(events))
+
+	for i, event := range events {
+		ids = append(ids, event.ID)
+		positions[event.ID] = i
+	}
+
+	query, args, err := sqlx.In("select event_id, tag from event_tags where event_id in (?) order by tag", ids)
+	if err != nil {
+		return err
+	}
+
+	var tags []struct {
+		EventID string `db:"event_id"`
+		Tag     string `db:"tag"`
+	}
+
+	if err := sqlx.SelectContext(ctx, db, &tags, db.Rebind(query), args...); err != nil {
+		return err
+	}
+
+	for _, t := range tags {
+		event := &events[positions[t.EventID]]
+		event.Tags = append(event.Tags, t.Tag)
+	}
+
+	return nil
+}
+
+// normalizeEvent brings times read back to UTC, drivers return them in the local time zone.
+func normalizeEvent(event *storage.Event) {
+	event.StartsAt = event.StartsAt.UTC()
+
+	if deletedAt := event.DeletedAt; deletedAt != nil {
+		*deletedAt = deletedAt.UTC()
+	}
+}
+
+func normalizeOutboxMessage(message *storage.OutboxMessage) {
+	message.CreatedAt = message.CreatedAt.UTC()
+
+	if sentAt := message.SentAt; sentAt != nil {
+		*sentAt = sentAt.UTC()
+	}
+}
+
+func normalizeNotification(notification *storage.Notification) {
+	notification.ScheduledAt = notification.ScheduledAt.UTC()
+	notification.CreatedAt = notification.CreatedAt.UTC()
+
+	if sentAt := notification.SentAt; sentAt != nil {
+		*sentAt = sentAt.UTC()
+	}
+}
+
+func normalizeDelivery(delivery *storage.WebhookDelivery) {
+	delivery.NextAttemptAt = delivery.NextAttemptAt.UTC()
+	delivery.CreatedAt = delivery.CreatedAt.UTC()
+
+	if deliveredAt := delivery.DeliveredAt; deliveredAt != nil {
+		*deliveredAt = deliveredAt.UTC()
+	}
+}
+
+// nullString keeps optional references null, so they don't point to a missing row.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func endsAt(event storage.Event) time.Time {
+	return event.StartsAt.Add(event.Duration).UTC()
+}
+
+// checkAffected reports errNoRows when the statement didn't touch any row.
+func checkAffected(res sql.Result, errNoRows error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errNoRows
+	}
+
+	return nil
 }
@@ -0,0 +1,59 @@
+package sqlstorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/migrations"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// postgresDSNEnv points to an empty database to run the suite against postgres too.
+const postgresDSNEnv = "CALENDAR_TEST_POSTGRES_DSN"
+
+func newStorage(t *testing.T, dialect, driver, dsn string) *Storage {
+	t.Helper()
+
+	storage, err := New(driver, dsn)
+	require.NoError(t, err)
+	require.NoError(t, storage.Connect(context.TODO()))
+	require.NoError(t, migrations.Run(storage.db.DB, dialect, "up"))
+
+	return storage
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		dsn := filepath.Join(t.TempDir(), "calendar.db") + "?_pragma=foreign_keys(1)&_time_format=sqlite"
+		storage := newStorage(t, "sqlite3", SQLiteDriver, dsn)
+
+		t.Cleanup(func() {
+			require.NoError(t, storage.Close(context.TODO()))
+		})
+
+		return storage
+	})
+}
+
+func TestPostgresStorage(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	storagetest.Run(t, func(t *testing.T) app.Storage {
+		storage := newStorage(t, "postgres", PostgresDriver, dsn)
+
+		t.Cleanup(func() {
+			require.NoError(t, migrations.Run(storage.db.DB, "postgres", "down-to", "0"))
+			require.NoError(t, storage.Close(context.TODO()))
+		})
+
+		return storage
+	})
+}
@@ -0,0 +1,1912 @@
+// Package storagetest provides a conformance suite every app.Storage implementation must pass.
+package storagetest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata" // time zones must not depend on the host database.
+
+	"github.com/pioz/faker"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// Factory returns a new empty storage, releasing it is up to the factory via t.Cleanup.
+type Factory func(t *testing.T) app.Storage
+
+type StorageSuite struct {
+	suite.Suite
+	newStorage Factory
+	storage    app.Storage
+}
+
+// Run runs the conformance suite against storages created by newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	t.Helper()
+
+	suite.Run(t, &StorageSuite{newStorage: newStorage})
+}
+
+func (s *StorageSuite) BeforeTest(suiteName, testName string) {
+	s.storage = s.newStorage(s.T())
+}
+
+func (s *StorageSuite) createEvents(events ...storage.Event) {
+	for _, e := range events {
+		s.Require().NoError(s.storage.CreateEvent(context.TODO(), e, storage.ChangeLog{}))
+	}
+}
+
+func (s *StorageSuite) TestEmpty() {
+	events, err := s.storage.ListDayEvents(context.TODO(), time.Now(), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), time.Now(), time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+
+	events, err = s.storage.ListMonthEvents(context.TODO(), time.Now(), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+}
+
+func (s *StorageSuite) TestCreate() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	require.NoError(s.T(), s.storage.CreateEvent(context.TODO(), event, storage.ChangeLog{}))
+
+	events, err := s.storage.ListDayEvents(context.TODO(), event.StartsAt, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event}, events)
+}
+
+func (s *StorageSuite) TestCreateExists() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	s.createEvents(event)
+
+	require.ErrorIs(s.T(), s.storage.CreateEvent(context.TODO(), event, storage.ChangeLog{}), storage.ErrEventAlreadyExists)
+}
+
+func (s *StorageSuite) TestGetNotExist() {
+	_, err := s.storage.GetEvent(context.TODO(), faker.UUID())
+	require.ErrorIs(s.T(), err, storage.ErrEventNotFound)
+}
+
+func (s *StorageSuite) TestGet() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, loadLocation(s.T(), "Europe/Moscow")))
+
+	s.createEvents(event)
+
+	actual, err := s.storage.GetEvent(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event}, []storage.Event{actual})
+}
+
+func (s *StorageSuite) TestUpdateNotExist() {
+	event := newEvent(time.Now())
+
+	require.ErrorIs(s.T(), s.storage.UpdateEvent(context.TODO(), event.ID, event, storage.ChangeLog{}), storage.ErrEventNotFound)
+}
+
+func (s *StorageSuite) TestUpdate() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	s.createEvents(event)
+
+	eventUpdate := event
+	eventUpdate.Title = faker.StringWithSize(20)
+	eventUpdate.Description = faker.String()
+	eventUpdate.StartsAt = event.StartsAt.AddDate(0, 0, 1)
+	eventUpdate.Duration = 2 * time.Hour
+	eventUpdate.OwnerID = faker.UUID()
+
+	require.NoError(s.T(), s.storage.UpdateEvent(context.TODO(), event.ID, eventUpdate, storage.ChangeLog{}))
+
+	events, err := s.storage.ListDayEvents(context.TODO(), event.StartsAt, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+
+	events, err = s.storage.ListDayEvents(context.TODO(), eventUpdate.StartsAt, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{eventUpdate}, events)
+}
+
+func (s *StorageSuite) TestDeleteNotExist() {
+	require.ErrorIs(s.T(), s.storage.DeleteEvent(context.TODO(), faker.UUID(), storage.ChangeLog{}), storage.ErrEventNotFound)
+}
+
+func (s *StorageSuite) TestDelete() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	s.createEvents(event)
+
+	require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+	require.ErrorIs(s.T(), s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}), storage.ErrEventNotFound)
+
+	events, err := s.storage.ListDayEvents(context.TODO(), event.StartsAt, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+}
+
+func (s *StorageSuite) TestTrash() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	userID := faker.UUID()
+	event := newEvent(date.Add(time.Hour))
+	event.OwnerID = userID
+	other := newEvent(date.Add(2 * time.Hour))
+	reminder := newReminder(event.ID, 15*time.Minute)
+
+	s.createEvents(event, other)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(event.ID, faker.UUID())))
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), reminder))
+
+	_, err := s.storage.GetDeletedEvent(context.TODO(), event.ID)
+	require.ErrorIs(s.T(), err, storage.ErrEventNotFound)
+	require.ErrorIs(s.T(), s.storage.RestoreEvent(context.TODO(), event.ID, storage.ChangeLog{}), storage.ErrEventNotFound)
+
+	before := time.Now()
+
+	require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+
+	// trashed events are gone for everything but the trash.
+	_, err = s.storage.GetEvent(context.TODO(), event.ID)
+	require.ErrorIs(s.T(), err, storage.ErrEventNotFound)
+	require.ErrorIs(s.T(), s.storage.UpdateEvent(context.TODO(), event.ID, event, storage.ChangeLog{}), storage.ErrEventNotFound)
+	require.ErrorIs(s.T(),
+		s.storage.SaveAttendee(context.TODO(), newAttendee(event.ID, faker.UUID())), storage.ErrEventNotFound)
+	require.ErrorIs(s.T(),
+		s.storage.CreateReminder(context.TODO(), newReminder(event.ID, time.Minute)), storage.ErrEventNotFound)
+	require.ErrorIs(s.T(), s.storage.CreateEvent(context.TODO(), event, storage.ChangeLog{}), storage.ErrEventAlreadyExists)
+
+	events, err := s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{other}, events)
+
+	intervals, err := s.storage.ListBusyIntervals(context.TODO(), []string{userID}, date, date.AddDate(0, 0, 1))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), intervals, 0)
+
+	s.requireDueReminders(date, date.AddDate(0, 0, 1))
+
+	trashed, err := s.storage.GetDeletedEvent(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), trashed.DeletedAt)
+	require.False(s.T(), trashed.DeletedAt.Before(before.Truncate(time.Second)))
+
+	deleted, err := s.storage.ListDeletedEvents(context.TODO(), storage.EventFilter{UserID: userID})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deleted, 1)
+	require.Equal(s.T(), event.ID, deleted[0].ID)
+
+	// an empty trash is listed as an empty slice, so both storages encode it the same way.
+	deleted, err = s.storage.ListDeletedEvents(context.TODO(), storage.EventFilter{UserID: faker.UUID()})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Event{}, deleted)
+
+	// restored events come back along with attendees and reminders.
+	require.NoError(s.T(), s.storage.RestoreEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+	require.ErrorIs(s.T(), s.storage.RestoreEvent(context.TODO(), event.ID, storage.ChangeLog{}), storage.ErrEventNotFound)
+
+	restored, err := s.storage.GetEvent(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event}, []storage.Event{restored})
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), attendees, 1)
+
+	s.requireDueReminders(date, date.AddDate(0, 0, 1), reminder)
+
+	deleted, err = s.storage.ListDeletedEvents(context.TODO(), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deleted, 0)
+}
+
+func (s *StorageSuite) TestPurgeDeletedEvents() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	first := newEvent(date.Add(time.Hour))
+	second := newEvent(date.Add(2 * time.Hour))
+	live := newEvent(date.Add(3 * time.Hour))
+
+	s.createEvents(first, second, live)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(first.ID, faker.UUID())))
+
+	before := time.Now().Add(-time.Minute)
+
+	for _, e := range []storage.Event{first, second} {
+		s.Require().NoError(s.storage.DeleteEvent(context.TODO(), e.ID, storage.ChangeLog{}))
+	}
+
+	// events trashed after the time are kept.
+	purged, err := s.storage.PurgeDeletedEvents(context.TODO(), before)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 0, purged)
+
+	purged, err = s.storage.PurgeDeletedEvents(context.TODO(), time.Now().Add(time.Minute))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 2, purged)
+
+	deleted, err := s.storage.ListDeletedEvents(context.TODO(), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deleted, 0)
+
+	require.ErrorIs(s.T(), s.storage.RestoreEvent(context.TODO(), first.ID, storage.ChangeLog{}), storage.ErrEventNotFound)
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), first.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Attendee{}, attendees)
+
+	events, err := s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{live}, events)
+}
+
+// purgeDeletedEvents empties the trash.
+func (s *StorageSuite) purgeDeletedEvents() {
+	s.T().Helper()
+
+	_, err := s.storage.PurgeDeletedEvents(context.TODO(), time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+}
+
+func (s *StorageSuite) TestList() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	event1 := newEvent(date.Add(90 * time.Minute))
+	event2 := newEvent(date.AddDate(0, 0, 1))
+
+	s.createEvents(event2, event1)
+
+	events, err := s.storage.ListMonthEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event1, event2}, events)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), date, time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event1}, events)
+
+	events, err = s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event1}, events)
+
+	nextMonthDate := date.AddDate(0, 1, 0)
+
+	events, err = s.storage.ListDayEvents(context.TODO(), nextMonthDate, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), nextMonthDate, time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+
+	events, err = s.storage.ListMonthEvents(context.TODO(), nextMonthDate, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+}
+
+func (s *StorageSuite) TestListOrder() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	expected := make([]storage.Event, 0, 10)
+
+	for i := 0; i < cap(expected); i++ {
+		expected = append(expected, newEvent(date.Add(time.Duration(i)*time.Hour)))
+	}
+
+	for _, i := range rand.Perm(len(expected)) {
+		s.createEvents(expected[i])
+	}
+
+	events, err := s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), expected, events)
+}
+
+func (s *StorageSuite) TestRangeBoundaries() {
+	tests := []struct {
+		name     string
+		list     func(ctx context.Context, date time.Time) ([]storage.Event, error)
+		date     time.Time
+		from, to time.Time
+	}{
+		{
+			"day", s.listDayEvents,
+			time.Date(2021, 6, 20, 15, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"week", s.listWeekEvents(time.Monday),
+			time.Date(2021, 6, 20, 15, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 14, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"week from sunday", s.listWeekEvents(time.Sunday),
+			time.Date(2021, 6, 20, 15, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 27, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"week from saturday", s.listWeekEvents(time.Saturday),
+			time.Date(2021, 6, 18, 15, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 12, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"month", s.listMonthEvents,
+			time.Date(2021, 6, 20, 15, 0, 0, 0, time.UTC),
+			time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			atStart := newEvent(tt.from)
+			beforeEnd := newEvent(tt.to.Add(-time.Second))
+			// ends right when the period starts.
+			beforeStart := newEvent(tt.from.Add(-time.Second))
+			beforeStart.Duration = time.Second
+			atEnd := newEvent(tt.to)
+
+			s.createEvents(atStart, beforeEnd, beforeStart, atEnd)
+
+			events, err := tt.list(context.TODO(), tt.date)
+			require.NoError(s.T(), err)
+			requireEvents(s.T(), []storage.Event{atStart, beforeEnd}, events)
+
+			for _, e := range []storage.Event{atStart, beforeEnd, beforeStart, atEnd} {
+				s.Require().NoError(s.storage.DeleteEvent(context.TODO(), e.ID, storage.ChangeLog{}))
+			}
+		})
+	}
+}
+
+func (s *StorageSuite) listDayEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
+	return s.storage.ListDayEvents(ctx, date, storage.EventFilter{})
+}
+
+func (s *StorageSuite) listWeekEvents(firstDay time.Weekday) func(context.Context, time.Time) ([]storage.Event, error) {
+	return func(ctx context.Context, date time.Time) ([]storage.Event, error) {
+		return s.storage.ListWeekEvents(ctx, date, firstDay, storage.EventFilter{})
+	}
+}
+
+func (s *StorageSuite) listMonthEvents(ctx context.Context, date time.Time) ([]storage.Event, error) {
+	return s.storage.ListMonthEvents(ctx, date, storage.EventFilter{})
+}
+
+func (s *StorageSuite) TestTimeZones() {
+	moscow := loadLocation(s.T(), "Europe/Moscow")
+	event := newEvent(time.Date(2021, 6, 20, 22, 0, 0, 0, time.UTC))
+
+	s.createEvents(event)
+
+	tests := []struct {
+		date     time.Time
+		expected []storage.Event
+	}{
+		{time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC), []storage.Event{event}},
+		{time.Date(2021, 6, 20, 12, 0, 0, 0, moscow), nil},
+		{time.Date(2021, 6, 21, 12, 0, 0, 0, moscow), []storage.Event{event}},
+	}
+
+	for _, tt := range tests {
+		events, err := s.storage.ListDayEvents(context.TODO(), tt.date, storage.EventFilter{})
+		require.NoError(s.T(), err)
+		requireEvents(s.T(), tt.expected, events)
+	}
+
+	// 2021-06-20 is Sunday in UTC but already Monday in Moscow.
+	events, err := s.storage.ListWeekEvents(context.TODO(), time.Date(2021, 6, 24, 0, 0, 0, 0, moscow), time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{event}, events)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), time.Date(2021, 6, 24, 0, 0, 0, 0, time.UTC), time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+}
+
+func (s *StorageSuite) TestMultiDayEvents() {
+	// a conference from Friday evening to Tuesday noon.
+	conference := newEvent(time.Date(2021, 6, 18, 18, 0, 0, 0, time.UTC))
+	conference.Duration = 3*24*time.Hour + 18*time.Hour
+	overnight := newEvent(time.Date(2021, 6, 30, 23, 0, 0, 0, time.UTC))
+	overnight.Duration = 2 * time.Hour
+	instant := newEvent(time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC))
+	instant.Duration = 0
+
+	s.createEvents(conference, overnight, instant)
+
+	tests := []struct {
+		name     string
+		list     func(ctx context.Context, date time.Time) ([]storage.Event, error)
+		date     time.Time
+		expected []storage.Event
+	}{
+		{"first day", s.listDayEvents, time.Date(2021, 6, 18, 12, 0, 0, 0, time.UTC), []storage.Event{conference}},
+		{"day within", s.listDayEvents, time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC), []storage.Event{conference}},
+		{
+			"last day", s.listDayEvents, time.Date(2021, 6, 22, 12, 0, 0, 0, time.UTC),
+			[]storage.Event{conference},
+		},
+		{"day after", s.listDayEvents, time.Date(2021, 6, 23, 12, 0, 0, 0, time.UTC), nil},
+		{
+			"next week", s.listWeekEvents(time.Monday), time.Date(2021, 6, 23, 12, 0, 0, 0, time.UTC),
+			[]storage.Event{conference, instant},
+		},
+		{
+			"next month", s.listMonthEvents, time.Date(2021, 7, 15, 12, 0, 0, 0, time.UTC),
+			[]storage.Event{overnight},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			events, err := tt.list(context.TODO(), tt.date)
+			require.NoError(s.T(), err)
+			requireEvents(s.T(), tt.expected, events)
+		})
+	}
+}
+
+func (s *StorageSuite) TestAllDayEvents() {
+	newYork := loadLocation(s.T(), "America/New_York")
+	tokyo := loadLocation(s.T(), "Asia/Tokyo")
+
+	birthday := newEvent(time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC))
+	birthday.AllDay = true
+	birthday.Duration = 24 * time.Hour
+	vacation := newEvent(time.Date(2021, 6, 28, 0, 0, 0, 0, time.UTC))
+	vacation.AllDay = true
+	vacation.Duration = 7 * 24 * time.Hour
+
+	s.createEvents(birthday, vacation)
+
+	actual, err := s.storage.GetEvent(context.TODO(), vacation.ID)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{vacation}, []storage.Event{actual})
+	require.Equal(s.T(), time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC), actual.EndDate())
+
+	tests := []struct {
+		name     string
+		list     func(ctx context.Context, date time.Time) ([]storage.Event, error)
+		date     time.Time
+		expected []storage.Event
+	}{
+		{"the day", s.listDayEvents, time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC), []storage.Event{birthday}},
+		// the same dates are covered whatever the time zone is.
+		{"the day in new york", s.listDayEvents, time.Date(2021, 6, 20, 0, 0, 0, 0, newYork), []storage.Event{birthday}},
+		{"the day in tokyo", s.listDayEvents, time.Date(2021, 6, 20, 23, 0, 0, 0, tokyo), []storage.Event{birthday}},
+		{"the day before in tokyo", s.listDayEvents, time.Date(2021, 6, 19, 23, 0, 0, 0, tokyo), nil},
+		{"the day after in new york", s.listDayEvents, time.Date(2021, 6, 21, 0, 0, 0, 0, newYork), nil},
+		{"vacation end", s.listDayEvents, time.Date(2021, 7, 4, 12, 0, 0, 0, newYork), []storage.Event{vacation}},
+		{"after vacation", s.listDayEvents, time.Date(2021, 7, 5, 1, 0, 0, 0, tokyo), nil},
+		{
+			"week in tokyo", s.listWeekEvents(time.Monday), time.Date(2021, 7, 1, 0, 0, 0, 0, tokyo),
+			[]storage.Event{vacation},
+		},
+		{
+			"month in new york", s.listMonthEvents, time.Date(2021, 6, 1, 0, 0, 0, 0, newYork),
+			[]storage.Event{birthday, vacation},
+		},
+		{
+			"next month in new york", s.listMonthEvents, time.Date(2021, 7, 31, 22, 0, 0, 0, newYork),
+			[]storage.Event{vacation},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			events, err := tt.list(context.TODO(), tt.date)
+			require.NoError(s.T(), err)
+			requireEvents(s.T(), tt.expected, events)
+		})
+	}
+}
+
+func (s *StorageSuite) TestDaylightSavingTime() {
+	berlin := loadLocation(s.T(), "Europe/Berlin")
+
+	// clocks go forward on 2021-03-28 and back on 2021-10-31 in Berlin.
+	springDayStart := newEvent(time.Date(2021, 3, 28, 0, 0, 0, 0, berlin))
+	springDayEnd := newEvent(time.Date(2021, 3, 28, 23, 30, 0, 0, berlin))
+	springNextDay := newEvent(time.Date(2021, 3, 29, 0, 0, 0, 0, berlin))
+	autumnDayEnd := newEvent(time.Date(2021, 10, 31, 23, 30, 0, 0, berlin))
+	autumnNextDay := newEvent(time.Date(2021, 11, 1, 0, 0, 0, 0, berlin))
+	octoberStart := newEvent(time.Date(2021, 10, 1, 0, 0, 0, 0, berlin))
+
+	s.createEvents(springDayStart, springDayEnd, springNextDay, autumnDayEnd, autumnNextDay, octoberStart)
+
+	events, err := s.storage.ListDayEvents(context.TODO(), time.Date(2021, 3, 28, 12, 0, 0, 0, berlin), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{springDayStart, springDayEnd}, events)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), time.Date(2021, 3, 24, 12, 0, 0, 0, berlin), time.Monday, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{springDayStart, springDayEnd}, events)
+
+	events, err = s.storage.ListDayEvents(context.TODO(), time.Date(2021, 10, 31, 12, 0, 0, 0, berlin), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{autumnDayEnd}, events)
+
+	events, err = s.storage.ListMonthEvents(context.TODO(), time.Date(2021, 10, 15, 12, 0, 0, 0, berlin), storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{octoberStart, autumnDayEnd}, events)
+}
+
+func (s *StorageSuite) TestSearchEvents() {
+	date := time.Date(2021, 6, 20, 10, 0, 0, 0, time.UTC)
+	ownerID := faker.UUID()
+
+	standup := newEvent(date)
+	standup.Title = "Team standup"
+	standup.Description = "Daily sync"
+	standup.OwnerID = ownerID
+
+	retro := newEvent(date.AddDate(0, 0, 1))
+	retro.Title = "Retro"
+	retro.Description = "Right after the standup, bring your notes"
+	retro.OwnerID = ownerID
+
+	standups := newEvent(date.Add(-time.Hour))
+	standups.Title = "Standups of the team"
+	standups.Description = ""
+	standups.OwnerID = ownerID
+
+	foreign := newEvent(date)
+	foreign.Title = "Standup"
+
+	trashed := newEvent(date)
+	trashed.Title = "Standup"
+	trashed.OwnerID = ownerID
+
+	s.createEvents(standup, retro, standups, foreign, trashed)
+	s.Require().NoError(s.storage.DeleteEvent(context.TODO(), trashed.ID, storage.ChangeLog{}))
+
+	// zero bounds leave the range open.
+	var open time.Time
+
+	search := func(text string, from, to time.Time, limit int) []storage.Event {
+		events, err := s.storage.SearchEvents(context.TODO(), storage.SearchQuery{
+			Text:   text,
+			From:   from,
+			To:     to,
+			Filter: storage.EventFilter{UserID: ownerID},
+			Limit:  limit,
+		})
+		s.Require().NoError(err)
+
+		return events
+	}
+
+	// titles go before descriptions, how events matching alike are ranked is up to the storage.
+	requireRanked := func(titles, descriptions, actual []storage.Event) {
+		s.Require().Len(actual, len(titles)+len(descriptions))
+		require.ElementsMatch(s.T(), normalize(titles), normalize(actual[:len(titles)]))
+		require.ElementsMatch(s.T(), normalize(descriptions), normalize(actual[len(titles):]))
+	}
+
+	// every word matches as a prefix regardless of the case.
+	requireRanked([]storage.Event{standups, standup}, []storage.Event{retro}, search("STANDUP", open, open, 10))
+	requireRanked([]storage.Event{standups, standup}, []storage.Event{retro}, search("stand", open, open, 10))
+	requireRanked([]storage.Event{standups, standup}, nil, search("team, stand", open, open, 10))
+	requireEvents(s.T(), []storage.Event{retro}, search("notes standup", open, open, 10))
+	requireEvents(s.T(), []storage.Event{standup, retro}, search("standup", date, open, 10))
+	requireEvents(s.T(), []storage.Event{standup}, search("standup", date, open, 1))
+	requireRanked([]storage.Event{standups, standup}, nil, search("standup", open, retro.StartsAt, 10))
+	require.Len(s.T(), search("sync standup retro", open, open, 10), 0)
+	require.Len(s.T(), search(" -- ", open, open, 10), 0)
+
+	events, err := s.storage.SearchEvents(context.TODO(), storage.SearchQuery{Text: "standup", Limit: 10})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 4)
+
+	// the index follows changes of events.
+	standup.Description = "Weekly sync"
+	s.Require().NoError(s.storage.UpdateEvent(context.TODO(), standup.ID, standup, storage.ChangeLog{}))
+	s.Require().NoError(s.storage.RestoreEvent(context.TODO(), trashed.ID, storage.ChangeLog{}))
+
+	require.Len(s.T(), search("daily", open, open, 10), 0)
+	requireEvents(s.T(), []storage.Event{standup}, search("weekly", open, open, 10))
+	requireRanked([]storage.Event{standups, standup, trashed}, []storage.Event{retro},
+		search("standup", open, open, 10))
+}
+
+func (s *StorageSuite) TestRemindersNotExist() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	reminder := newReminder(event.ID, time.Hour)
+
+	require.ErrorIs(s.T(), s.storage.CreateReminder(context.TODO(), reminder), storage.ErrEventNotFound)
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), reminder))
+
+	require.ErrorIs(s.T(), s.storage.DeleteReminder(context.TODO(), event.ID, faker.UUID()), storage.ErrReminderNotFound)
+	require.ErrorIs(s.T(), s.storage.DeleteReminder(context.TODO(), faker.UUID(), reminder.ID), storage.ErrReminderNotFound)
+	require.ErrorIs(s.T(), s.storage.MarkReminderFired(context.TODO(), faker.UUID(), time.Now()), storage.ErrReminderNotFound)
+}
+
+func (s *StorageSuite) TestReminders() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	early := newReminder(event.ID, time.Hour)
+	early.Channel = storage.ChannelWebhook
+	early.Message = "get ready"
+	late := newReminder(event.ID, 15*time.Minute)
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), late))
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), early))
+
+	early.RemindAt = time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC)
+	late.RemindAt = time.Date(2021, 6, 20, 11, 45, 0, 0, time.UTC)
+
+	reminders, err := s.storage.ListReminders(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Reminder{early, late}, reminders)
+
+	firedAt := time.Date(2021, 6, 20, 11, 0, 1, 0, time.UTC)
+	require.NoError(s.T(), s.storage.MarkReminderFired(context.TODO(), early.ID, firedAt))
+	require.NoError(s.T(), s.storage.DeleteReminder(context.TODO(), event.ID, late.ID))
+
+	reminders, err = s.storage.ListReminders(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), reminders, 1)
+	require.NotNil(s.T(), reminders[0].FiredAt)
+	require.True(s.T(), firedAt.Equal(*reminders[0].FiredAt))
+
+	// reminders go away with the event once it's purged from the trash.
+	require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+	s.purgeDeletedEvents()
+
+	reminders, err = s.storage.ListReminders(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), reminders, 0)
+}
+
+func (s *StorageSuite) TestListDueReminders() {
+	date := time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC)
+
+	atFrom := newEvent(date.Add(15 * time.Minute))
+	beforeTo := newEvent(date.Add(time.Hour))
+	beforeFrom := newEvent(date.Add(15*time.Minute - time.Second))
+	atTo := newEvent(date.Add(time.Hour + 15*time.Minute))
+	twice := newEvent(date.Add(30 * time.Minute))
+	silent := newEvent(date.Add(30 * time.Minute))
+
+	s.createEvents(atFrom, beforeTo, beforeFrom, atTo, twice, silent)
+
+	atFromReminder := newReminder(atFrom.ID, 15*time.Minute)
+	beforeToReminder := newReminder(beforeTo.ID, time.Minute+time.Second)
+	atToReminder := newReminder(atTo.ID, 15*time.Minute)
+	// every reminder of an event fires on its own.
+	twiceEarly := newReminder(twice.ID, 20*time.Minute)
+	twiceLate := newReminder(twice.ID, 10*time.Minute)
+
+	for _, r := range []storage.Reminder{
+		atFromReminder, beforeToReminder, newReminder(beforeFrom.ID, 15*time.Minute), atToReminder, twiceEarly, twiceLate,
+	} {
+		s.Require().NoError(s.storage.CreateReminder(context.TODO(), r))
+	}
+
+	s.requireDueReminders(date, date.Add(time.Hour), atFromReminder, twiceEarly, twiceLate, beforeToReminder)
+
+	// fired reminders are not due anymore.
+	require.NoError(s.T(), s.storage.MarkReminderFired(context.TODO(), twiceEarly.ID, date.Add(10*time.Minute)))
+	s.requireDueReminders(date, date.Add(time.Hour), atFromReminder, twiceLate, beforeToReminder)
+
+	// moving the event moves its reminders along and arms fired ones again.
+	twice.StartsAt = twice.StartsAt.Add(2 * time.Hour)
+	require.NoError(s.T(), s.storage.UpdateEvent(context.TODO(), twice.ID, twice, storage.ChangeLog{}))
+	require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), beforeTo.ID, storage.ChangeLog{}))
+
+	s.requireDueReminders(date, date.Add(time.Hour), atFromReminder)
+	s.requireDueReminders(date.Add(time.Hour), date.Add(3*time.Hour), atToReminder, twiceEarly, twiceLate)
+}
+
+func (s *StorageSuite) requireDueReminders(from, to time.Time, expected ...storage.Reminder) {
+	s.T().Helper()
+
+	reminders, err := s.storage.ListDueReminders(context.TODO(), from, to)
+	require.NoError(s.T(), err)
+
+	expectedIDs := make([]string, 0, len(expected))
+	actualIDs := make([]string, 0, len(reminders))
+
+	for _, r := range expected {
+		expectedIDs = append(expectedIDs, r.ID)
+	}
+
+	for _, r := range reminders {
+		actualIDs = append(actualIDs, r.ID)
+	}
+
+	require.Equal(s.T(), expectedIDs, actualIDs)
+}
+
+func (s *StorageSuite) TestUserSettingsNotExist() {
+	_, err := s.storage.GetUserSettings(context.TODO(), faker.UUID())
+	require.ErrorIs(s.T(), err, storage.ErrUserSettingsNotFound)
+}
+
+func (s *StorageSuite) TestSaveUserSettings() {
+	settings := storage.UserSettings{
+		UserID:         faker.UUID(),
+		TimeZone:       "Europe/Moscow",
+		FirstDayOfWeek: time.Sunday,
+	}
+
+	require.NoError(s.T(), s.storage.SaveUserSettings(context.TODO(), settings))
+
+	actual, err := s.storage.GetUserSettings(context.TODO(), settings.UserID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), settings, actual)
+
+	settings.TimeZone = "America/New_York"
+	settings.FirstDayOfWeek = time.Monday
+
+	require.NoError(s.T(), s.storage.SaveUserSettings(context.TODO(), settings))
+
+	actual, err = s.storage.GetUserSettings(context.TODO(), settings.UserID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), settings, actual)
+}
+
+func (s *StorageSuite) TestListUserEvents() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	userID := faker.UUID()
+
+	owned := newEvent(date.Add(time.Hour))
+	owned.OwnerID = userID
+	invited := newEvent(date.Add(2 * time.Hour))
+	other := newEvent(date.Add(3 * time.Hour))
+
+	s.createEvents(owned, invited, other)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(invited.ID, userID)))
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(other.ID, faker.UUID())))
+
+	filter := storage.EventFilter{UserID: userID}
+
+	events, err := s.storage.ListDayEvents(context.TODO(), date, filter)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{owned, invited}, events)
+
+	events, err = s.storage.ListWeekEvents(context.TODO(), date, time.Monday, filter)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{owned, invited}, events)
+
+	events, err = s.storage.ListMonthEvents(context.TODO(), date, filter)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{owned, invited}, events)
+
+	events, err = s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{owned, invited, other}, events)
+
+	s.Require().NoError(s.storage.RemoveAttendee(context.TODO(), invited.ID, userID))
+
+	events, err = s.storage.ListDayEvents(context.TODO(), date, filter)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{owned}, events)
+}
+
+func (s *StorageSuite) TestAttendeesNotExist() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	err := s.storage.SaveAttendee(context.TODO(), newAttendee(event.ID, faker.UUID()))
+	require.ErrorIs(s.T(), err, storage.ErrEventNotFound)
+
+	s.createEvents(event)
+
+	userID := faker.UUID()
+
+	_, err = s.storage.GetAttendee(context.TODO(), event.ID, userID)
+	require.ErrorIs(s.T(), err, storage.ErrAttendeeNotFound)
+
+	err = s.storage.SetAttendeeStatus(context.TODO(), event.ID, userID, storage.StatusAccepted)
+	require.ErrorIs(s.T(), err, storage.ErrAttendeeNotFound)
+
+	err = s.storage.RemoveAttendee(context.TODO(), event.ID, userID)
+	require.ErrorIs(s.T(), err, storage.ErrAttendeeNotFound)
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), attendees, 0)
+}
+
+func (s *StorageSuite) TestAttendees() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	first := newAttendee(event.ID, "00000000-0000-4000-8000-000000000001")
+	second := newAttendee(event.ID, "00000000-0000-4000-8000-000000000002")
+	second.Role = storage.RoleOptional
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), second))
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), first))
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Attendee{first, second}, attendees)
+
+	require.NoError(s.T(), s.storage.SetAttendeeStatus(context.TODO(), event.ID, first.UserID, storage.StatusAccepted))
+
+	// inviting again changes the invitation but keeps the response.
+	first.Email = faker.Email()
+	first.Role = storage.RoleOptional
+	require.NoError(s.T(), s.storage.SaveAttendee(context.TODO(), first))
+
+	first.Status = storage.StatusAccepted
+
+	actual, err := s.storage.GetAttendee(context.TODO(), event.ID, first.UserID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), first, actual)
+
+	require.NoError(s.T(), s.storage.RemoveAttendee(context.TODO(), event.ID, second.UserID))
+
+	attendees, err = s.storage.ListAttendees(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Attendee{first}, attendees)
+}
+
+func (s *StorageSuite) TestDeleteEventAttendees() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(event.ID, faker.UUID())))
+	s.Require().NoError(s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+	s.purgeDeletedEvents()
+
+	// attendees go away along with the event, so they don't come back with an event under the same id.
+	s.createEvents(event)
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), attendees, 0)
+}
+
+func (s *StorageSuite) TestListBusyIntervals() {
+	date := time.Date(2021, 6, 21, 0, 0, 0, 0, time.UTC)
+	from, to := date.Add(8*time.Hour), date.Add(18*time.Hour)
+	first, second, other := "00000000-0000-4000-8000-000000000001", "00000000-0000-4000-8000-000000000002", faker.UUID()
+
+	eventAt := func(ownerID string, start, end int) storage.Event {
+		event := newEvent(date.Add(time.Duration(start) * time.Hour))
+		event.OwnerID = ownerID
+		event.Duration = time.Duration(end-start) * time.Hour
+
+		return event
+	}
+
+	overlapsFrom := eventAt(first, 7, 9)
+	within := eventAt(first, 12, 13)
+	atTo := eventAt(first, 18, 19)
+	endsAtFrom := eventAt(first, 6, 8)
+	instant := eventAt(first, 10, 10)
+	accepted := eventAt(other, 10, 11)
+	declined := eventAt(other, 14, 15)
+	foreign := eventAt(other, 11, 12)
+	// all-day events are taken a day around the range, the day before may overlap it in time zones ahead of UTC.
+	dayBefore := eventAt(first, -24, 0)
+	dayBefore.AllDay = true
+	twoDaysAfter := eventAt(first, 48, 72)
+	twoDaysAfter.AllDay = true
+
+	s.createEvents(overlapsFrom, within, atTo, endsAtFrom, instant, accepted, declined, foreign, dayBefore, twoDaysAfter)
+
+	acceptedAttendee := newAttendee(accepted.ID, second)
+	declinedAttendee := newAttendee(declined.ID, second)
+
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), acceptedAttendee))
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), declinedAttendee))
+	s.Require().NoError(s.storage.SetAttendeeStatus(context.TODO(), accepted.ID, second, storage.StatusAccepted))
+	s.Require().NoError(s.storage.SetAttendeeStatus(context.TODO(), declined.ID, second, storage.StatusDeclined))
+
+	busy := func(userID string, event storage.Event) storage.BusyInterval {
+		return storage.BusyInterval{
+			UserID:   userID,
+			Interval: storage.Interval{Start: event.StartsAt, End: event.StartsAt.Add(event.Duration)},
+			OwnerID:  event.OwnerID,
+			AllDay:   event.AllDay,
+		}
+	}
+
+	intervals, err := s.storage.ListBusyIntervals(context.TODO(), []string{second, first}, from, to)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.BusyInterval{
+		busy(first, dayBefore), busy(first, overlapsFrom), busy(first, within), busy(second, accepted),
+	}, normalizeIntervals(intervals))
+
+	intervals, err = s.storage.ListBusyIntervals(context.TODO(), []string{faker.UUID()}, from, to)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), intervals, 0)
+}
+
+func (s *StorageSuite) TestCalendarsNotExist() {
+	calendar := newCalendar(faker.UUID(), "Work")
+
+	_, err := s.storage.GetCalendar(context.TODO(), calendar.ID)
+	require.ErrorIs(s.T(), err, storage.ErrCalendarNotFound)
+	require.ErrorIs(s.T(), s.storage.UpdateCalendar(context.TODO(), calendar), storage.ErrCalendarNotFound)
+	_, err = s.storage.DeleteCalendar(context.TODO(), calendar.ID, func(storage.Event, []storage.Attendee) (
+		storage.ChangeLog, error,
+	) {
+		return storage.ChangeLog{}, nil
+	})
+	require.ErrorIs(s.T(), err, storage.ErrCalendarNotFound)
+
+	share := storage.CalendarShare{CalendarID: calendar.ID, UserID: faker.UUID(), Access: storage.AccessRead}
+
+	require.ErrorIs(s.T(), s.storage.SaveShare(context.TODO(), share), storage.ErrCalendarNotFound)
+
+	s.Require().NoError(s.storage.CreateCalendar(context.TODO(), calendar))
+
+	_, err = s.storage.GetShare(context.TODO(), calendar.ID, share.UserID)
+	require.ErrorIs(s.T(), err, storage.ErrShareNotFound)
+	require.ErrorIs(s.T(), s.storage.RemoveShare(context.TODO(), calendar.ID, share.UserID), storage.ErrShareNotFound)
+}
+
+func (s *StorageSuite) TestCalendars() {
+	ownerID, userID := faker.UUID(), faker.UUID()
+	work := newCalendar(ownerID, "Work")
+	home := newCalendar(ownerID, "Home")
+
+	s.Require().NoError(s.storage.CreateCalendar(context.TODO(), work))
+	s.Require().NoError(s.storage.CreateCalendar(context.TODO(), home))
+
+	home.Color = "#00ff00"
+	home.TimeZone = "America/New_York"
+	require.NoError(s.T(), s.storage.UpdateCalendar(context.TODO(), home))
+
+	actual, err := s.storage.GetCalendar(context.TODO(), home.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), home, actual)
+
+	calendars, err := s.storage.ListCalendars(context.TODO(), ownerID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Calendar{home, work}, calendars)
+
+	calendars, err = s.storage.ListCalendars(context.TODO(), userID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), calendars, 0)
+
+	share := storage.CalendarShare{CalendarID: work.ID, UserID: userID, Access: storage.AccessFreeBusy}
+	require.NoError(s.T(), s.storage.SaveShare(context.TODO(), share))
+
+	share.Access = storage.AccessWrite
+	require.NoError(s.T(), s.storage.SaveShare(context.TODO(), share))
+
+	actualShare, err := s.storage.GetShare(context.TODO(), work.ID, userID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), share, actualShare)
+
+	shares, err := s.storage.ListShares(context.TODO(), work.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.CalendarShare{share}, shares)
+
+	calendars, err = s.storage.ListCalendars(context.TODO(), userID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Calendar{work}, calendars)
+
+	require.NoError(s.T(), s.storage.RemoveShare(context.TODO(), work.ID, userID))
+
+	calendars, err = s.storage.ListCalendars(context.TODO(), userID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), calendars, 0)
+}
+
+func (s *StorageSuite) TestListCalendarEvents() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	ownerID, readerID, freeBusyID := faker.UUID(), faker.UUID(), faker.UUID()
+	work := newCalendar(ownerID, "Work")
+
+	s.Require().NoError(s.storage.CreateCalendar(context.TODO(), work))
+	s.Require().NoError(s.storage.SaveShare(context.TODO(), storage.CalendarShare{
+		CalendarID: work.ID, UserID: readerID, Access: storage.AccessRead,
+	}))
+	s.Require().NoError(s.storage.SaveShare(context.TODO(), storage.CalendarShare{
+		CalendarID: work.ID, UserID: freeBusyID, Access: storage.AccessFreeBusy,
+	}))
+
+	// an event somebody with write access put into the calendar.
+	inCalendar := newEvent(date.Add(time.Hour))
+	inCalendar.CalendarID = work.ID
+	outside := newEvent(date.Add(2 * time.Hour))
+	outside.OwnerID = ownerID
+
+	s.createEvents(inCalendar, outside)
+
+	actual, err := s.storage.GetEvent(context.TODO(), inCalendar.ID)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{inCalendar}, []storage.Event{actual})
+
+	tests := []struct {
+		name     string
+		filter   storage.EventFilter
+		expected []storage.Event
+	}{
+		{"calendar", storage.EventFilter{CalendarID: work.ID}, []storage.Event{inCalendar}},
+		{"calendar owner", storage.EventFilter{UserID: ownerID}, []storage.Event{inCalendar, outside}},
+		{"reader", storage.EventFilter{UserID: readerID}, []storage.Event{inCalendar}},
+		{"free/busy only", storage.EventFilter{UserID: freeBusyID}, nil},
+		{"owner in calendar", storage.EventFilter{UserID: ownerID, CalendarID: work.ID}, []storage.Event{inCalendar}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			events, err := s.storage.ListDayEvents(context.TODO(), date, tt.filter)
+			require.NoError(s.T(), err)
+			requireEvents(s.T(), tt.expected, events)
+		})
+	}
+}
+
+func (s *StorageSuite) TestEventTags() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	onCall := newEvent(date.Add(time.Hour))
+	onCall.Tags = []string{"on-call", "work"}
+	onCall.Color = "#ff0000"
+	meeting := newEvent(date.Add(2 * time.Hour))
+	meeting.Tags = []string{"meeting", "work"}
+	personal := newEvent(date.Add(3 * time.Hour))
+
+	s.createEvents(onCall, meeting, personal)
+
+	actual, err := s.storage.GetEvent(context.TODO(), onCall.ID)
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{onCall}, []storage.Event{actual})
+
+	tests := []struct {
+		name     string
+		filter   storage.EventFilter
+		expected []storage.Event
+	}{
+		{"no tags", storage.EventFilter{}, []storage.Event{onCall, meeting, personal}},
+		{"tag", storage.EventFilter{Tags: []string{"work"}}, []storage.Event{onCall, meeting}},
+		{"any tag", storage.EventFilter{Tags: []string{"meeting", "on-call"}}, []storage.Event{onCall, meeting}},
+		{
+			"all tags",
+			storage.EventFilter{Tags: []string{"on-call", "work"}, TagsMatch: storage.TagsAll},
+			[]storage.Event{onCall},
+		},
+		{
+			"all tags nobody has",
+			storage.EventFilter{Tags: []string{"meeting", "on-call"}, TagsMatch: storage.TagsAll},
+			nil,
+		},
+		{"unknown tag", storage.EventFilter{Tags: []string{"personal"}}, nil},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			events, err := s.storage.ListDayEvents(context.TODO(), date, tt.filter)
+			require.NoError(s.T(), err)
+			requireEvents(s.T(), tt.expected, events)
+		})
+	}
+
+	// tags are replaced as a whole.
+	meeting.Tags = []string{"personal"}
+	meeting.Color = "#00ff00"
+
+	require.NoError(s.T(), s.storage.UpdateEvent(context.TODO(), meeting.ID, meeting, storage.ChangeLog{}))
+
+	events, err := s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{Tags: []string{"personal"}})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{meeting}, events)
+
+	require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), onCall.ID, storage.ChangeLog{}))
+
+	events, err = s.storage.ListDeletedEvents(context.TODO(), storage.EventFilter{Tags: []string{"on-call"}})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 1)
+	require.Equal(s.T(), onCall.Tags, events[0].Tags)
+}
+
+func (s *StorageSuite) TestDeleteCalendar() {
+	date := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	calendar := newCalendar(faker.UUID(), "Work")
+	userID := faker.UUID()
+
+	s.Require().NoError(s.storage.CreateCalendar(context.TODO(), calendar))
+	s.Require().NoError(s.storage.SaveShare(context.TODO(), storage.CalendarShare{
+		CalendarID: calendar.ID, UserID: userID, Access: storage.AccessRead,
+	}))
+
+	inCalendar := newEvent(date.Add(time.Hour))
+	inCalendar.CalendarID = calendar.ID
+	earlier := newEvent(date.Add(30 * time.Minute))
+	earlier.CalendarID = calendar.ID
+	trashed := newEvent(date.Add(3 * time.Hour))
+	trashed.CalendarID = calendar.ID
+	outside := newEvent(date.Add(2 * time.Hour))
+
+	s.createEvents(inCalendar, earlier, trashed, outside)
+	s.Require().NoError(s.storage.SaveAttendee(context.TODO(), newAttendee(inCalendar.ID, userID)))
+	s.Require().NoError(s.storage.DeleteEvent(context.TODO(), trashed.ID, storage.ChangeLog{}))
+
+	trashedBefore, err := s.storage.GetDeletedEvent(context.TODO(), trashed.ID)
+	s.Require().NoError(err)
+
+	deletedAt := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	logs := map[string]storage.ChangeLog{}
+	logged := []string{}
+
+	removed, err := s.storage.DeleteCalendar(context.TODO(), calendar.ID, func(
+		event storage.Event, attendees []storage.Attendee,
+	) (storage.ChangeLog, error) {
+		// attendees are read within the deletion, so the log tells everyone invited.
+		if event.ID == inCalendar.ID {
+			require.Len(s.T(), attendees, 1)
+			require.Equal(s.T(), userID, attendees[0].UserID)
+		}
+
+		logs[event.ID] = newChangeLog(event.ID, storage.AuditDelete, event, storage.Event{}, deletedAt)
+		logged = append(logged, event.ID)
+
+		return logs[event.ID], nil
+	})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{earlier, inCalendar}, removed)
+
+	_, err = s.storage.GetCalendar(context.TODO(), calendar.ID)
+	require.ErrorIs(s.T(), err, storage.ErrCalendarNotFound)
+
+	// only events moved to the trash along with the calendar get their deletion logged, in the order they start.
+	require.Equal(s.T(), []string{earlier.ID, inCalendar.ID}, logged)
+
+	records, err := s.storage.ListAuditRecords(context.TODO(), inCalendar.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), logs[inCalendar.ID].Audit, records)
+
+	// events of the calendar are moved to the trash, the ones trashed before stay there as they were.
+	events, err := s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{outside}, events)
+
+	deleted, err := s.storage.GetDeletedEvent(context.TODO(), inCalendar.ID)
+	require.NoError(s.T(), err)
+	require.NotNil(s.T(), deleted.DeletedAt)
+
+	deleted, err = s.storage.GetDeletedEvent(context.TODO(), trashed.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), trashedBefore.DeletedAt.UTC(), deleted.DeletedAt.UTC())
+
+	calendars, err := s.storage.ListCalendars(context.TODO(), userID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), calendars, 0)
+
+	// restored events are out of the deleted calendar, they keep their attendees.
+	require.NoError(s.T(), s.storage.RestoreEvent(context.TODO(), inCalendar.ID, storage.ChangeLog{}))
+
+	restored := inCalendar
+	restored.CalendarID = ""
+
+	events, err = s.storage.ListDayEvents(context.TODO(), date, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	requireEvents(s.T(), []storage.Event{restored, outside}, events)
+
+	attendees, err := s.storage.ListAttendees(context.TODO(), inCalendar.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), attendees, 1)
+}
+
+func (s *StorageSuite) TestListChanges() {
+	ctx := context.TODO()
+	ownerID, readerID, attendeeID, otherID := faker.UUID(), faker.UUID(), faker.UUID(), faker.UUID()
+	calendar := newCalendar(ownerID, "Work")
+	all := storage.ListScopes("", "")[0]
+	owner, reader, attendee, other := storage.UserScope(ownerID), storage.UserScope(readerID),
+		storage.UserScope(attendeeID), storage.UserScope(otherID)
+	inCalendar := storage.CalendarScope(calendar.ID)
+	scopes := []string{all, owner, reader, attendee, other, inCalendar}
+
+	changedAt := func() map[string]time.Time {
+		stamps := make(map[string]time.Time, len(scopes))
+
+		for _, scope := range scopes {
+			t, err := s.storage.LastListChange(ctx, []string{scope})
+			s.Require().NoError(err)
+
+			stamps[scope] = t
+		}
+
+		return stamps
+	}
+
+	// requireChanged makes the change and checks only lists of the changed scopes are marked.
+	requireChanged := func(name string, change func() error, changed ...string) {
+		before := changedAt()
+
+		s.Require().NoError(change(), name)
+
+		after := changedAt()
+
+		for _, scope := range scopes {
+			if contains(changed, scope) {
+				require.True(s.T(), after[scope].After(before[scope]), "%s: %s", name, scope)
+			} else {
+				require.Equal(s.T(), before[scope], after[scope], "%s: %s", name, scope)
+			}
+		}
+	}
+
+	for scope, t := range changedAt() {
+		require.True(s.T(), t.IsZero(), scope)
+	}
+
+	s.Require().NoError(s.storage.CreateCalendar(ctx, calendar))
+
+	requireChanged("share", func() error {
+		return s.storage.SaveShare(ctx, storage.CalendarShare{
+			CalendarID: calendar.ID, UserID: readerID, Access: storage.AccessRead,
+		})
+	}, reader)
+
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	event.OwnerID = ownerID
+	event.CalendarID = calendar.ID
+
+	requireChanged("create", func() error {
+		return s.storage.CreateEvent(ctx, event, storage.ChangeLog{})
+	}, all, owner, reader, inCalendar)
+
+	requireChanged("invite", func() error {
+		return s.storage.SaveAttendee(ctx, newAttendee(event.ID, attendeeID))
+	}, all, owner, reader, attendee, inCalendar)
+
+	requireChanged("respond", func() error {
+		return s.storage.SetAttendeeStatus(ctx, event.ID, attendeeID, storage.StatusAccepted)
+	}, all, owner, reader, attendee, inCalendar)
+
+	// lists the event leaves are marked along with the ones it stays in.
+	event.CalendarID = ""
+
+	requireChanged("move out of calendar", func() error {
+		return s.storage.UpdateEvent(ctx, event.ID, event, storage.ChangeLog{})
+	}, all, owner, reader, attendee, inCalendar)
+
+	requireChanged("trash", func() error {
+		return s.storage.DeleteEvent(ctx, event.ID, storage.ChangeLog{})
+	}, all, owner, attendee)
+
+	requireChanged("restore", func() error {
+		return s.storage.RestoreEvent(ctx, event.ID, storage.ChangeLog{})
+	}, all, owner, attendee)
+
+	requireChanged("remove attendee", func() error {
+		return s.storage.RemoveAttendee(ctx, event.ID, attendeeID)
+	}, all, owner, attendee)
+
+	requireChanged("settings", func() error {
+		return s.storage.SaveUserSettings(ctx, storage.UserSettings{
+			UserID: otherID, TimeZone: "Europe/Moscow", FirstDayOfWeek: time.Sunday,
+		})
+	}, other)
+
+	requireChanged("calendar time zone", func() error {
+		calendar.TimeZone = "America/New_York"
+
+		return s.storage.UpdateCalendar(ctx, calendar)
+	}, inCalendar)
+
+	event.CalendarID = calendar.ID
+
+	requireChanged("move into calendar", func() error {
+		return s.storage.UpdateEvent(ctx, event.ID, event, storage.ChangeLog{})
+	}, all, owner, reader, inCalendar)
+
+	requireChanged("unshare", func() error {
+		return s.storage.RemoveShare(ctx, calendar.ID, readerID)
+	}, reader)
+
+	requireChanged("delete calendar", func() error {
+		_, err := s.storage.DeleteCalendar(ctx, calendar.ID, func(storage.Event, []storage.Attendee) (storage.ChangeLog, error) {
+			return storage.ChangeLog{}, nil
+		})
+
+		return err
+	}, all, owner, inCalendar)
+
+	// a list of several scopes changed when the newest of them did.
+	last, err := s.storage.LastListChange(ctx, []string{other, owner})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), changedAt()[owner], last)
+}
+
+func (s *StorageSuite) TestAuditRecords() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	updated := event
+	updated.Title = faker.Sentence()
+
+	createdAt := time.Date(2021, 6, 1, 9, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	records := []storage.AuditRecord{
+		{
+			ID:        faker.UUID(),
+			EventID:   event.ID,
+			ActorID:   event.OwnerID,
+			Operation: storage.AuditCreate,
+			CreatedAt: createdAt,
+			Changes:   storage.DiffEvents(storage.Event{}, event),
+		},
+		{
+			ID:        faker.UUID(),
+			EventID:   event.ID,
+			Operation: storage.AuditUpdate,
+			CreatedAt: createdAt.Add(time.Minute),
+			Changes:   storage.DiffEvents(event, updated),
+		},
+	}
+
+	// records of other events are not listed.
+	s.Require().NoError(s.storage.AppendAuditRecord(context.TODO(), storage.AuditRecord{
+		ID:        faker.UUID(),
+		EventID:   faker.UUID(),
+		Operation: storage.AuditDelete,
+		CreatedAt: createdAt,
+	}))
+
+	for i := len(records) - 1; i >= 0; i-- {
+		s.Require().NoError(s.storage.AppendAuditRecord(context.TODO(), records[i]))
+	}
+
+	actual, err := s.storage.ListAuditRecords(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+
+	for i := range records {
+		records[i].CreatedAt = records[i].CreatedAt.UTC()
+	}
+
+	require.Equal(s.T(), records, actual)
+
+	actual, err = s.storage.ListAuditRecords(context.TODO(), faker.UUID())
+	require.NoError(s.T(), err)
+	require.Len(s.T(), actual, 0)
+}
+
+func (s *StorageSuite) TestChangeLog() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	updated := event
+	updated.Title = faker.Sentence()
+
+	createdAt := time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)
+	logs := []storage.ChangeLog{
+		newChangeLog(event.ID, storage.AuditCreate, storage.Event{}, event, createdAt),
+		newChangeLog(event.ID, storage.AuditUpdate, event, updated, createdAt.Add(time.Minute)),
+		newChangeLog(event.ID, storage.AuditDelete, updated, storage.Event{}, createdAt.Add(2*time.Minute)),
+		newChangeLog(event.ID, storage.AuditRestore, storage.Event{}, updated, createdAt.Add(3*time.Minute)),
+	}
+
+	// the update is told to an attendee.
+	notice := storage.Notification{
+		ID:          faker.UUID(),
+		EventID:     event.ID,
+		UserID:      faker.UUID(),
+		Channel:     storage.ChannelLog,
+		ScheduledAt: createdAt.Add(time.Minute),
+		Status:      storage.NotificationPending,
+		Payload:     `{"message":"changed"}`,
+		CreatedAt:   createdAt.Add(time.Minute),
+	}
+	message := storage.OutboxMessage{ID: faker.UUID(), Payload: notice.Payload, CreatedAt: notice.CreatedAt}
+
+	logs[1].Notifications = []storage.Notification{notice}
+	logs[1].Outbox = []storage.OutboxMessage{message}
+
+	s.Require().NoError(s.storage.CreateEvent(context.TODO(), event, logs[0]))
+	s.Require().NoError(s.storage.UpdateEvent(context.TODO(), event.ID, updated, logs[1]))
+	s.Require().NoError(s.storage.DeleteEvent(context.TODO(), event.ID, logs[2]))
+	s.Require().NoError(s.storage.RestoreEvent(context.TODO(), event.ID, logs[3]))
+
+	// the log of a change that fails is not written either.
+	failed := newChangeLog(event.ID, storage.AuditCreate, storage.Event{}, event, createdAt.Add(4*time.Minute))
+	failed.Notifications = []storage.Notification{notice}
+	failed.Notifications[0].ID = faker.UUID()
+	failed.Outbox = []storage.OutboxMessage{{ID: faker.UUID(), Payload: notice.Payload, CreatedAt: notice.CreatedAt}}
+
+	require.ErrorIs(s.T(), s.storage.CreateEvent(context.TODO(), event, failed), storage.ErrEventAlreadyExists)
+	require.ErrorIs(s.T(), s.storage.RestoreEvent(context.TODO(), event.ID, failed), storage.ErrEventNotFound)
+
+	notifications, err := s.storage.ListNotifications(context.TODO(), storage.NotificationFilter{EventID: event.ID})
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Notification{notice}, notifications)
+
+	pending, err := s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.OutboxMessage{message}, pending)
+
+	var expected []storage.AuditRecord
+	for _, log := range logs {
+		expected = append(expected, log.Audit...)
+	}
+
+	actual, err := s.storage.ListAuditRecords(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), expected, actual)
+}
+
+func (s *StorageSuite) TestWebhooksNotExist() {
+	_, err := s.storage.GetWebhook(context.TODO(), faker.UUID())
+	require.ErrorIs(s.T(), err, storage.ErrWebhookNotFound)
+	require.ErrorIs(s.T(), s.storage.DeleteWebhook(context.TODO(), faker.UUID()), storage.ErrWebhookNotFound)
+	require.ErrorIs(s.T(),
+		s.storage.CreateWebhookDelivery(context.TODO(), newDelivery(faker.UUID(), time.Now())), storage.ErrWebhookNotFound)
+	require.ErrorIs(s.T(),
+		s.storage.UpdateWebhookDelivery(context.TODO(), newDelivery(faker.UUID(), time.Now())), storage.ErrDeliveryNotFound)
+}
+
+func (s *StorageSuite) TestWebhooks() {
+	ownerID := faker.UUID()
+	second := newWebhook(ownerID, "https://b.example.com/hook")
+	first := newWebhook(ownerID, "https://a.example.com/hook")
+	first.Operations = storage.Operations{storage.AuditCreate, storage.AuditDelete}
+
+	for _, w := range []storage.Webhook{second, first, newWebhook(faker.UUID(), "https://c.example.com/hook")} {
+		s.Require().NoError(s.storage.CreateWebhook(context.TODO(), w))
+	}
+
+	actual, err := s.storage.GetWebhook(context.TODO(), first.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), first, actual)
+
+	webhooks, err := s.storage.ListWebhooks(context.TODO(), ownerID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Webhook{first, second}, webhooks)
+
+	require.NoError(s.T(), s.storage.DeleteWebhook(context.TODO(), second.ID))
+
+	webhooks, err = s.storage.ListWebhooks(context.TODO(), ownerID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.Webhook{first}, webhooks)
+}
+
+func (s *StorageSuite) TestWebhookDeliveries() {
+	webhook := newWebhook(faker.UUID(), "https://example.com/hook")
+	s.Require().NoError(s.storage.CreateWebhook(context.TODO(), webhook))
+
+	now := time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC)
+	due := newDelivery(webhook.ID, now.Add(-time.Minute))
+	later := newDelivery(webhook.ID, now.Add(time.Minute))
+	later.CreatedAt = due.CreatedAt.Add(time.Second)
+
+	for _, d := range []storage.WebhookDelivery{later, due} {
+		s.Require().NoError(s.storage.CreateWebhookDelivery(context.TODO(), d))
+	}
+
+	deliveries, err := s.storage.ListDueWebhookDeliveries(context.TODO(), now)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.WebhookDelivery{due}, deliveries)
+
+	deliveredAt := now.In(time.FixedZone("UTC+3", 3*60*60))
+	due.Status = storage.DeliveryDelivered
+	due.Attempts = 2
+	due.LastError = "503 Service Unavailable"
+	due.DeliveredAt = &deliveredAt
+	// the payload is never changed.
+	update := due
+	update.Payload = "{}"
+	s.Require().NoError(s.storage.UpdateWebhookDelivery(context.TODO(), update))
+
+	deliveries, err = s.storage.ListDueWebhookDeliveries(context.TODO(), now.Add(time.Hour))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.WebhookDelivery{later}, deliveries)
+
+	deliveries, err = s.storage.ListWebhookDeliveries(context.TODO(), webhook.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, 2)
+	require.Equal(s.T(), later, deliveries[0])
+	require.Equal(s.T(), deliveredAt.UTC(), *deliveries[1].DeliveredAt)
+	deliveries[1].DeliveredAt = due.DeliveredAt
+	require.Equal(s.T(), due, deliveries[1])
+
+	require.NoError(s.T(), s.storage.DeleteWebhook(context.TODO(), webhook.ID))
+
+	deliveries, err = s.storage.ListWebhookDeliveries(context.TODO(), webhook.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), deliveries, 0)
+}
+
+func (s *StorageSuite) TestOutboxNotExist() {
+	message := newOutboxMessage(time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC))
+
+	require.ErrorIs(s.T(), s.storage.FireReminder(context.TODO(), faker.UUID(), time.Now(),
+		[]storage.Notification{newNotification(faker.UUID(), faker.UUID(), time.Now())}, []storage.OutboxMessage{message}),
+		storage.ErrReminderNotFound)
+	require.ErrorIs(s.T(), s.storage.MarkOutboxSent(context.TODO(), message.ID, time.Now()), storage.ErrOutboxMessageNotFound)
+
+	// messages and notifications of the reminder which failed to fire are not written.
+	messages, err := s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), messages, 0)
+
+	notifications, err := s.storage.ListNotifications(context.TODO(), storage.NotificationFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), notifications, 0)
+}
+
+func (s *StorageSuite) TestOutbox() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	reminder := newReminder(event.ID, time.Hour)
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), reminder))
+
+	createdAt := time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC)
+	first := newOutboxMessage(createdAt)
+	second := newOutboxMessage(createdAt.Add(time.Second))
+	third := newOutboxMessage(createdAt.Add(2 * time.Second))
+
+	firedAt := createdAt.In(time.FixedZone("UTC+3", 3*60*60))
+	s.Require().NoError(s.storage.FireReminder(context.TODO(), reminder.ID, firedAt,
+		nil, []storage.OutboxMessage{third, first, second}))
+
+	reminders, err := s.storage.ListReminders(context.TODO(), event.ID)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), reminders, 1)
+	require.NotNil(s.T(), reminders[0].FiredAt)
+	require.Equal(s.T(), createdAt, *reminders[0].FiredAt)
+
+	messages, err := s.storage.ListPendingOutbox(context.TODO(), 2)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.OutboxMessage{first, second}, messages)
+
+	sentAt := createdAt.Add(time.Minute)
+	require.NoError(s.T(), s.storage.MarkOutboxSent(context.TODO(), first.ID, sentAt))
+	require.NoError(s.T(), s.storage.MarkOutboxSent(context.TODO(), second.ID, sentAt.Add(time.Hour)))
+
+	messages, err = s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.OutboxMessage{third}, messages)
+
+	// messages are purged once sent, pending ones are kept regardless of their age.
+	purged, err := s.storage.PurgeSentOutbox(context.TODO(), sentAt.Add(time.Second))
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 1, purged)
+
+	require.ErrorIs(s.T(), s.storage.MarkOutboxSent(context.TODO(), first.ID, sentAt), storage.ErrOutboxMessageNotFound)
+	require.NoError(s.T(), s.storage.MarkOutboxSent(context.TODO(), second.ID, sentAt))
+
+	messages, err = s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), []storage.OutboxMessage{third}, messages)
+}
+
+func (s *StorageSuite) TestNotificationsNotExist() {
+	notification := newNotification(faker.UUID(), faker.UUID(), time.Now())
+
+	_, err := s.storage.GetNotification(context.TODO(), notification.ID)
+	require.ErrorIs(s.T(), err, storage.ErrNotificationNotFound)
+	require.ErrorIs(s.T(), s.storage.UpdateNotification(context.TODO(), notification), storage.ErrNotificationNotFound)
+
+	message := newOutboxMessage(time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC))
+	require.ErrorIs(s.T(), s.storage.ResendNotification(context.TODO(), notification.ID, message),
+		storage.ErrNotificationNotFound)
+
+	// the message of the notification which failed to be sent again is not written.
+	messages, err := s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), messages, 0)
+}
+
+func (s *StorageSuite) TestNotifications() {
+	event := newEvent(time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC))
+	first := newReminder(event.ID, time.Hour)
+	second := newReminder(event.ID, 30*time.Minute)
+
+	s.createEvents(event)
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), first))
+	s.Require().NoError(s.storage.CreateReminder(context.TODO(), second))
+
+	firstAt, secondAt := event.StartsAt.Add(-time.Hour), event.StartsAt.Add(-30*time.Minute)
+
+	guestID := faker.UUID()
+	earlier := newNotification(event.ID, first.ID, firstAt)
+	earlierGuest := newNotification(event.ID, first.ID, firstAt)
+	earlierGuest.UserID = guestID
+	later := newNotification(event.ID, second.ID, secondAt.In(time.FixedZone("UTC+3", 3*60*60)))
+
+	s.Require().NoError(s.storage.FireReminder(context.TODO(), first.ID, firstAt,
+		[]storage.Notification{earlier, earlierGuest}, []storage.OutboxMessage{newOutboxMessage(firstAt)}))
+	s.Require().NoError(s.storage.FireReminder(context.TODO(), second.ID, secondAt,
+		[]storage.Notification{later}, nil))
+
+	later.ScheduledAt = later.ScheduledAt.UTC()
+
+	listNotifications := func(filter storage.NotificationFilter) []storage.Notification {
+		notifications, err := s.storage.ListNotifications(context.TODO(), filter)
+		s.Require().NoError(err)
+
+		return notifications
+	}
+
+	// the latest scheduled come first.
+	all := listNotifications(storage.NotificationFilter{EventID: event.ID})
+	s.Require().Len(all, 3)
+	require.Equal(s.T(), later, all[0])
+	require.ElementsMatch(s.T(), []storage.Notification{earlier, earlierGuest}, all[1:])
+
+	require.Equal(s.T(), []storage.Notification{earlierGuest}, listNotifications(storage.NotificationFilter{
+		UserID: guestID,
+	}))
+	require.Len(s.T(), listNotifications(storage.NotificationFilter{EventID: faker.UUID()}), 0)
+
+	failedAt := firstAt.Add(time.Second)
+	failed := earlier
+	failed.Status = storage.NotificationFailed
+	failed.Attempts = 1
+	failed.LastError = "no notifier for channel"
+	failed.Payload = `{"changed":true}`
+	s.Require().NoError(s.storage.UpdateNotification(context.TODO(), failed))
+
+	sent := later
+	sent.Status = storage.NotificationSent
+	sent.Attempts = 1
+	sent.SentAt = &failedAt
+	s.Require().NoError(s.storage.UpdateNotification(context.TODO(), sent))
+
+	// the payload is never changed.
+	failed.Payload = earlier.Payload
+
+	notification, err := s.storage.GetNotification(context.TODO(), failed.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), failed, notification)
+
+	require.Equal(s.T(), []storage.Notification{failed}, listNotifications(storage.NotificationFilter{
+		EventID: event.ID, Status: storage.NotificationFailed,
+	}))
+	require.Equal(s.T(), []storage.Notification{sent}, listNotifications(storage.NotificationFilter{
+		Status: storage.NotificationSent,
+	}))
+
+	messages, err := s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), messages, 1)
+
+	resent := newOutboxMessage(failedAt)
+	s.Require().NoError(s.storage.ResendNotification(context.TODO(), failed.ID, resent))
+
+	// attempts and the last error are kept until the next attempt.
+	failed.Status = storage.NotificationPending
+
+	notification, err = s.storage.GetNotification(context.TODO(), failed.ID)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), failed, notification)
+
+	messages, err = s.storage.ListPendingOutbox(context.TODO(), 10)
+	require.NoError(s.T(), err)
+	require.Len(s.T(), messages, 2)
+	require.Equal(s.T(), resent, messages[1])
+
+	// notifications go along with the purged event.
+	s.Require().NoError(s.storage.DeleteEvent(context.TODO(), event.ID, storage.ChangeLog{}))
+
+	_, err = s.storage.PurgeDeletedEvents(context.TODO(), time.Now().Add(time.Minute))
+	s.Require().NoError(err)
+
+	require.Len(s.T(), listNotifications(storage.NotificationFilter{EventID: event.ID}), 0)
+}
+
+func (s *StorageSuite) TestConcurrency() {
+	wg := &sync.WaitGroup{}
+	wg.Add(4)
+
+	eventsCount := 100
+	deleteCh := make(chan string, eventsCount)
+	updateCh := make(chan storage.Event, eventsCount)
+	createCh := make(chan storage.Event, eventsCount)
+	startsAt := time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC)
+	done := make(chan struct{})
+
+	for i := 0; i < eventsCount; i++ {
+		createCh <- newEvent(startsAt)
+	}
+
+	close(createCh)
+
+	go func() {
+		defer close(updateCh)
+		defer wg.Done()
+
+		for e := range createCh {
+			require.NoError(s.T(), s.storage.CreateEvent(context.TODO(), e, storage.ChangeLog{}))
+
+			<-time.After(time.Nanosecond * time.Duration(rand.Intn(1000)))
+			e.Description = faker.String()
+			updateCh <- e
+		}
+	}()
+
+	go func() {
+		defer close(deleteCh)
+		defer wg.Done()
+
+		for e := range updateCh {
+			require.NoError(s.T(), s.storage.UpdateEvent(context.TODO(), e.ID, e, storage.ChangeLog{}))
+
+			<-time.After(time.Nanosecond * time.Duration(rand.Intn(1000)))
+			deleteCh <- e.ID
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		defer wg.Done()
+
+		for id := range deleteCh {
+			require.NoError(s.T(), s.storage.DeleteEvent(context.TODO(), id, storage.ChangeLog{}))
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, err := s.storage.ListDayEvents(context.TODO(), startsAt, storage.EventFilter{})
+				require.NoError(s.T(), err)
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	events, err := s.storage.ListDayEvents(context.TODO(), startsAt, storage.EventFilter{})
+	require.NoError(s.T(), err)
+	require.Len(s.T(), events, 0)
+}
+
+func newEvent(startsAt time.Time) storage.Event {
+	return storage.Event{
+		ID:          faker.UUID(),
+		Title:       faker.StringWithSize(10),
+		StartsAt:    startsAt,
+		Duration:    time.Hour,
+		Description: faker.String(),
+		OwnerID:     faker.UUID(),
+	}
+}
+
+func newReminder(eventID string, offset time.Duration) storage.Reminder {
+	return storage.Reminder{
+		ID:      faker.UUID(),
+		EventID: eventID,
+		Offset:  offset,
+		Channel: storage.ChannelLog,
+	}
+}
+
+func newCalendar(ownerID, name string) storage.Calendar {
+	return storage.Calendar{
+		ID:       faker.UUID(),
+		OwnerID:  ownerID,
+		Name:     name,
+		Color:    "#ff0000",
+		TimeZone: "Europe/Moscow",
+	}
+}
+
+func newWebhook(ownerID, url string) storage.Webhook {
+	return storage.Webhook{
+		ID:      faker.UUID(),
+		OwnerID: ownerID,
+		URL:     url,
+		Secret:  faker.StringWithSize(32),
+	}
+}
+
+func newDelivery(webhookID string, nextAttemptAt time.Time) storage.WebhookDelivery {
+	return storage.WebhookDelivery{
+		ID:            faker.UUID(),
+		WebhookID:     webhookID,
+		EventID:       faker.UUID(),
+		Operation:     storage.AuditCreate,
+		Payload:       `{"operation":"create"}`,
+		Status:        storage.DeliveryPending,
+		NextAttemptAt: nextAttemptAt,
+		CreatedAt:     time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC),
+	}
+}
+
+func newOutboxMessage(createdAt time.Time) storage.OutboxMessage {
+	return storage.OutboxMessage{
+		ID:        faker.UUID(),
+		Payload:   `{"eventId":"` + faker.UUID() + `"}`,
+		CreatedAt: createdAt,
+	}
+}
+
+func newNotification(eventID, reminderID string, scheduledAt time.Time) storage.Notification {
+	return storage.Notification{
+		ID:          faker.UUID(),
+		EventID:     eventID,
+		ReminderID:  reminderID,
+		UserID:      faker.UUID(),
+		Channel:     storage.ChannelLog,
+		ScheduledAt: scheduledAt,
+		Status:      storage.NotificationPending,
+		Payload:     `{"eventId":"` + eventID + `"}`,
+		CreatedAt:   time.Date(2021, 6, 20, 11, 0, 0, 0, time.UTC),
+	}
+}
+
+func newAttendee(eventID, userID string) storage.Attendee {
+	return storage.Attendee{
+		EventID: eventID,
+		UserID:  userID,
+		Email:   faker.Email(),
+		Role:    storage.RoleRequired,
+		Status:  storage.StatusNeedsAction,
+	}
+}
+
+func newChangeLog(
+	eventID string, op storage.AuditOperation, before, after storage.Event, createdAt time.Time,
+) storage.ChangeLog {
+	return storage.ChangeLog{
+		Audit: []storage.AuditRecord{{
+			ID:        faker.UUID(),
+			EventID:   eventID,
+			ActorID:   faker.UUID(),
+			Operation: op,
+			CreatedAt: createdAt,
+			Changes:   storage.DiffEvents(before, after),
+		}},
+	}
+}
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+
+	return loc
+}
+
+// requireEvents compares events in order, instants are compared regardless of their location.
+func requireEvents(t *testing.T, expected, actual []storage.Event) {
+	t.Helper()
+
+	require.Equal(t, normalize(expected), normalize(actual))
+}
+
+func normalize(events []storage.Event) []storage.Event {
+	res := make([]storage.Event, 0, len(events))
+
+	for _, e := range events {
+		e.StartsAt = e.StartsAt.UTC()
+		res = append(res, e)
+	}
+
+	return res
+}
+
+func normalizeIntervals(intervals []storage.BusyInterval) []storage.BusyInterval {
+	res := make([]storage.BusyInterval, 0, len(intervals))
+
+	for _, i := range intervals {
+		i.Start, i.End = i.Start.UTC(), i.End.UTC()
+		res = append(res, i)
+	}
+
+	return res
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
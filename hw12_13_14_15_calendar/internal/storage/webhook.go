@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Webhook subscribes the URL to changes of events its owner owns.
+type Webhook struct {
+	ID      string `db:"id"`
+	OwnerID string `db:"owner_id"`
+	URL     string `db:"url"`
+	// Secret signs payloads, so the receiver can tell they come from us.
+	Secret string `db:"secret"`
+	// Operations filter the changes the webhook is notified about, every change is if it's empty.
+	Operations Operations `db:"operations"`
+}
+
+// Subscribed tells whether the webhook is notified about the operation.
+func (w Webhook) Subscribed(op AuditOperation) bool {
+	if len(w.Operations) == 0 {
+		return true
+	}
+
+	for _, o := range w.Operations {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Operations are kept as a comma separated list by SQL storage.
+type Operations []AuditOperation
+
+func (o Operations) Value() (driver.Value, error) {
+	values := make([]string, 0, len(o))
+
+	for _, op := range o {
+		values = append(values, string(op))
+	}
+
+	return strings.Join(values, ","), nil
+}
+
+func (o *Operations) Scan(src interface{}) error {
+	var value string
+
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported operations type %T", src)
+	}
+
+	*o = nil
+
+	if value == "" {
+		return nil
+	}
+
+	for _, op := range strings.Split(value, ",") {
+		*o = append(*o, AuditOperation(op))
+	}
+
+	return nil
+}
+
+// DeliveryStatus is where a webhook delivery is at.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryFailed is final, the delivery is not attempted anymore.
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is a payload posted to the webhook along with the attempts made so far.
+type WebhookDelivery struct {
+	ID        string         `db:"id"`
+	WebhookID string         `db:"webhook_id"`
+	EventID   string         `db:"event_id"`
+	Operation AuditOperation `db:"operation"`
+	// Payload is the JSON posted as is on every attempt.
+	Payload string         `db:"payload"`
+	Status  DeliveryStatus `db:"status"`
+	// Attempts counts attempts made so far.
+	Attempts int `db:"attempts"`
+	// NextAttemptAt is when a pending delivery is attempted.
+	NextAttemptAt time.Time `db:"next_attempt_at"`
+	// LastError tells why the last attempt failed.
+	LastError   string     `db:"last_error"`
+	CreatedAt   time.Time  `db:"created_at"`
+	DeliveredAt *time.Time `db:"delivered_at"`
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenDestination = errors.New("destination address is forbidden")
+
+// privateNetworks are ranges not reachable from the internet, see RFC 1918, RFC 6598 and RFC 4193.
+var privateNetworks = []*net.IPNet{
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("fc00::/7"),
+}
+
+// NewClient returns the client posting deliveries.
+// Unless allowPrivate is set, it refuses to connect to loopback, private and link-local addresses,
+// so webhooks can't be pointed at services next to the calendar. The check is made on the address dialed,
+// after the host is resolved, so a name resolving to such an address is refused as well.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if !allowPrivate {
+		dialer.Control = guardDestination
+		// a proxy would be dialed instead of the webhook, hiding the destination from the check.
+		transport.Proxy = nil
+	}
+
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func guardDestination(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || isInternal(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+	}
+
+	return nil
+}
+
+func isInternal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
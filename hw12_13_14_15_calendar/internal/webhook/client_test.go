@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRefusesInternalDestinations(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusNoContent}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	webhook := storage.Webhook{ID: "webhook", URL: server.URL, Secret: "secret"}
+	st := newDeliveriesStorage(webhook, newDelivery(webhook.ID))
+	policy := RetryPolicy{MaxAttempts: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	actual := runWorkerWithClient(t, st, NewClient(time.Second, false), policy)
+
+	require.Equal(t, storage.DeliveryFailed, actual.Status)
+	require.Contains(t, actual.LastError, ErrForbiddenDestination.Error())
+
+	requests, _ := r.received()
+	require.Len(t, requests, 0)
+
+	// allowed explicitly, e.g. for local setups.
+	st = newDeliveriesStorage(webhook, newDelivery(webhook.ID))
+	actual = runWorkerWithClient(t, st, NewClient(time.Second, true), policy)
+
+	require.Equal(t, storage.DeliveryDelivered, actual.Status)
+}
+
+func TestIsInternal(t *testing.T) {
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "100.64.0.1",
+		"169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0",
+	} {
+		require.True(t, isInternal(net.ParseIP(addr)), addr)
+	}
+
+	for _, addr := range []string{"8.8.8.8", "172.32.0.1", "2001:4860:4860::8888"} {
+		require.False(t, isInternal(net.ParseIP(addr)), addr)
+	}
+}
@@ -0,0 +1,200 @@
+// Package webhook posts queued deliveries about event changes to webhooks.
+// Payloads are signed with the webhook secret along with the time they are sent at
+// and failed attempts are retried with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+)
+
+const (
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of the timestamp, a dot and the body
+	// keyed with the webhook secret.
+	SignatureHeader = "X-Calendar-Signature"
+	// TimestampHeader holds the unix time the request is sent at, receivers reject stale ones as replays.
+	TimestampHeader = "X-Calendar-Timestamp"
+	DeliveryHeader  = "X-Calendar-Delivery"
+	OperationHeader = "X-Calendar-Operation"
+
+	signaturePrefix = "sha256="
+)
+
+var ErrDeliveryFailed = errors.New("delivery failed")
+
+type Logger interface {
+	Info(msg string)
+	Error(msg string)
+}
+
+type Storage interface {
+	GetWebhook(ctx context.Context, id string) (storage.Webhook, error)
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time) ([]storage.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+}
+
+// RetryPolicy bounds attempts of a delivery, the delay doubles after every failed one up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Backoff returns how long to wait before the next attempt once the given number of attempts failed.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.MinBackoff
+
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return backoff
+}
+
+type Worker struct {
+	logger   Logger
+	storage  Storage
+	client   *http.Client
+	interval time.Duration
+	policy   RetryPolicy
+}
+
+func New(logger Logger, storage Storage, client *http.Client, interval time.Duration, policy RetryPolicy) *Worker {
+	return &Worker{logger, storage, client, interval, policy}
+}
+
+// Run attempts due deliveries every interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			// failures to reach the storage are retried on the next tick.
+			if err := w.deliverDue(ctx, now); err != nil {
+				w.logger.Error(fmt.Sprintln("failed to deliver webhooks:", err))
+			}
+		}
+	}
+}
+
+func (w *Worker) deliverDue(ctx context.Context, now time.Time) error {
+	deliveries, err := w.storage.ListDueWebhookDeliveries(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deliveries {
+		webhook, err := w.storage.GetWebhook(ctx, d.WebhookID)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			// the webhook has been deleted along with its deliveries since they were listed.
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err := w.storage.UpdateWebhookDelivery(ctx, w.attempt(ctx, webhook, d)); err != nil &&
+			!errors.Is(err, storage.ErrDeliveryNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attempt posts the delivery and returns it updated with the outcome.
+func (w *Worker) attempt(ctx context.Context, webhook storage.Webhook, d storage.WebhookDelivery) storage.WebhookDelivery {
+	d.Attempts++
+
+	err := w.post(ctx, webhook, d)
+	now := time.Now().UTC()
+
+	switch {
+	case err == nil:
+		d.Status = storage.DeliveryDelivered
+		d.LastError = ""
+		d.DeliveredAt = &now
+	case d.Attempts >= w.policy.MaxAttempts:
+		d.Status = storage.DeliveryFailed
+		d.LastError = err.Error()
+
+		w.logger.Error(fmt.Sprintf("webhook delivery %s failed after %d attempts: %s", d.ID, d.Attempts, err))
+	default:
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(w.policy.Backoff(d.Attempts))
+	}
+
+	return d
+}
+
+func (w *Worker) post(ctx context.Context, webhook storage.Webhook, d storage.WebhookDelivery) error {
+	body := []byte(d.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, body))
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(OperationHeader, string(d.Operation))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%w: %s", ErrDeliveryFailed, res.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the signature header value of the body sent at the timestamp header value,
+// receivers compute it the same way to verify payloads.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether the signature header value matches the body sent at the timestamp header value,
+// requests sent longer than tolerance ago are rejected, so a captured one can't be replayed.
+func Verify(secret string, body []byte, timestamp, signature string, tolerance time.Duration) bool {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if age := time.Since(time.Unix(sentAt, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,256 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)  {}
+func (nopLogger) Error(string) {}
+
+type deliveriesStorage struct {
+	mu         sync.Mutex
+	webhooks   map[string]storage.Webhook
+	deliveries map[string]storage.WebhookDelivery
+}
+
+func newDeliveriesStorage(webhook storage.Webhook, deliveries ...storage.WebhookDelivery) *deliveriesStorage {
+	st := &deliveriesStorage{
+		webhooks:   map[string]storage.Webhook{webhook.ID: webhook},
+		deliveries: make(map[string]storage.WebhookDelivery),
+	}
+
+	for _, d := range deliveries {
+		st.deliveries[d.ID] = d
+	}
+
+	return st
+}
+
+func (s *deliveriesStorage) GetWebhook(ctx context.Context, id string) (storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return webhook, storage.ErrWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+func (s *deliveriesStorage) ListDueWebhookDeliveries(
+	ctx context.Context, now time.Time,
+) ([]storage.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deliveries []storage.WebhookDelivery
+
+	for _, d := range s.deliveries {
+		if d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (s *deliveriesStorage) UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return storage.ErrDeliveryNotFound
+	}
+
+	s.deliveries[delivery.ID] = delivery
+
+	return nil
+}
+
+func (s *deliveriesStorage) delivery(id string) storage.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deliveries[id]
+}
+
+// receiver is a webhook endpoint answering with the statuses in turn, the last one repeats.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+
+	status := r.statuses[0]
+	if len(r.statuses) > 1 {
+		r.statuses = r.statuses[1:]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() ([]*http.Request, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*http.Request{}, r.requests...), append([]string{}, r.bodies...)
+}
+
+func newDelivery(webhookID string) storage.WebhookDelivery {
+	return storage.WebhookDelivery{
+		ID:            "delivery",
+		WebhookID:     webhookID,
+		EventID:       "event",
+		Operation:     storage.AuditUpdate,
+		Payload:       `{"operation":"update"}`,
+		Status:        storage.DeliveryPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	}
+}
+
+// runWorker runs the worker until the delivery is no longer pending and returns it.
+func runWorker(t *testing.T, st *deliveriesStorage, policy RetryPolicy) storage.WebhookDelivery {
+	t.Helper()
+
+	return runWorkerWithClient(t, st, http.DefaultClient, policy)
+}
+
+func runWorkerWithClient(
+	t *testing.T, st *deliveriesStorage, client *http.Client, policy RetryPolicy,
+) storage.WebhookDelivery {
+	t.Helper()
+
+	w := New(nopLogger{}, st, client, 5*time.Millisecond, policy)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return st.delivery("delivery").Status != storage.DeliveryPending
+	}, time.Second, 5*time.Millisecond)
+
+	cancelFn()
+	require.NoError(t, <-done)
+
+	return st.delivery("delivery")
+}
+
+func TestWorkerPostsSignedPayload(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusNoContent}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	webhook := storage.Webhook{ID: "webhook", URL: server.URL, Secret: "secret"}
+	delivery := newDelivery(webhook.ID)
+	st := newDeliveriesStorage(webhook, delivery)
+
+	actual := runWorker(t, st, RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	require.Equal(t, storage.DeliveryDelivered, actual.Status)
+	require.Equal(t, 1, actual.Attempts)
+	require.NotNil(t, actual.DeliveredAt)
+
+	requests, bodies := r.received()
+	require.Len(t, requests, 1)
+	require.Equal(t, delivery.Payload, bodies[0])
+	require.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	require.Equal(t, "delivery", requests[0].Header.Get(DeliveryHeader))
+	require.Equal(t, "update", requests[0].Header.Get(OperationHeader))
+
+	timestamp, signature := requests[0].Header.Get(TimestampHeader), requests[0].Header.Get(SignatureHeader)
+
+	require.True(t, Verify("secret", []byte(bodies[0]), timestamp, signature, time.Minute))
+	require.False(t, Verify("other", []byte(bodies[0]), timestamp, signature, time.Minute))
+	require.False(t, Verify("secret", []byte(bodies[0]), "0", signature, time.Minute))
+}
+
+func TestVerifyRejectsReplays(t *testing.T) {
+	body := []byte(`{"operation":"update"}`)
+	sentAt := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	signature := Sign("secret", sentAt, body)
+
+	require.True(t, Verify("secret", body, sentAt, signature, 2*time.Hour))
+	require.False(t, Verify("secret", body, sentAt, signature, time.Minute))
+
+	// the timestamp is signed, so it can't be refreshed without the secret.
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	require.False(t, Verify("secret", body, now, signature, time.Minute))
+	require.False(t, Verify("secret", body, "yesterday", signature, time.Minute))
+}
+
+func TestWorkerRetriesFailedAttempts(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	webhook := storage.Webhook{ID: "webhook", URL: server.URL, Secret: "secret"}
+	st := newDeliveriesStorage(webhook, newDelivery(webhook.ID))
+
+	actual := runWorker(t, st, RetryPolicy{MaxAttempts: 5, MinBackoff: 10 * time.Millisecond, MaxBackoff: time.Second})
+
+	require.Equal(t, storage.DeliveryDelivered, actual.Status)
+	require.Equal(t, 3, actual.Attempts)
+	require.Empty(t, actual.LastError)
+
+	requests, _ := r.received()
+	require.Len(t, requests, 3)
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	webhook := storage.Webhook{ID: "webhook", URL: server.URL, Secret: "secret"}
+	st := newDeliveriesStorage(webhook, newDelivery(webhook.ID))
+
+	actual := runWorker(t, st, RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	require.Equal(t, storage.DeliveryFailed, actual.Status)
+	require.Equal(t, 2, actual.Attempts)
+	require.Equal(t, "delivery failed: 502 Bad Gateway", actual.LastError)
+	require.Nil(t, actual.DeliveredAt)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	for attempts, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		require.Equal(t, expected, policy.Backoff(attempts), "after %d attempts", attempts)
+	}
+}
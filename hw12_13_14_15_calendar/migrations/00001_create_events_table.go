@@ -2,20 +2,33 @@ package migrations
 
 import (
 	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose"
 )
 
+func init() {
+	goose.AddNamedMigration("00001_create_events_table.go", Up0001, Down0001)
+}
+
 func Up0001(tx *sql.Tx) error {
-	query := `
+	now := "NOW()"
+
+	if isSQLite() {
+		now = "CURRENT_TIMESTAMP"
+	}
+
+	query := fmt.Sprintf(`
 		CREATE TABLE events (
 			id varchar(36) PRIMARY KEY,
 			title varchar(255) NOT NULL,
-			starts_at timestamp NOT NULL DEFAULT NOW(),
+			starts_at timestamp NOT NULL DEFAULT %s,
 			duration varchar(32) NOT NULL,
 			description text,
 			owner_id bigint NOT NULL,
 			notify_before varchar(32)
 		);
-	`
+	`, now)
 
 	if _, err := tx.Exec(query); err != nil {
 		return err
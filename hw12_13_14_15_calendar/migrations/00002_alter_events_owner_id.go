@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00002_alter_events_owner_id.go", Up0002, Down0002)
+}
+
+// Up0002 makes owner_id able to hold UUIDs.
+// Column types are only affinities in sqlite, so there is nothing to change there.
+func Up0002(tx *sql.Tx) error {
+	if isSQLite() {
+		return nil
+	}
+
+	if _, err := tx.Exec("ALTER TABLE events ALTER COLUMN owner_id TYPE varchar(36);"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Down0002(tx *sql.Tx) error {
+	if isSQLite() {
+		return nil
+	}
+
+	if _, err := tx.Exec("ALTER TABLE events ALTER COLUMN owner_id TYPE bigint USING owner_id::bigint;"); err != nil {
+		return err
+	}
+
+	return nil
+}
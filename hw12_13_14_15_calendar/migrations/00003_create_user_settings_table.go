@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00003_create_user_settings_table.go", Up0003, Down0003)
+}
+
+func Up0003(tx *sql.Tx) error {
+	query := `
+		CREATE TABLE user_settings (
+			user_id varchar(36) PRIMARY KEY,
+			time_zone varchar(64) NOT NULL DEFAULT '',
+			first_day_of_week smallint NOT NULL DEFAULT 1
+		);
+	`
+
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Down0003(tx *sql.Tx) error {
+	if _, err := tx.Exec("DROP TABLE user_settings;"); err != nil {
+		return err
+	}
+
+	return nil
+}
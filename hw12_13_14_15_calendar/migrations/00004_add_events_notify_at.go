@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00004_add_events_notify_at.go", Up0004, Down0004)
+}
+
+func Up0004(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE events ADD COLUMN notify_at timestamp;"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("CREATE INDEX events_notify_at_idx ON events (notify_at);"); err != nil {
+		return err
+	}
+
+	// durations are kept as nanoseconds the database can't do arithmetic with, so existing rows are filled in here.
+	rows, err := tx.Query("SELECT id, starts_at, notify_before FROM events WHERE notify_before IS NOT NULL;")
+	if err != nil {
+		return err
+	}
+
+	type notifyRow struct {
+		id       string
+		notifyAt time.Time
+	}
+
+	var pending []notifyRow
+
+	for rows.Next() {
+		var (
+			id           string
+			startsAt     time.Time
+			notifyBefore int64
+		)
+
+		if err := rows.Scan(&id, &startsAt, &notifyBefore); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		if notifyBefore > 0 {
+			pending = append(pending, notifyRow{id, startsAt.Add(-time.Duration(notifyBefore)).UTC()})
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query := "UPDATE events SET notify_at = $1 WHERE id = $2;"
+
+	if isSQLite() {
+		query = "UPDATE events SET notify_at = ? WHERE id = ?;"
+	}
+
+	for _, r := range pending {
+		if _, err := tx.Exec(query, r.notifyAt, r.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0004(tx *sql.Tx) error {
+	if _, err := tx.Exec("DROP INDEX events_notify_at_idx;"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("ALTER TABLE events DROP COLUMN notify_at;"); err != nil {
+		return err
+	}
+
+	return nil
+}
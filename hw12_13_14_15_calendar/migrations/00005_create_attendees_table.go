@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00005_create_attendees_table.go", Up0005, Down0005)
+}
+
+func Up0005(tx *sql.Tx) error {
+	query := `
+		CREATE TABLE attendees (
+			event_id varchar(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+			user_id varchar(36) NOT NULL,
+			email varchar(254) NOT NULL DEFAULT '',
+			role varchar(16) NOT NULL,
+			status varchar(16) NOT NULL,
+			PRIMARY KEY (event_id, user_id)
+		);
+	`
+
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+
+	// events a user is invited to are looked up by the user.
+	if _, err := tx.Exec("CREATE INDEX attendees_user_id_idx ON attendees (user_id);"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Down0005(tx *sql.Tx) error {
+	if _, err := tx.Exec("DROP TABLE attendees;"); err != nil {
+		return err
+	}
+
+	return nil
+}
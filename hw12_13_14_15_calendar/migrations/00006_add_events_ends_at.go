@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00006_add_events_ends_at.go", Up0006, Down0006)
+}
+
+func Up0006(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE events ADD COLUMN ends_at timestamp;"); err != nil {
+		return err
+	}
+
+	// busy time of the users is looked up by the owner first and then by the time range.
+	if _, err := tx.Exec("CREATE INDEX events_owner_id_starts_at_idx ON events (owner_id, starts_at);"); err != nil {
+		return err
+	}
+
+	// like notify_at, the end is computed here as the database can't add nanoseconds to a timestamp.
+	rows, err := tx.Query("SELECT id, starts_at, duration FROM events;")
+	if err != nil {
+		return err
+	}
+
+	type endsRow struct {
+		id     string
+		endsAt time.Time
+	}
+
+	var pending []endsRow
+
+	for rows.Next() {
+		var (
+			id       string
+			startsAt time.Time
+			duration int64
+		)
+
+		if err := rows.Scan(&id, &startsAt, &duration); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		pending = append(pending, endsRow{id, startsAt.Add(time.Duration(duration)).UTC()})
+	}
+
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	query := "UPDATE events SET ends_at = $1 WHERE id = $2;"
+
+	if isSQLite() {
+		query = "UPDATE events SET ends_at = ? WHERE id = ?;"
+	}
+
+	for _, r := range pending {
+		if _, err := tx.Exec(query, r.endsAt, r.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0006(tx *sql.Tx) error {
+	if _, err := tx.Exec("DROP INDEX events_owner_id_starts_at_idx;"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("ALTER TABLE events DROP COLUMN ends_at;"); err != nil {
+		return err
+	}
+
+	return nil
+}
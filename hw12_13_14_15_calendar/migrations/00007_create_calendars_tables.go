@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00007_create_calendars_tables.go", Up0007, Down0007)
+}
+
+func Up0007(tx *sql.Tx) error {
+	queries := []string{
+		`
+		CREATE TABLE calendars (
+			id varchar(36) PRIMARY KEY,
+			owner_id varchar(36) NOT NULL,
+			name varchar(255) NOT NULL,
+			color varchar(7) NOT NULL DEFAULT '',
+			time_zone varchar(64) NOT NULL DEFAULT ''
+		);
+		`,
+		"CREATE INDEX calendars_owner_id_idx ON calendars (owner_id);",
+		`
+		CREATE TABLE calendar_shares (
+			calendar_id varchar(36) NOT NULL REFERENCES calendars (id) ON DELETE CASCADE,
+			user_id varchar(36) NOT NULL,
+			access varchar(16) NOT NULL,
+			PRIMARY KEY (calendar_id, user_id)
+		);
+		`,
+		"CREATE INDEX calendar_shares_user_id_idx ON calendar_shares (user_id);",
+		// events of a deleted calendar are deleted by the storage, sqlite can't drop a column
+		// referencing another table, so there is no foreign key to keep the migration reversible.
+		"ALTER TABLE events ADD COLUMN calendar_id varchar(36);",
+		"CREATE INDEX events_calendar_id_idx ON events (calendar_id);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0007(tx *sql.Tx) error {
+	queries := []string{
+		"DROP INDEX events_calendar_id_idx;",
+		"ALTER TABLE events DROP COLUMN calendar_id;",
+		"DROP TABLE calendar_shares;",
+		"DROP TABLE calendars;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00008_create_reminders_table.go", Up0008, Down0008)
+}
+
+func Up0008(tx *sql.Tx) error {
+	queries := []string{
+		`
+		CREATE TABLE reminders (
+			id varchar(36) PRIMARY KEY,
+			event_id varchar(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+			remind_offset bigint NOT NULL,
+			channel varchar(16) NOT NULL,
+			message text NOT NULL DEFAULT '',
+			remind_at timestamp NOT NULL,
+			fired_at timestamp
+		);
+		`,
+		"CREATE INDEX reminders_event_id_idx ON reminders (event_id);",
+		"CREATE INDEX reminders_remind_at_idx ON reminders (remind_at);",
+		// the single notification of an event becomes its reminder, ids of both tables never meet,
+		// so the event id is reused instead of generating a new one.
+		`
+		INSERT INTO reminders (id, event_id, remind_offset, channel, message, remind_at)
+		SELECT id, id, CAST(notify_before AS bigint), 'log', '', notify_at FROM events
+		WHERE notify_at IS NOT NULL;
+		`,
+		"DROP INDEX events_notify_at_idx;",
+		"ALTER TABLE events DROP COLUMN notify_at;",
+		"ALTER TABLE events DROP COLUMN notify_before;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0008(tx *sql.Tx) error {
+	queries := []string{
+		"ALTER TABLE events ADD COLUMN notify_before varchar(32);",
+		"ALTER TABLE events ADD COLUMN notify_at timestamp;",
+		"CREATE INDEX events_notify_at_idx ON events (notify_at);",
+		// there is room for one notification only, the earliest reminder is kept.
+		`
+		UPDATE events SET
+			notify_before = (
+				SELECT CAST(MAX(remind_offset) AS varchar(32)) FROM reminders WHERE reminders.event_id = events.id
+			),
+			notify_at = (SELECT MIN(remind_at) FROM reminders WHERE reminders.event_id = events.id);
+		`,
+		"DROP TABLE reminders;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
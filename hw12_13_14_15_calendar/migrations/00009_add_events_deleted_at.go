@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00009_add_events_deleted_at.go", Up0009, Down0009)
+}
+
+func Up0009(tx *sql.Tx) error {
+	queries := []string{
+		"ALTER TABLE events ADD COLUMN deleted_at timestamp;",
+		// the trash is purged by the time events were deleted.
+		"CREATE INDEX events_deleted_at_idx ON events (deleted_at);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0009(tx *sql.Tx) error {
+	queries := []string{
+		// there is no trash without the column, so trashed events are deleted for good rather than coming back.
+		"DELETE FROM events WHERE deleted_at IS NOT NULL;",
+		"DROP INDEX events_deleted_at_idx;",
+		"ALTER TABLE events DROP COLUMN deleted_at;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
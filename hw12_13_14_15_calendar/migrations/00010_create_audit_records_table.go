@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00010_create_audit_records_table.go", Up0010, Down0010)
+}
+
+func Up0010(tx *sql.Tx) error {
+	queries := []string{
+		// the history outlives purged events, so there is no reference to them.
+		`
+		CREATE TABLE audit_records (
+			id varchar(36) PRIMARY KEY,
+			event_id varchar(36) NOT NULL,
+			actor_id varchar(36) NOT NULL DEFAULT '',
+			operation varchar(16) NOT NULL,
+			created_at timestamp NOT NULL,
+			changes text NOT NULL
+		);
+		`,
+		"CREATE INDEX audit_records_event_id_idx ON audit_records (event_id, created_at);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0010(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE audit_records;")
+
+	return err
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00011_create_webhooks_tables.go", Up0011, Down0011)
+}
+
+func Up0011(tx *sql.Tx) error {
+	queries := []string{
+		`
+		CREATE TABLE webhooks (
+			id varchar(36) PRIMARY KEY,
+			owner_id varchar(36) NOT NULL,
+			url text NOT NULL,
+			secret text NOT NULL,
+			operations text NOT NULL DEFAULT ''
+		);
+		`,
+		"CREATE INDEX webhooks_owner_id_idx ON webhooks (owner_id);",
+		// deliveries outlive purged events, so there is no reference to them.
+		`
+		CREATE TABLE webhook_deliveries (
+			id varchar(36) PRIMARY KEY,
+			webhook_id varchar(36) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+			event_id varchar(36) NOT NULL,
+			operation varchar(16) NOT NULL,
+			payload text NOT NULL,
+			status varchar(16) NOT NULL,
+			attempts integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamp NOT NULL,
+			last_error text NOT NULL DEFAULT '',
+			created_at timestamp NOT NULL,
+			delivered_at timestamp
+		);
+		`,
+		"CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at);",
+		"CREATE INDEX webhook_deliveries_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0011(tx *sql.Tx) error {
+	queries := []string{
+		"DROP TABLE webhook_deliveries;",
+		"DROP TABLE webhooks;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
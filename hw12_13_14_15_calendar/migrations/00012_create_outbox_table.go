@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00012_create_outbox_table.go", Up0012, Down0012)
+}
+
+func Up0012(tx *sql.Tx) error {
+	queries := []string{
+		`
+		CREATE TABLE outbox (
+			id varchar(36) PRIMARY KEY,
+			payload text NOT NULL,
+			created_at timestamp NOT NULL,
+			sent_at timestamp
+		);
+		`,
+		// pending messages are relayed in the order they were written.
+		"CREATE INDEX outbox_sent_at_idx ON outbox (sent_at, created_at);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0012(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE outbox;")
+
+	return err
+}
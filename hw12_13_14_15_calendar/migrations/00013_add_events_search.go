@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00013_add_events_search.go", Up0013, Down0013)
+}
+
+// Up0013 indexes words of event titles and descriptions, titles weigh more in ranking.
+// The simple configuration doesn't stem words, so search behaves the same for any language.
+func Up0013(tx *sql.Tx) error {
+	queries := []string{
+		`
+		ALTER TABLE events ADD COLUMN search tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', title), 'A') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+		) STORED;
+		`,
+		"CREATE INDEX events_search_idx ON events USING GIN (search);",
+	}
+
+	if isSQLite() {
+		queries = sqliteUp0013()
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sqliteUp0013 keeps words in a full-text table of its own, which triggers keep in sync with events.
+func sqliteUp0013() []string {
+	return []string{
+		`
+		CREATE VIRTUAL TABLE events_search USING fts5 (
+			event_id UNINDEXED, title, description, tokenize = 'unicode61 remove_diacritics 0'
+		);
+		`,
+		`
+		INSERT INTO events_search (event_id, title, description)
+		SELECT id, title, coalesce(description, '') FROM events;
+		`,
+		`
+		CREATE TRIGGER events_search_insert AFTER INSERT ON events BEGIN
+			INSERT INTO events_search (event_id, title, description)
+			VALUES (new.id, new.title, coalesce(new.description, ''));
+		END;
+		`,
+		`
+		CREATE TRIGGER events_search_update AFTER UPDATE OF title, description ON events BEGIN
+			UPDATE events_search SET title = new.title, description = coalesce(new.description, '')
+			WHERE event_id = old.id;
+		END;
+		`,
+		`
+		CREATE TRIGGER events_search_delete AFTER DELETE ON events BEGIN
+			DELETE FROM events_search WHERE event_id = old.id;
+		END;
+		`,
+	}
+}
+
+func Down0013(tx *sql.Tx) error {
+	queries := []string{
+		"DROP INDEX events_search_idx;",
+		"ALTER TABLE events DROP COLUMN search;",
+	}
+
+	if isSQLite() {
+		queries = []string{
+			"DROP TRIGGER events_search_delete;",
+			"DROP TRIGGER events_search_update;",
+			"DROP TRIGGER events_search_insert;",
+			"DROP TABLE events_search;",
+		}
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00014_add_events_tags.go", Up0014, Down0014)
+}
+
+func Up0014(tx *sql.Tx) error {
+	queries := []string{
+		"ALTER TABLE events ADD COLUMN color varchar(7) NOT NULL DEFAULT '';",
+		`
+		CREATE TABLE event_tags (
+			event_id varchar(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+			tag varchar(32) NOT NULL,
+			PRIMARY KEY (event_id, tag)
+		);
+		`,
+		// events are filtered by tags.
+		"CREATE INDEX event_tags_tag_idx ON event_tags (tag);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0014(tx *sql.Tx) error {
+	queries := []string{
+		"DROP TABLE event_tags;",
+		"ALTER TABLE events DROP COLUMN color;",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00015_add_events_all_day.go", Up0015, Down0015)
+}
+
+func Up0015(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE events ADD COLUMN all_day boolean NOT NULL DEFAULT false;"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func Down0015(tx *sql.Tx) error {
+	if _, err := tx.Exec("ALTER TABLE events DROP COLUMN all_day;"); err != nil {
+		return err
+	}
+
+	return nil
+}
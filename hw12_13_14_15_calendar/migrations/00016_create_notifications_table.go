@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00016_create_notifications_table.go", Up0016, Down0016)
+}
+
+func Up0016(tx *sql.Tx) error {
+	queries := []string{
+		// notifications outlive removed reminders, so there is no reference to them.
+		`
+		CREATE TABLE notifications (
+			id varchar(36) PRIMARY KEY,
+			event_id varchar(36) NOT NULL REFERENCES events (id) ON DELETE CASCADE,
+			reminder_id varchar(36) NOT NULL,
+			user_id varchar(36) NOT NULL,
+			channel varchar(16) NOT NULL,
+			scheduled_at timestamp NOT NULL,
+			status varchar(16) NOT NULL,
+			attempts integer NOT NULL DEFAULT 0,
+			last_error text NOT NULL DEFAULT '',
+			payload text NOT NULL,
+			created_at timestamp NOT NULL,
+			sent_at timestamp
+		);
+		`,
+		"CREATE INDEX notifications_event_id_idx ON notifications (event_id, scheduled_at);",
+		"CREATE INDEX notifications_user_id_idx ON notifications (user_id, scheduled_at);",
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Down0016(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE notifications;")
+
+	return err
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/pressly/goose"
+)
+
+func init() {
+	goose.AddNamedMigration("00017_create_list_changes_table.go", Up0017, Down0017)
+}
+
+// Up0017 keeps when lists of events were changed last per scope,
+// so conditional list requests are answered without listing events.
+func Up0017(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE list_changes (
+			scope varchar(64) PRIMARY KEY,
+			changed_at timestamp NOT NULL
+		);
+	`)
+
+	return err
+}
+
+func Down0017(tx *sql.Tx) error {
+	_, err := tx.Exec("DROP TABLE list_changes;")
+
+	return err
+}
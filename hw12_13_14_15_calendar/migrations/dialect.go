@@ -0,0 +1,10 @@
+package migrations
+
+import "github.com/pressly/goose"
+
+// isSQLite reports whether goose is configured to migrate an sqlite database.
+func isSQLite() bool {
+	_, ok := goose.GetDialect().(*goose.Sqlite3Dialect)
+
+	return ok
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/pressly/goose"
+)
+
+// Run runs the goose command (up, down, redo, status, version, ...) with migrations registered by this package.
+// goose also collects migration files from a directory, an empty temporary one makes sure
+// nothing but the registered migrations is applied wherever the binary is started from.
+func Run(db *sql.DB, dialect, command string, args ...string) error {
+	if err := goose.SetDialect(dialect); err != nil {
+		return fmt.Errorf("failed to set dialect: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "calendar-migrations")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	return goose.Run(command, db, dir, args...)
+}
@@ -0,0 +1,135 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestRun(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "calendar.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	requireVersion := func(expected int64) {
+		t.Helper()
+
+		version, err := goose.GetDBVersion(db)
+		require.NoError(t, err)
+		require.Equal(t, expected, version)
+	}
+
+	require.NoError(t, Run(db, "sqlite3", "up-to", "3"))
+	requireVersion(3)
+
+	startsAt := time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC)
+
+	_, err = db.Exec(
+		"INSERT INTO events (id, title, starts_at, duration, owner_id, notify_before) VALUES (?, ?, ?, ?, ?, ?)",
+		"1", "event", startsAt, time.Hour, "owner", 15*time.Minute,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, Run(db, "sqlite3", "up-to", "7"))
+	requireVersion(7)
+
+	var notifyAt, endsAt time.Time
+
+	require.NoError(t, db.QueryRow("SELECT notify_at, ends_at FROM events WHERE id = ?", "1").Scan(&notifyAt, &endsAt))
+	require.Equal(t, startsAt.Add(-15*time.Minute), notifyAt.UTC())
+	require.Equal(t, startsAt.Add(time.Hour), endsAt.UTC())
+
+	require.NoError(t, Run(db, "sqlite3", "up"))
+	requireVersion(17)
+
+	var (
+		remindOffset time.Duration
+		remindAt     time.Time
+	)
+
+	require.NoError(t, db.QueryRow(
+		"SELECT remind_offset, remind_at FROM reminders WHERE event_id = ?", "1",
+	).Scan(&remindOffset, &remindAt))
+	require.Equal(t, 15*time.Minute, remindOffset)
+	require.Equal(t, notifyAt.UTC(), remindAt.UTC())
+
+	_, err = db.Exec(
+		"INSERT INTO events (id, title, starts_at, duration, owner_id, ends_at, deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		"2", "trashed", startsAt, time.Hour, "owner", startsAt.Add(time.Hour), startsAt,
+	)
+	require.NoError(t, err)
+
+	var found []string
+
+	// events created before the search are indexed along with the ones created after.
+	rows, err := db.Query(
+		"SELECT event_id FROM events_search WHERE events_search MATCH ? ORDER BY event_id", "event OR trashed",
+	)
+	require.NoError(t, err)
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+
+		require.NoError(t, rows.Scan(&id))
+
+		found = append(found, id)
+	}
+
+	require.NoError(t, rows.Err())
+	require.Equal(t, []string{"1", "2"}, found)
+
+	_, err = db.Exec("INSERT INTO event_tags (event_id, tag) VALUES (?, ?)", "1", "on-call")
+	require.NoError(t, err)
+
+	var (
+		color  string
+		allDay bool
+	)
+
+	// events created before colors and all-day events have no color and a time.
+	require.NoError(t, db.QueryRow("SELECT color, all_day FROM events WHERE id = ?", "1").Scan(&color, &allDay))
+	require.Empty(t, color)
+	require.False(t, allDay)
+
+	_, err = db.Exec(
+		`INSERT INTO notifications (id, event_id, reminder_id, user_id, channel, scheduled_at, status, payload, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		"1", "1", "1", "owner", "log", notifyAt, "pending", "{}", notifyAt,
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, Run(db, "sqlite3", "down-to", "8"))
+	requireVersion(8)
+
+	var count int
+
+	// trashed events don't come back to life without the trash.
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM events").Scan(&count))
+	require.Equal(t, 1, count)
+
+	require.NoError(t, Run(db, "sqlite3", "down"))
+	requireVersion(7)
+
+	require.NoError(t, db.QueryRow("SELECT notify_at FROM events WHERE id = ?", "1").Scan(&notifyAt))
+	require.Equal(t, startsAt.Add(-15*time.Minute), notifyAt.UTC())
+
+	require.NoError(t, Run(db, "sqlite3", "redo"))
+	requireVersion(7)
+
+	require.NoError(t, Run(db, "sqlite3", "status"))
+	require.NoError(t, Run(db, "sqlite3", "version"))
+
+	require.NoError(t, Run(db, "sqlite3", "down-to", "0"))
+	requireVersion(0)
+
+	require.Error(t, Run(db, "sqlite3", "sideways"))
+	require.Error(t, Run(db, "mysql-ish", "up"))
+}
@@ -0,0 +1,952 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pioz/faker"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/type/dayofweek"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	transportGRPC = "grpc"
+	transportHTTP = "http"
+
+	notificationTimeout = 5 * time.Second
+)
+
+// CalendarSuite describes the calendar behavior as scenarios, each step of a scenario is a subtest
+// named after it, and the first failed step stops the scenario.
+type CalendarSuite struct {
+	suite.Suite
+	opts      Options
+	transport string
+	harness   *Harness
+	conn      *grpc.ClientConn
+	api       calendarAPI
+	userID    string
+}
+
+func TestCalendar(t *testing.T) {
+	for _, storage := range []string{MemoryStorage, SQLiteStorage} {
+		for _, transport := range []string{transportGRPC, transportHTTP} {
+			t.Run(storage+"/"+transport, func(t *testing.T) {
+				suite.Run(t, &CalendarSuite{
+					opts:      Options{Storage: storage, SchedulerInterval: 50 * time.Millisecond},
+					transport: transport,
+				})
+			})
+		}
+	}
+}
+
+func (s *CalendarSuite) SetupSuite() {
+	s.harness = Start(s.T(), s.opts)
+	s.userID = uuid.New().String()
+
+	if s.transport == transportGRPC {
+		conn, err := grpc.DialContext(context.Background(), s.harness.GRPCAddress, grpc.WithInsecure())
+		s.Require().NoError(err)
+
+		s.T().Cleanup(func() {
+			conn.Close()
+		})
+
+		s.conn = conn
+	}
+
+	s.api = s.apiFor(s.userID)
+}
+
+// apiFor returns the API as seen by the given user.
+func (s *CalendarSuite) apiFor(userID string) calendarAPI {
+	if s.transport == transportHTTP {
+		return newHTTPAPI(s.harness.HTTPAddress, userID)
+	}
+
+	return newGRPCAPI(s.conn, userID)
+}
+
+func (s *CalendarSuite) given(desc string, fn func()) {
+	s.step("given "+desc, fn)
+}
+
+func (s *CalendarSuite) when(desc string, fn func()) {
+	s.step("when "+desc, fn)
+}
+
+func (s *CalendarSuite) then(desc string, fn func()) {
+	s.step("then "+desc, fn)
+}
+
+func (s *CalendarSuite) step(name string, fn func()) {
+	s.Require().True(s.Run(name, fn), "step %q failed", name)
+}
+
+func (s *CalendarSuite) TestCreateEvent() {
+	var (
+		req *pb.CreateRequest
+		id  string
+	)
+
+	s.given("a new event of the user", func() {
+		req = &pb.CreateRequest{
+			Title:       faker.StringWithSize(20),
+			StartsAt:    timestamppb.New(time.Date(2030, 1, 15, 10, 0, 0, 0, time.UTC)),
+			Duration:    durationpb.New(time.Hour),
+			Description: faker.String(),
+		}
+	})
+
+	s.when("the user creates it", func() {
+		res, err := s.api.CreateEvent(context.Background(), req)
+		s.Require().NoError(err)
+
+		id = res.GetId()
+	})
+
+	s.then("it is returned with every detail and owned by the user", func() {
+		event, err := s.api.GetEvent(context.Background(), &pb.GetRequest{Id: id})
+		s.Require().NoError(err)
+		s.Require().Equal(req.GetTitle(), event.GetTitle())
+		s.Require().Equal(req.GetStartsAt().AsTime(), event.GetStartsAt().AsTime())
+		s.Require().Equal(req.GetDuration().AsDuration(), event.GetDuration().AsDuration())
+		s.Require().Equal(req.GetDescription(), event.GetDescription())
+		s.Require().Equal(s.userID, event.GetOwnerId())
+	})
+
+	s.then("it is listed for its day, week and month", func() {
+		for _, period := range []string{"day", "week", "month"} {
+			s.requireListed(period, &pb.ListRequest{Date: req.GetStartsAt()}, id)
+		}
+	})
+
+	s.when("the user adds reminders to it", func() {
+		for _, offset := range []time.Duration{15 * time.Minute, 24 * time.Hour} {
+			_, err := s.api.AddReminder(context.Background(), &pb.AddReminderRequest{
+				EventId: id,
+				Offset:  durationpb.New(offset),
+				Channel: pb.ReminderChannel_REMINDER_CHANNEL_EMAIL,
+			})
+			s.Require().NoError(err)
+		}
+	})
+
+	s.then("the reminders are listed earliest first", func() {
+		res, err := s.api.ListReminders(context.Background(), &pb.ListRemindersRequest{EventId: id})
+		s.Require().NoError(err)
+		s.Require().Len(res.GetReminders(), 2)
+		s.Require().Equal(req.GetStartsAt().AsTime().Add(-24*time.Hour), res.GetReminders()[0].GetRemindAt().AsTime())
+		s.Require().Equal(req.GetStartsAt().AsTime().Add(-15*time.Minute), res.GetReminders()[1].GetRemindAt().AsTime())
+		s.Require().Equal(pb.ReminderChannel_REMINDER_CHANNEL_EMAIL, res.GetReminders()[1].GetChannel())
+	})
+}
+
+func (s *CalendarSuite) TestCreateInvalidEvent() {
+	var err error
+
+	s.when("the user creates an event with a too short title", func() {
+		_, err = s.api.CreateEvent(context.Background(), &pb.CreateRequest{Title: "short"})
+	})
+
+	s.then("the request is rejected", func() {
+		s.requireCode(codes.InvalidArgument, err)
+	})
+}
+
+func (s *CalendarSuite) TestUpdateEvent() {
+	var (
+		id  string
+		req *pb.UpdateRequest
+	)
+
+	startsAt := time.Date(2030, 2, 12, 10, 0, 0, 0, time.UTC)
+
+	s.given("an existing event", func() {
+		id = s.createEvent(startsAt, 0)
+	})
+
+	s.when("the user moves it to the next month and renames it", func() {
+		req = &pb.UpdateRequest{
+			Id:       id,
+			Title:    faker.StringWithSize(20),
+			StartsAt: timestamppb.New(startsAt.AddDate(0, 1, 0)),
+			Duration: durationpb.New(2 * time.Hour),
+		}
+
+		_, err := s.api.UpdateEvent(context.Background(), req)
+		s.Require().NoError(err)
+	})
+
+	s.then("it is returned updated", func() {
+		event, err := s.api.GetEvent(context.Background(), &pb.GetRequest{Id: id})
+		s.Require().NoError(err)
+		s.Require().Equal(req.GetTitle(), event.GetTitle())
+		s.Require().Equal(req.GetStartsAt().AsTime(), event.GetStartsAt().AsTime())
+		s.Require().Equal(req.GetDuration().AsDuration(), event.GetDuration().AsDuration())
+	})
+
+	s.then("it is listed for the new month only", func() {
+		s.requireListed("month", &pb.ListRequest{Date: timestamppb.New(startsAt)})
+		s.requireListed("month", &pb.ListRequest{Date: req.GetStartsAt()}, id)
+	})
+
+	s.then("its history records the change", func() {
+		res, err := s.api.ListEventHistory(context.Background(), &pb.ListEventHistoryRequest{EventId: id})
+		s.Require().NoError(err)
+		s.Require().Len(res.GetRecords(), 2)
+
+		record := res.GetRecords()[1]
+		s.Require().Equal(pb.AuditOperation_AUDIT_OPERATION_UPDATE, record.GetOperation())
+		s.Require().Equal(s.userID, record.GetActorId())
+
+		fields := make([]string, 0, len(record.GetChanges()))
+
+		for _, c := range record.GetChanges() {
+			fields = append(fields, c.GetField())
+		}
+
+		s.Require().Equal([]string{"title", "starts_at", "duration"}, fields)
+	})
+}
+
+func (s *CalendarSuite) TestDeleteEvent() {
+	var id string
+
+	startsAt := time.Date(2030, 3, 12, 10, 0, 0, 0, time.UTC)
+
+	s.given("an existing event", func() {
+		id = s.createEvent(startsAt, 0)
+	})
+
+	s.when("the user deletes it", func() {
+		s.Require().NoError(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: id}))
+	})
+
+	s.then("it is gone", func() {
+		_, err := s.api.GetEvent(context.Background(), &pb.GetRequest{Id: id})
+		s.requireCode(codes.NotFound, err)
+
+		s.requireListed("day", &pb.ListRequest{Date: timestamppb.New(startsAt)})
+	})
+
+	s.then("it can't be deleted again", func() {
+		s.Require().Error(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: id}))
+	})
+
+	s.then("it is in the trash", func() {
+		res, err := s.api.ListDeletedEvents(context.Background(), &pb.ListDeletedRequest{})
+		s.Require().NoError(err)
+		s.Require().Contains(eventIDs(res.GetEvents()), id)
+	})
+
+	s.when("the user restores it", func() {
+		event, err := s.api.RestoreEvent(context.Background(), &pb.RestoreRequest{Id: id})
+		s.Require().NoError(err)
+		s.Require().Equal(id, event.GetId())
+	})
+
+	s.then("it is back and out of the trash", func() {
+		s.requireListed("day", &pb.ListRequest{Date: timestamppb.New(startsAt)}, id)
+
+		res, err := s.api.ListDeletedEvents(context.Background(), &pb.ListDeletedRequest{})
+		s.Require().NoError(err)
+		s.Require().NotContains(eventIDs(res.GetEvents()), id)
+	})
+
+	// the restored event must not leak into the listings of the other tests.
+	s.when("the user deletes it again", func() {
+		s.Require().NoError(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: id}))
+	})
+}
+
+func (s *CalendarSuite) TestSearchEvents() {
+	var titled, described string
+
+	// the word is unique to the scenario, since scenarios share the running calendar.
+	word := "offsite" + faker.StringWithSize(8)
+	startsAt := time.Date(2030, 7, 9, 10, 0, 0, 0, time.UTC)
+
+	search := func(req *pb.SearchRequest) []string {
+		res, err := s.api.SearchEvents(context.Background(), req)
+		s.Require().NoError(err)
+
+		return eventIDs(res.GetEvents())
+	}
+
+	s.given("an event with the word in the title and another one with it in the description", func() {
+		res, err := s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title:       "Follow-up meeting",
+			Description: "notes of the " + word,
+			StartsAt:    timestamppb.New(startsAt.AddDate(0, 0, 1)),
+			Duration:    durationpb.New(time.Hour),
+		})
+		s.Require().NoError(err)
+		described = res.GetId()
+
+		res, err = s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title:    "Team " + word + " planning",
+			StartsAt: timestamppb.New(startsAt),
+			Duration: durationpb.New(time.Hour),
+		})
+		s.Require().NoError(err)
+		titled = res.GetId()
+	})
+
+	s.then("both are found by the beginning of the word, the title match first", func() {
+		s.Require().Equal([]string{titled, described}, search(&pb.SearchRequest{Query: word[:10]}))
+	})
+
+	s.then("the search is narrowed down by the start of events", func() {
+		s.Require().Equal([]string{described}, search(&pb.SearchRequest{
+			Query: word,
+			From:  timestamppb.New(startsAt.Add(time.Hour)),
+		}))
+		s.Require().Equal([]string{titled}, search(&pb.SearchRequest{Query: word, Limit: 1}))
+	})
+
+	s.then("events of other users are not found", func() {
+		res, err := s.apiFor(uuid.New().String()).SearchEvents(context.Background(), &pb.SearchRequest{Query: word})
+		s.Require().NoError(err)
+		s.Require().Empty(res.GetEvents())
+	})
+
+	s.when("the user deletes the event with the word in the title", func() {
+		s.Require().NoError(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: titled}))
+	})
+
+	s.then("it is not found anymore", func() {
+		s.Require().Equal([]string{described}, search(&pb.SearchRequest{Query: word}))
+	})
+}
+
+func (s *CalendarSuite) TestListPeriods() {
+	var previousWeek, weekStart, day, nextWeek, nextMonth string
+
+	// 2030-04-17 is Wednesday.
+	date := timestamppb.New(time.Date(2030, 4, 17, 12, 0, 0, 0, time.UTC))
+
+	s.given("events around the day, week and month boundaries", func() {
+		// events last an hour, so it ends before Monday.
+		previousWeek = s.createEvent(time.Date(2030, 4, 14, 22, 59, 0, 0, time.UTC), 0)
+		weekStart = s.createEvent(time.Date(2030, 4, 15, 0, 0, 0, 0, time.UTC), 0)
+		day = s.createEvent(time.Date(2030, 4, 17, 10, 0, 0, 0, time.UTC), 0)
+		nextWeek = s.createEvent(time.Date(2030, 4, 22, 0, 0, 0, 0, time.UTC), 0)
+		nextMonth = s.createEvent(time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC), 0)
+	})
+
+	s.then("the day lists events of that day", func() {
+		s.requireListed("day", &pb.ListRequest{Date: date}, day)
+	})
+
+	s.then("the week lists events from Monday on", func() {
+		s.requireListed("week", &pb.ListRequest{Date: date}, weekStart, day)
+	})
+
+	s.then("the week lists events from Sunday on when asked to", func() {
+		s.requireListed("week", &pb.ListRequest{
+			Date:           date,
+			FirstDayOfWeek: dayofweek.DayOfWeek_SUNDAY,
+		}, previousWeek, weekStart, day)
+	})
+
+	s.then("the month lists events of the calendar month", func() {
+		s.requireListed("month", &pb.ListRequest{Date: date}, previousWeek, weekStart, day, nextWeek)
+	})
+
+	s.then("time zone shifts the period", func() {
+		// the Sunday evening event is already on Monday in Moscow.
+		s.requireListed("week", &pb.ListRequest{
+			Date:     date,
+			TimeZone: "Europe/Moscow",
+		}, previousWeek, weekStart, day)
+	})
+
+	s.then("the next month lists its own events", func() {
+		s.requireListed("month", &pb.ListRequest{
+			Date: timestamppb.New(date.AsTime().AddDate(0, 1, 0)),
+		}, nextMonth)
+	})
+}
+
+func (s *CalendarSuite) TestEventTags() {
+	var onCall, meeting, personal string
+
+	date := timestamppb.New(time.Date(2030, 8, 5, 0, 0, 0, 0, time.UTC))
+
+	create := func(title string, hour int, tags ...string) string {
+		res, err := s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title:    title,
+			StartsAt: timestamppb.New(date.AsTime().Add(time.Duration(hour) * time.Hour)),
+			Duration: durationpb.New(time.Hour),
+			Tags:     tags,
+			Color:    "#ff4500",
+		})
+		s.Require().NoError(err)
+
+		return res.GetId()
+	}
+
+	s.given("events tagged as on-call, meetings and personal ones", func() {
+		onCall = create("Primary on-call shift", 8, "on-call", "Work")
+		meeting = create("Weekly team meeting", 10, "meeting", "work")
+		personal = create("Dentist appointment", 12, "personal")
+	})
+
+	s.then("tags and colors are kept", func() {
+		event, err := s.api.GetEvent(context.Background(), &pb.GetRequest{Id: onCall})
+		s.Require().NoError(err)
+		s.Require().Equal([]string{"on-call", "work"}, event.GetTags())
+		s.Require().Equal("#ff4500", event.GetColor())
+	})
+
+	s.then("lists keep events having any of the tags", func() {
+		s.requireListed("day", &pb.ListRequest{Date: date, Tags: []string{"work"}}, onCall, meeting)
+		s.requireListed("week", &pb.ListRequest{Date: date, Tags: []string{"on-call", "personal"}}, onCall, personal)
+	})
+
+	s.then("lists keep events having all of the tags when asked to", func() {
+		s.requireListed("month", &pb.ListRequest{
+			Date: date, Tags: []string{"meeting", "work"}, TagsMatch: "all",
+		}, meeting)
+	})
+
+	s.when("the user retags the meeting as personal", func() {
+		_, err := s.api.UpdateEvent(context.Background(), &pb.UpdateRequest{
+			Id:       meeting,
+			Title:    "Weekly team meeting",
+			StartsAt: timestamppb.New(date.AsTime().Add(10 * time.Hour)),
+			Duration: durationpb.New(time.Hour),
+			Tags:     []string{"personal"},
+		})
+		s.Require().NoError(err)
+	})
+
+	s.then("it is listed by the new tag only", func() {
+		s.requireListed("day", &pb.ListRequest{Date: date, Tags: []string{"work"}}, onCall)
+		s.requireListed("day", &pb.ListRequest{Date: date, Tags: []string{"personal"}}, meeting, personal)
+	})
+
+	s.when("the user deletes the on-call shift", func() {
+		s.Require().NoError(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: onCall}))
+	})
+
+	s.then("the trash is filtered by tags as well", func() {
+		res, err := s.api.ListDeletedEvents(context.Background(), &pb.ListDeletedRequest{Tags: []string{"on-call"}})
+		s.Require().NoError(err)
+		s.Require().Equal([]string{onCall}, eventIDs(res.GetEvents()))
+	})
+}
+
+func (s *CalendarSuite) TestAllDayEvents() {
+	var birthday, trip string
+
+	// 2030-09-16 is Monday.
+	date := time.Date(2030, 9, 16, 0, 0, 0, 0, time.UTC)
+
+	s.given("a birthday and a trip over the weekend", func() {
+		res, err := s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title: "Birthday of a friend", AllDay: true, StartDate: "2030-09-18",
+		})
+		s.Require().NoError(err)
+		birthday = res.GetId()
+
+		res, err = s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title:    "Trip to the seaside",
+			StartsAt: timestamppb.New(time.Date(2030, 9, 20, 18, 0, 0, 0, time.UTC)),
+			Duration: durationpb.New(3 * 24 * time.Hour),
+		})
+		s.Require().NoError(err)
+		trip = res.GetId()
+	})
+
+	s.then("the birthday is on its date in any time zone", func() {
+		// it's still the 18th in Auckland at that time and already the 18th in Los Angeles.
+		for _, tz := range []string{"America/Los_Angeles", "UTC", "Pacific/Auckland"} {
+			s.requireListed("day", &pb.ListRequest{
+				Date: timestamppb.New(time.Date(2030, 9, 18, 11, 0, 0, 0, time.UTC)), TimeZone: tz,
+			}, birthday)
+		}
+	})
+
+	s.then("the trip is on every day it lasts", func() {
+		for day := 20; day <= 23; day++ {
+			s.requireListed("day", &pb.ListRequest{
+				Date: timestamppb.New(time.Date(2030, 9, day, 12, 0, 0, 0, time.UTC)),
+			}, trip)
+		}
+
+		s.requireListed("week", &pb.ListRequest{Date: timestamppb.New(date)}, birthday, trip)
+		s.requireListed("week", &pb.ListRequest{Date: timestamppb.New(date.AddDate(0, 0, 7))}, trip)
+	})
+
+	s.then("the birthday has dates instead of a time", func() {
+		event, err := s.api.GetEvent(context.Background(), &pb.GetRequest{Id: birthday})
+		s.Require().NoError(err)
+		s.Require().True(event.GetAllDay())
+		s.Require().Equal("2030-09-18", event.GetStartDate())
+		s.Require().Equal("2030-09-18", event.GetEndDate())
+	})
+}
+
+func (s *CalendarSuite) TestConditionalList() {
+	if s.transport != transportHTTP {
+		s.T().Skip("conditional requests are HTTP only")
+	}
+
+	var (
+		id, etag, lastModified string
+		listed                 *http.Response
+	)
+
+	query := "/events?view=week&date=2030-08-14&tz=Europe/Moscow"
+
+	s.given("an event of the user", func() {
+		id = s.createEvent(time.Date(2030, 8, 14, 10, 0, 0, 0, time.UTC), 0)
+	})
+
+	s.given("changes made within the current second are over", func() {
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	})
+
+	s.when("the user lists its week by query parameters", func() {
+		var res pb.ListResponse
+
+		listed = s.get(query, nil, &res)
+		s.Require().Equal(http.StatusOK, listed.StatusCode)
+		s.Require().Equal([]string{id}, eventIDs(res.GetEvents()))
+
+		etag = listed.Header.Get("ETag")
+		s.Require().NotEmpty(etag)
+
+		lastModified = listed.Header.Get("Last-Modified")
+		s.Require().NotEmpty(lastModified)
+	})
+
+	s.then("the week is cached per user and revalidated before reuse", func() {
+		s.Require().Equal("X-User-Id", listed.Header.Get("Vary"))
+		s.Require().Equal("private, no-cache", listed.Header.Get("Cache-Control"))
+	})
+
+	s.then("the same list is not sent again by the ETag", func() {
+		res := s.get(query, http.Header{"If-None-Match": {etag}}, nil)
+		s.Require().Equal(http.StatusNotModified, res.StatusCode)
+		s.Require().Equal(etag, res.Header.Get("ETag"))
+		s.Require().Equal(lastModified, res.Header.Get("Last-Modified"))
+	})
+
+	s.then("the same list is not sent again by the modification time", func() {
+		res := s.get(query, http.Header{"If-Modified-Since": {lastModified}}, nil)
+		s.Require().Equal(http.StatusNotModified, res.StatusCode)
+	})
+
+	s.when("the user invites a guest to the event", func() {
+		_, err := s.api.InviteAttendee(context.Background(), &pb.InviteRequest{EventId: id, UserId: uuid.New().String()})
+		s.Require().NoError(err)
+	})
+
+	s.then("the list is sent again, though no listed event was updated", func() {
+		var res pb.ListResponse
+
+		listed = s.get(query, http.Header{"If-None-Match": {etag}}, &res)
+		s.Require().Equal(http.StatusOK, listed.StatusCode)
+		s.Require().Equal([]string{id}, eventIDs(res.GetEvents()))
+		s.Require().NotEqual(etag, listed.Header.Get("ETag"))
+
+		etag = listed.Header.Get("ETag")
+	})
+
+	s.when("the user deletes the event", func() {
+		s.Require().NoError(s.api.DeleteEvent(context.Background(), &pb.DeleteRequest{Id: id}))
+	})
+
+	s.then("the changed list is sent", func() {
+		var res pb.ListResponse
+
+		listed = s.get(query, http.Header{"If-None-Match": {etag}}, &res)
+		s.Require().Equal(http.StatusOK, listed.StatusCode)
+		s.Require().Empty(res.GetEvents())
+		s.Require().NotEqual(etag, listed.Header.Get("ETag"))
+	})
+}
+
+func (s *CalendarSuite) TestNotification() {
+	var notified, silent string
+
+	s.given("an event the user asked to be notified about in a moment", func() {
+		notified = s.createEvent(time.Now().Add(time.Hour+200*time.Millisecond), time.Hour)
+	})
+
+	s.given("an event without notification", func() {
+		silent = s.createEvent(time.Now().Add(100*time.Millisecond), 0)
+	})
+
+	s.then("the user is notified about the first event", func() {
+		n := s.waitNotification(notified)
+		s.Require().Equal(s.userID, n.UserID)
+		s.Require().Equal("log", n.Channel)
+	})
+
+	s.then("the user is notified just once and not about the second event", func() {
+		time.Sleep(5 * s.opts.SchedulerInterval)
+
+		for {
+			select {
+			case n := <-s.harness.Notifications():
+				s.Require().NotEqual(notified, n.EventID)
+				s.Require().NotEqual(silent, n.EventID)
+			default:
+				return
+			}
+		}
+	})
+}
+
+func (s *CalendarSuite) TestNotificationStatus() {
+	var (
+		id             string
+		sent, failed   string
+		listByReminder func() map[string]*pb.Notification
+	)
+
+	s.given("an event reminded about in a moment by log and by email, which is not set up", func() {
+		id = s.createEvent(time.Now().Add(time.Hour+200*time.Millisecond), time.Hour)
+
+		res, err := s.api.ListReminders(context.Background(), &pb.ListRemindersRequest{EventId: id})
+		s.Require().NoError(err)
+		s.Require().Len(res.GetReminders(), 1)
+		sent = res.GetReminders()[0].GetId()
+
+		reminder, err := s.api.AddReminder(context.Background(), &pb.AddReminderRequest{
+			EventId: id,
+			Offset:  durationpb.New(time.Hour),
+			Channel: pb.ReminderChannel_REMINDER_CHANNEL_EMAIL,
+		})
+		s.Require().NoError(err)
+		failed = reminder.GetId()
+
+		listByReminder = func() map[string]*pb.Notification {
+			res, err := s.api.ListNotifications(context.Background(), &pb.ListNotificationsRequest{EventId: id})
+			s.Require().NoError(err)
+
+			notifications := make(map[string]*pb.Notification, len(res.GetNotifications()))
+
+			for _, n := range res.GetNotifications() {
+				notifications[n.GetReminderId()] = n
+			}
+
+			return notifications
+		}
+	})
+
+	s.then("the notification by log is recorded sent", func() {
+		s.waitNotification(id)
+
+		s.Require().Eventually(func() bool {
+			return listByReminder()[sent].GetStatus() == pb.NotificationStatus_NOTIFICATION_STATUS_SENT
+		}, notificationTimeout, s.opts.SchedulerInterval)
+
+		n := listByReminder()[sent]
+		s.Require().Equal(s.userID, n.GetUserId())
+		s.Require().Equal(int32(1), n.GetAttempts())
+		s.Require().NotNil(n.GetSentAt())
+	})
+
+	s.then("the notification by email is recorded failed", func() {
+		s.Require().Eventually(func() bool {
+			return listByReminder()[failed].GetStatus() == pb.NotificationStatus_NOTIFICATION_STATUS_FAILED
+		}, notificationTimeout, s.opts.SchedulerInterval)
+
+		n := listByReminder()[failed]
+		s.Require().Equal(int32(1), n.GetAttempts())
+		s.Require().Contains(n.GetLastError(), "no notifier for channel")
+
+		res, err := s.api.ListNotifications(context.Background(), &pb.ListNotificationsRequest{
+			Status: pb.NotificationStatus_NOTIFICATION_STATUS_FAILED,
+		})
+		s.Require().NoError(err)
+		s.Require().Contains(notificationIDs(res.GetNotifications()), n.GetId())
+	})
+
+	s.when("the failed notification is sent again", func() {
+		n, err := s.api.ResendNotification(context.Background(), &pb.ResendNotificationRequest{
+			Id: listByReminder()[failed].GetId(),
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(pb.NotificationStatus_NOTIFICATION_STATUS_PENDING, n.GetStatus())
+	})
+
+	s.then("it's attempted once more", func() {
+		s.Require().Eventually(func() bool {
+			return listByReminder()[failed].GetAttempts() == 2
+		}, notificationTimeout, s.opts.SchedulerInterval)
+
+		s.Require().Equal(pb.NotificationStatus_NOTIFICATION_STATUS_FAILED, listByReminder()[failed].GetStatus())
+	})
+
+	s.then("the sent notification can't be sent again", func() {
+		_, err := s.api.ResendNotification(context.Background(), &pb.ResendNotificationRequest{
+			Id: listByReminder()[sent].GetId(),
+		})
+		s.requireCode(codes.FailedPrecondition, err)
+	})
+}
+
+func (s *CalendarSuite) TestInvitation() {
+	var (
+		id    string
+		guest calendarAPI
+	)
+
+	guestID := uuid.New().String()
+	startsAt := time.Now().Add(time.Hour + 300*time.Millisecond)
+
+	s.given("an event the user asked to be notified about in a moment", func() {
+		id = s.createEvent(startsAt, time.Hour)
+	})
+
+	s.when("the user invites a guest", func() {
+		guest = s.apiFor(guestID)
+
+		attendee, err := s.api.InviteAttendee(context.Background(), &pb.InviteRequest{
+			EventId: id,
+			UserId:  guestID,
+			Email:   "guest@example.com",
+		})
+		s.Require().NoError(err)
+		s.Require().Equal(pb.AttendeeRole_ATTENDEE_ROLE_REQUIRED, attendee.GetRole())
+		s.Require().Equal(pb.ResponseStatus_RESPONSE_STATUS_NEEDS_ACTION, attendee.GetStatus())
+	})
+
+	s.then("the event is listed for the guest", func() {
+		res, err := guest.ListEvents(context.Background(), "day", &pb.ListRequest{Date: timestamppb.New(startsAt)})
+		s.Require().NoError(err)
+		s.Require().Len(res.GetEvents(), 1)
+		s.Require().Equal(id, res.GetEvents()[0].GetId())
+	})
+
+	s.when("the guest accepts the invitation", func() {
+		_, err := guest.RespondToInvitation(context.Background(), &pb.RespondRequest{
+			EventId: id,
+			Status:  pb.ResponseStatus_RESPONSE_STATUS_ACCEPTED,
+		})
+		s.Require().NoError(err)
+	})
+
+	s.then("the user sees the response", func() {
+		res, err := s.api.ListAttendees(context.Background(), &pb.ListAttendeesRequest{EventId: id})
+		s.Require().NoError(err)
+		s.Require().Len(res.GetAttendees(), 1)
+		s.Require().Equal(guestID, res.GetAttendees()[0].GetUserId())
+		s.Require().Equal(pb.ResponseStatus_RESPONSE_STATUS_ACCEPTED, res.GetAttendees()[0].GetStatus())
+	})
+
+	s.then("both the user and the guest are notified", func() {
+		// notifications of a reminder are written at once, so they come in no particular order.
+		recipients := map[string]queue.Notification{}
+
+		for i := 0; i < 2; i++ {
+			n := s.waitNotification(id)
+			recipients[n.UserID] = n
+		}
+
+		s.Require().Contains(recipients, s.userID)
+		s.Require().Contains(recipients, guestID)
+		s.Require().Equal("guest@example.com", recipients[guestID].Email)
+	})
+}
+
+func (s *CalendarSuite) TestWebhook() {
+	var (
+		webhookID string
+		id        string
+		delivery  *http.Request
+		payload   app.WebhookPayload
+	)
+
+	secret := faker.StringWithSize(32)
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+	}))
+	s.T().Cleanup(receiver.Close)
+
+	// another user, so events of the other scenarios are not delivered.
+	user := s.apiFor(uuid.New().String())
+
+	s.given("a webhook subscribed to new events", func() {
+		res, err := user.CreateWebhook(context.Background(), &pb.CreateWebhookRequest{
+			Url:        receiver.URL,
+			Secret:     secret,
+			Operations: []pb.AuditOperation{pb.AuditOperation_AUDIT_OPERATION_CREATE},
+		})
+		s.Require().NoError(err)
+
+		webhookID = res.GetId()
+	})
+
+	s.when("the user creates an event", func() {
+		res, err := user.CreateEvent(context.Background(), &pb.CreateRequest{
+			Title:    faker.StringWithSize(20),
+			StartsAt: timestamppb.New(time.Date(2030, 6, 1, 10, 0, 0, 0, time.UTC)),
+			Duration: durationpb.New(time.Hour),
+		})
+		s.Require().NoError(err)
+
+		id = res.GetId()
+	})
+
+	s.then("the webhook receives the signed change", func() {
+		select {
+		case delivery = <-requests:
+		case <-time.After(notificationTimeout):
+			s.Require().FailNow("webhook was not called")
+		}
+
+		body := <-bodies
+		s.Require().True(webhook.Verify(
+			secret, body, delivery.Header.Get(webhook.TimestampHeader), delivery.Header.Get(webhook.SignatureHeader),
+			time.Minute,
+		))
+		s.Require().NoError(json.Unmarshal(body, &payload))
+		s.Require().Equal("create", string(payload.Operation))
+		s.Require().Equal(id, payload.Event.ID)
+	})
+
+	s.then("the delivery is recorded", func() {
+		var deliveries []*pb.WebhookDelivery
+
+		// the delivery is saved as delivered right after the webhook responds.
+		s.Require().Eventually(func() bool {
+			res, err := user.ListWebhookDeliveries(context.Background(), &pb.ListWebhookDeliveriesRequest{
+				WebhookId: webhookID,
+			})
+			deliveries = res.GetDeliveries()
+
+			return err == nil && len(deliveries) == 1 &&
+				deliveries[0].GetStatus() == pb.DeliveryStatus_DELIVERY_STATUS_DELIVERED
+		}, notificationTimeout, s.opts.SchedulerInterval)
+
+		s.Require().Equal(payload.DeliveryID, deliveries[0].GetId())
+		s.Require().Equal(int32(1), deliveries[0].GetAttempts())
+	})
+}
+
+// createEvent creates an hour long event, reminded about the offset before it starts unless the offset is zero.
+func (s *CalendarSuite) createEvent(startsAt time.Time, remindBefore time.Duration) string {
+	res, err := s.api.CreateEvent(context.Background(), &pb.CreateRequest{
+		Title:    faker.StringWithSize(20),
+		StartsAt: timestamppb.New(startsAt),
+		Duration: durationpb.New(time.Hour),
+	})
+	s.Require().NoError(err)
+
+	if remindBefore > 0 {
+		_, err = s.api.AddReminder(context.Background(), &pb.AddReminderRequest{
+			EventId: res.GetId(),
+			Offset:  durationpb.New(remindBefore),
+		})
+		s.Require().NoError(err)
+	}
+
+	return res.GetId()
+}
+
+// requireListed checks the period lists exactly the given events in order.
+func (s *CalendarSuite) requireListed(period string, req *pb.ListRequest, ids ...string) {
+	res, err := s.api.ListEvents(context.Background(), period, req)
+	s.Require().NoError(err)
+	s.Require().Equal(append([]string{}, ids...), eventIDs(res.GetEvents()), "%s events", period)
+}
+
+// get requests the path from the HTTP server on behalf of the user along with the header,
+// the body of successful responses is read into out.
+func (s *CalendarSuite) get(path string, header http.Header, out proto.Message) *http.Response {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+s.harness.HTTPAddress+path, nil)
+	s.Require().NoError(err)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	req.Header.Set("X-User-Id", s.userID)
+
+	res, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	s.Require().NoError(err)
+
+	if out != nil && res.StatusCode == http.StatusOK {
+		s.Require().NoError(protojson.Unmarshal(data, out))
+	}
+
+	return res
+}
+
+func eventIDs(events []*pb.Event) []string {
+	ids := make([]string, 0, len(events))
+
+	for _, e := range events {
+		ids = append(ids, e.GetId())
+	}
+
+	return ids
+}
+
+func notificationIDs(notifications []*pb.Notification) []string {
+	ids := make([]string, 0, len(notifications))
+
+	for _, n := range notifications {
+		ids = append(ids, n.GetId())
+	}
+
+	return ids
+}
+
+func (s *CalendarSuite) requireCode(code codes.Code, err error) {
+	s.Require().Error(err)
+	s.Require().Equal(code, status.Code(err), err.Error())
+}
+
+// waitNotification skips notifications about other events, scenarios share the running calendar.
+func (s *CalendarSuite) waitNotification(eventID string) queue.Notification {
+	timeout := time.After(notificationTimeout)
+
+	for {
+		select {
+		case n := <-s.harness.Notifications():
+			if n.EventID == eventID {
+				return n
+			}
+		case <-timeout:
+			s.FailNow("notification is not delivered", eventID)
+
+			return queue.Notification{}
+		}
+	}
+}
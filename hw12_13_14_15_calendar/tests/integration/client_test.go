@@ -0,0 +1,363 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// calendarAPI is the part of the API scenarios use, implemented over both transports.
+// Errors are reported as gRPC statuses either way so scenarios can check them alike.
+type calendarAPI interface {
+	CreateEvent(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error)
+	GetEvent(ctx context.Context, req *pb.GetRequest) (*pb.Event, error)
+	UpdateEvent(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error)
+	DeleteEvent(ctx context.Context, req *pb.DeleteRequest) error
+	RestoreEvent(ctx context.Context, req *pb.RestoreRequest) (*pb.Event, error)
+	ListDeletedEvents(ctx context.Context, req *pb.ListDeletedRequest) (*pb.ListResponse, error)
+	ListEventHistory(ctx context.Context, req *pb.ListEventHistoryRequest) (*pb.ListEventHistoryResponse, error)
+	ListEvents(ctx context.Context, period string, req *pb.ListRequest) (*pb.ListResponse, error)
+	SearchEvents(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error)
+	InviteAttendee(ctx context.Context, req *pb.InviteRequest) (*pb.Attendee, error)
+	ListAttendees(ctx context.Context, req *pb.ListAttendeesRequest) (*pb.ListAttendeesResponse, error)
+	RespondToInvitation(ctx context.Context, req *pb.RespondRequest) (*pb.Attendee, error)
+	AddReminder(ctx context.Context, req *pb.AddReminderRequest) (*pb.Reminder, error)
+	ListReminders(ctx context.Context, req *pb.ListRemindersRequest) (*pb.ListRemindersResponse, error)
+	ListNotifications(ctx context.Context, req *pb.ListNotificationsRequest) (*pb.ListNotificationsResponse, error)
+	ResendNotification(ctx context.Context, req *pb.ResendNotificationRequest) (*pb.Notification, error)
+	CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.Webhook, error)
+	ListWebhookDeliveries(
+		ctx context.Context, req *pb.ListWebhookDeliveriesRequest,
+	) (*pb.ListWebhookDeliveriesResponse, error)
+}
+
+type grpcAPI struct {
+	client pb.CalendarServiceClient
+	userID string
+}
+
+func newGRPCAPI(conn *grpc.ClientConn, userID string) *grpcAPI {
+	return &grpcAPI{pb.NewCalendarServiceClient(conn), userID}
+}
+
+func (a *grpcAPI) withUser(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, internalgrpc.UserIDMetadataKey, a.userID)
+}
+
+func (a *grpcAPI) CreateEvent(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	return a.client.CreateEvent(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) GetEvent(ctx context.Context, req *pb.GetRequest) (*pb.Event, error) {
+	return a.client.GetEvent(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) UpdateEvent(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	return a.client.UpdateEvent(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) DeleteEvent(ctx context.Context, req *pb.DeleteRequest) error {
+	_, err := a.client.DeleteEvent(a.withUser(ctx), req)
+
+	return err
+}
+
+func (a *grpcAPI) RestoreEvent(ctx context.Context, req *pb.RestoreRequest) (*pb.Event, error) {
+	return a.client.RestoreEvent(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListDeletedEvents(ctx context.Context, req *pb.ListDeletedRequest) (*pb.ListResponse, error) {
+	return a.client.ListDeletedEvents(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListEventHistory(
+	ctx context.Context, req *pb.ListEventHistoryRequest,
+) (*pb.ListEventHistoryResponse, error) {
+	return a.client.ListEventHistory(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListEvents(ctx context.Context, period string, req *pb.ListRequest) (*pb.ListResponse, error) {
+	switch period {
+	case "week":
+		return a.client.ListWeekEvents(a.withUser(ctx), req)
+	case "month":
+		return a.client.ListMonthEvents(a.withUser(ctx), req)
+	default:
+		return a.client.ListDayEvents(a.withUser(ctx), req)
+	}
+}
+
+func (a *grpcAPI) SearchEvents(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	return a.client.SearchEvents(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) InviteAttendee(ctx context.Context, req *pb.InviteRequest) (*pb.Attendee, error) {
+	return a.client.InviteAttendee(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListAttendees(
+	ctx context.Context, req *pb.ListAttendeesRequest,
+) (*pb.ListAttendeesResponse, error) {
+	return a.client.ListAttendees(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) RespondToInvitation(ctx context.Context, req *pb.RespondRequest) (*pb.Attendee, error) {
+	return a.client.RespondToInvitation(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) AddReminder(ctx context.Context, req *pb.AddReminderRequest) (*pb.Reminder, error) {
+	return a.client.AddReminder(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListReminders(
+	ctx context.Context, req *pb.ListRemindersRequest,
+) (*pb.ListRemindersResponse, error) {
+	return a.client.ListReminders(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListNotifications(
+	ctx context.Context, req *pb.ListNotificationsRequest,
+) (*pb.ListNotificationsResponse, error) {
+	return a.client.ListNotifications(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ResendNotification(
+	ctx context.Context, req *pb.ResendNotificationRequest,
+) (*pb.Notification, error) {
+	return a.client.ResendNotification(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.Webhook, error) {
+	return a.client.CreateWebhook(a.withUser(ctx), req)
+}
+
+func (a *grpcAPI) ListWebhookDeliveries(
+	ctx context.Context, req *pb.ListWebhookDeliveriesRequest,
+) (*pb.ListWebhookDeliveriesResponse, error) {
+	return a.client.ListWebhookDeliveries(a.withUser(ctx), req)
+}
+
+// httpAPI talks JSON to the gateway the way any HTTP client would.
+type httpAPI struct {
+	baseURL string
+	userID  string
+}
+
+func newHTTPAPI(address, userID string) *httpAPI {
+	return &httpAPI{"http://" + address, userID}
+}
+
+func (a *httpAPI) CreateEvent(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	res := &pb.CreateResponse{}
+
+	return res, a.do(ctx, http.MethodPost, "/events", req, res)
+}
+
+func (a *httpAPI) GetEvent(ctx context.Context, req *pb.GetRequest) (*pb.Event, error) {
+	res := &pb.Event{}
+
+	return res, a.do(ctx, http.MethodGet, "/events/"+req.GetId(), nil, res)
+}
+
+func (a *httpAPI) UpdateEvent(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	res := &pb.UpdateResponse{}
+
+	return res, a.do(ctx, http.MethodPut, "/events/"+req.GetId(), req, res)
+}
+
+func (a *httpAPI) DeleteEvent(ctx context.Context, req *pb.DeleteRequest) error {
+	return a.do(ctx, http.MethodDelete, "/events/"+req.GetId(), nil, nil)
+}
+
+func (a *httpAPI) RestoreEvent(ctx context.Context, req *pb.RestoreRequest) (*pb.Event, error) {
+	res := &pb.Event{}
+
+	return res, a.do(ctx, http.MethodPost, "/events/"+req.GetId()+"/restore", nil, res)
+}
+
+func (a *httpAPI) ListDeletedEvents(ctx context.Context, req *pb.ListDeletedRequest) (*pb.ListResponse, error) {
+	query := url.Values{"tags": req.GetTags()}
+
+	if req.GetTagsMatch() != "" {
+		query.Set("tags_match", req.GetTagsMatch())
+	}
+
+	res := &pb.ListResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/trash?"+query.Encode(), nil, res)
+}
+
+func (a *httpAPI) ListEventHistory(
+	ctx context.Context, req *pb.ListEventHistoryRequest,
+) (*pb.ListEventHistoryResponse, error) {
+	res := &pb.ListEventHistoryResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/events/"+req.GetEventId()+"/history", nil, res)
+}
+
+func (a *httpAPI) ListEvents(ctx context.Context, period string, req *pb.ListRequest) (*pb.ListResponse, error) {
+	res := &pb.ListResponse{}
+
+	return res, a.do(ctx, http.MethodPost, "/events/"+period, req, res)
+}
+
+func (a *httpAPI) SearchEvents(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	query := url.Values{"query": {req.GetQuery()}}
+
+	if req.GetFrom() != nil {
+		query.Set("from", req.GetFrom().AsTime().Format(time.RFC3339Nano))
+	}
+
+	if req.GetTo() != nil {
+		query.Set("to", req.GetTo().AsTime().Format(time.RFC3339Nano))
+	}
+
+	if req.GetLimit() > 0 {
+		query.Set("limit", strconv.FormatUint(uint64(req.GetLimit()), 10))
+	}
+
+	res := &pb.SearchResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/search?"+query.Encode(), nil, res)
+}
+
+func (a *httpAPI) InviteAttendee(ctx context.Context, req *pb.InviteRequest) (*pb.Attendee, error) {
+	res := &pb.Attendee{}
+
+	return res, a.do(ctx, http.MethodPost, "/events/"+req.GetEventId()+"/attendees", req, res)
+}
+
+func (a *httpAPI) ListAttendees(
+	ctx context.Context, req *pb.ListAttendeesRequest,
+) (*pb.ListAttendeesResponse, error) {
+	res := &pb.ListAttendeesResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/events/"+req.GetEventId()+"/attendees", nil, res)
+}
+
+func (a *httpAPI) RespondToInvitation(ctx context.Context, req *pb.RespondRequest) (*pb.Attendee, error) {
+	res := &pb.Attendee{}
+
+	return res, a.do(ctx, http.MethodPut, "/events/"+req.GetEventId()+"/response", req, res)
+}
+
+func (a *httpAPI) AddReminder(ctx context.Context, req *pb.AddReminderRequest) (*pb.Reminder, error) {
+	res := &pb.Reminder{}
+
+	return res, a.do(ctx, http.MethodPost, "/events/"+req.GetEventId()+"/reminders", req, res)
+}
+
+func (a *httpAPI) ListReminders(
+	ctx context.Context, req *pb.ListRemindersRequest,
+) (*pb.ListRemindersResponse, error) {
+	res := &pb.ListRemindersResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/events/"+req.GetEventId()+"/reminders", nil, res)
+}
+
+func (a *httpAPI) ListNotifications(
+	ctx context.Context, req *pb.ListNotificationsRequest,
+) (*pb.ListNotificationsResponse, error) {
+	query := url.Values{}
+
+	if req.GetEventId() != "" {
+		query.Set("event_id", req.GetEventId())
+	}
+
+	if req.GetStatus() != pb.NotificationStatus_NOTIFICATION_STATUS_UNSPECIFIED {
+		query.Set("status", req.GetStatus().String())
+	}
+
+	res := &pb.ListNotificationsResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/notifications?"+query.Encode(), nil, res)
+}
+
+func (a *httpAPI) ResendNotification(
+	ctx context.Context, req *pb.ResendNotificationRequest,
+) (*pb.Notification, error) {
+	res := &pb.Notification{}
+
+	return res, a.do(ctx, http.MethodPost, "/notifications/"+req.GetId()+"/resend", nil, res)
+}
+
+func (a *httpAPI) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.Webhook, error) {
+	res := &pb.Webhook{}
+
+	return res, a.do(ctx, http.MethodPost, "/webhooks", req, res)
+}
+
+func (a *httpAPI) ListWebhookDeliveries(
+	ctx context.Context, req *pb.ListWebhookDeliveriesRequest,
+) (*pb.ListWebhookDeliveriesResponse, error) {
+	res := &pb.ListWebhookDeliveriesResponse{}
+
+	return res, a.do(ctx, http.MethodGet, "/webhooks/"+req.GetWebhookId()+"/deliveries", nil, res)
+}
+
+func (a *httpAPI) do(ctx context.Context, method, path string, in, out proto.Message) error {
+	var body io.Reader
+
+	if in != nil {
+		data, err := protojson.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Id", a.userID)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Code    codes.Code `json:"code"`
+			Message string     `json:"message"`
+		}
+
+		if err := json.Unmarshal(data, &apiErr); err != nil {
+			return fmt.Errorf("unexpected response %d: %s", res.StatusCode, data)
+		}
+
+		return status.Error(apiErr.Code, apiErr.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return protojson.Unmarshal(data, out)
+}
@@ -0,0 +1,153 @@
+// Package integration runs the whole calendar within the test process for API level tests:
+// the gRPC server and the HTTP gateway on ephemeral ports, the scheduler and the sender
+// connected through an in-memory queue, the webhook delivery worker and the chosen storage.
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/app"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/lifecycle"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/logger"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/queue"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/scheduler"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/sender"
+	internalgrpc "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/grpc"
+	internalhttp "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/server/http"
+	memorystorage "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage/memory"
+	sqlstorage "github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/storage/sql"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/internal/webhook"
+	"github.com/seth2810/otus_homework/hw12_13_14_15_calendar/migrations"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	MemoryStorage = "memory"
+	SQLiteStorage = "sqlite"
+)
+
+type Options struct {
+	// Storage is either MemoryStorage or SQLiteStorage.
+	Storage string
+	// SchedulerInterval is how often the scheduler looks for due notifications and webhook deliveries.
+	SchedulerInterval time.Duration
+}
+
+// Harness is a running calendar, it is stopped when the test finishes.
+type Harness struct {
+	GRPCAddress string
+	HTTPAddress string
+
+	notifications chan queue.Notification
+}
+
+// notifier stands in for a real delivery channel and hands notifications over to the test.
+type notifier chan<- queue.Notification
+
+func (n notifier) Notify(ctx context.Context, notification queue.Notification) error {
+	select {
+	case n <- notification:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func Start(t *testing.T, opts Options) *Harness {
+	t.Helper()
+
+	log, err := logger.New("error", "stderr")
+	require.NoError(t, err)
+
+	storage := newStorage(t, opts.Storage)
+	calendar := app.New(log, storage)
+
+	grpcServer := internalgrpc.NewServer("127.0.0.1:0", log, calendar)
+	require.NoError(t, grpcServer.Listen())
+
+	httpServer := internalhttp.NewServer("127.0.0.1:0", grpcServer.Addr(), log, internalhttp.Options{})
+	require.NoError(t, httpServer.Listen())
+
+	h := &Harness{
+		GRPCAddress:   grpcServer.Addr(),
+		HTTPAddress:   httpServer.Addr(),
+		notifications: make(chan queue.Notification, 100),
+	}
+
+	q := queue.NewMemory(100)
+
+	manager := lifecycle.New(log, 5*time.Second)
+	manager.Add("grpc server", grpcServer)
+	manager.Add("http server", httpServer)
+	// only the log channel is set up, so notifications through the others fail.
+	channels := sender.Channels{"log": notifier(h.notifications)}
+
+	manager.Add("sender", lifecycle.Worker(sender.New(log, q, channels, storage).Run))
+	manager.Add("scheduler", lifecycle.Worker(scheduler.New(log, storage, opts.SchedulerInterval, 0).Run))
+	manager.Add("outbox relay", lifecycle.Worker(scheduler.NewRelay(log, storage, q, opts.SchedulerInterval, 0).Run))
+	// receivers of the tests listen on the loopback.
+	webhookClient := webhook.NewClient(10*time.Second, true)
+
+	manager.Add("webhooks", lifecycle.Worker(webhook.New(log, storage, webhookClient, opts.SchedulerInterval,
+		webhook.RetryPolicy{MaxAttempts: 3, MinBackoff: opts.SchedulerInterval, MaxBackoff: opts.SchedulerInterval},
+	).Run))
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- manager.Run(context.Background())
+	}()
+
+	select {
+	case <-manager.Ready():
+	case <-manager.Done():
+		require.FailNow(t, "calendar failed to start", <-errCh)
+	}
+
+	t.Cleanup(func() {
+		manager.Shutdown("test finished")
+		require.NoError(t, <-errCh)
+	})
+
+	return h
+}
+
+// Notifications returns notifications delivered by the sender.
+func (h *Harness) Notifications() <-chan queue.Notification {
+	return h.notifications
+}
+
+func newStorage(t *testing.T, storageType string) app.Storage {
+	t.Helper()
+
+	switch storageType {
+	case MemoryStorage:
+		return memorystorage.New()
+	case SQLiteStorage:
+		dsn := filepath.Join(t.TempDir(), "calendar.db") + "?_pragma=foreign_keys(1)&_time_format=sqlite"
+
+		db, err := sql.Open(sqlstorage.SQLiteDriver, dsn)
+		require.NoError(t, err)
+		require.NoError(t, migrations.Run(db, "sqlite3", "up"))
+		require.NoError(t, db.Close())
+
+		storage, err := sqlstorage.New(sqlstorage.SQLiteDriver, dsn)
+		require.NoError(t, err)
+		require.NoError(t, storage.Connect(context.Background()))
+
+		t.Cleanup(func() {
+			require.NoError(t, storage.Close(context.Background()))
+		})
+
+		return storage
+	default:
+		require.FailNowf(t, "unsupported storage", "%q", storageType)
+
+		return nil
+	}
+}
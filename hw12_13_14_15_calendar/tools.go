@@ -6,6 +6,7 @@ import (
 	_ "github.com/bufbuild/buf/cmd/buf"
 	_ "github.com/envoyproxy/protoc-gen-validate"
 	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-grpc-gateway"
+	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2"
 	_ "google.golang.org/grpc/cmd/protoc-gen-go-grpc"
 	_ "google.golang.org/protobuf/cmd/protoc-gen-go"
 )